@@ -0,0 +1,11 @@
+// Package api holds the renderer's API definitions: the OpenAPI specification
+// of the HTTP API and, under proto, the gRPC service
+package api
+
+import _ "embed"
+
+// Swagger is the OpenAPI specification of the HTTP API, compiled into the
+// binary so /swagger.json doesn't depend on the working directory
+//
+//go:embed swagger.json
+var Swagger []byte
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,8 +14,10 @@ import (
 
 	"github.com/koios/matrx-renderer/internal/config"
 	"github.com/koios/matrx-renderer/internal/handlers"
+	"github.com/koios/matrx-renderer/pkg/rendererpb"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
 )
 
 func newLogger(level string) (*zap.Logger, error) {
@@ -51,29 +54,96 @@ func main() {
 	}
 	defer logger.Sync()
 
-	// Create context for graceful shutdown
-	_, cancel := context.WithCancel(context.Background())
+	// Root context for the process. Request contexts derive from it, so cancelling
+	// it aborts handlers still running when shutdown gives up on them.
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Initialize event handler
 	eventHandler := handlers.NewEventHandler(logger, cfg)
 
+	// Warm up apps before accepting traffic so first requests aren't cold
+	eventHandler.GetProcessor().Warmup(ctx)
+
 	// Create HTTP server for app management API
 	mux := http.NewServeMux()
 	appHandler := handlers.NewAppHandler(eventHandler.GetProcessor(), logger)
 	appHandler.RegisterRoutes(mux)
 
+	cors := handlers.NewCORS(cfg.Server)
+	appHandler.SetCORS(cors)
+
+	authenticator, err := handlers.NewAuthenticator(cfg.Auth, logger)
+	if err != nil {
+		logger.Fatal("Invalid authentication configuration", zap.Error(err))
+	}
+
+	rateLimiter := handlers.NewRateLimiter(cfg.Server, logger)
+	if cfg.Server.RateLimitShared {
+		rateLimiter.UseShared(eventHandler.GetProcessor().SharedRateLimiter())
+	}
+
+	serverTLS, err := handlers.NewTLS(cfg.Server, logger)
+	if err != nil {
+		logger.Fatal("Invalid TLS configuration", zap.Error(err))
+	}
+
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      mux,
+		Handler:      handlers.RequestID(cors.Wrap(serverTLS.Wrap(authenticator.Wrap(rateLimiter.Wrap(mux))))),
+		TLSConfig:    serverTLS.Config(),
 		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return ctx },
+	}
+	// Live streams never go idle, so end them rather than wait out the deadline
+	httpServer.RegisterOnShutdown(appHandler.CloseStreams)
+
+	// Profiling endpoints listen separately so they are never exposed with the API
+	var pprofServer *http.Server
+	if cfg.Server.PprofAddr != "" {
+		pprofServer = &http.Server{
+			Addr:              cfg.Server.PprofAddr,
+			Handler:           handlers.NewPprofMux(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			logger.Info("Starting pprof server", zap.String("addr", cfg.Server.PprofAddr))
+			if err := pprofServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("pprof server failed", zap.Error(err))
+			}
+		}()
+	}
+
+	// The gRPC service shares the processor, authentication and TLS with the HTTP API
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPCPort != 0 {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.GRPCPort))
+		if err != nil {
+			logger.Fatal("Failed to listen for gRPC", zap.Int("port", cfg.Server.GRPCPort), zap.Error(err))
+		}
+		grpcServer = grpc.NewServer(append(authenticator.GRPCServerOptions(), serverTLS.GRPCServerOptions()...)...)
+		rendererpb.RegisterRendererServer(grpcServer, handlers.NewGRPCServer(eventHandler.GetProcessor(), logger))
+		go func() {
+			logger.Info("Starting gRPC server", zap.Int("port", cfg.Server.GRPCPort))
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Error("gRPC server failed", zap.Error(err))
+				cancel()
+			}
+		}()
 	}
 
 	// Start HTTP server
 	go func() {
-		logger.Info("Starting HTTP server", zap.Int("port", cfg.Server.Port))
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("Starting HTTP server", zap.Int("port", cfg.Server.Port), zap.Bool("tls", serverTLS != nil))
+		var err error
+		if serverTLS != nil {
+			// Certificates come from TLSConfig.GetCertificate
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Error("HTTP server failed", zap.Error(err))
 			cancel()
 		}
@@ -83,33 +153,71 @@ func main() {
 		zap.Int("port", cfg.Server.Port),
 		zap.String("apps_path", cfg.Pixlet.AppsPath))
 
-	// Wait for interrupt signal to gracefully shutdown
+	// Wait for a shutdown signal, or for a server to fail
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	logger.Info("Shutting down server...")
-
-	// Give outstanding requests a deadline for completion
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
+	select {
+	case sig := <-quit:
+		logger.Info("Shutting down server...", zap.String("signal", sig.String()))
+	case <-ctx.Done():
+		logger.Info("Shutting down after a server failed")
+	}
 
-	// Shutdown HTTP server
-	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		logger.Error("HTTP server shutdown failed", zap.Error(err))
+	// 1. Stop taking new work: /readyz fails from here on, and the delay gives
+	// load balancers time to notice before renders start being refused
+	appHandler.StartDraining()
+	if cfg.Server.ShutdownDelay > 0 {
+		logger.Info("Waiting for traffic to move away", zap.Int("seconds", cfg.Server.ShutdownDelay))
+		select {
+		case <-time.After(time.Duration(cfg.Server.ShutdownDelay) * time.Second):
+		case <-quit:
+			logger.Info("Second signal received, skipping shutdown delay")
+		}
 	}
 
-	// Stop the processor's worker pool
-	eventHandler.GetProcessor().Stop()
+	// 2. Drain the pool: accepted render jobs finish while new ones get 503.
+	// Anything left at the deadline fails explicitly.
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownDrainTimeout)*time.Second)
+	if err := eventHandler.GetProcessor().Drain(drainCtx); err != nil {
+		logger.Warn("Render jobs cancelled at drain deadline", zap.Error(err))
+	}
+	drainCancel()
+
+	// 3. Stop the servers: requests in flight finish, and live streams are ended.
+	// At the deadline the root context is cancelled so stragglers abort.
+	serversCtx, serversCancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownHTTPTimeout)*time.Second)
+	defer serversCancel()
+
+	grpcStopped := make(chan struct{})
+	if grpcServer != nil {
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+	}
+	if err := httpServer.Shutdown(serversCtx); err != nil {
+		logger.Warn("HTTP requests cut off at shutdown deadline", zap.Error(err))
+		cancel()
+		httpServer.Close()
+	}
+	if grpcServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-serversCtx.Done():
+			logger.Warn("gRPC calls cut off at shutdown deadline")
+			cancel()
+			grpcServer.Stop()
+			<-grpcStopped
+		}
+	}
+	if pprofServer != nil {
+		// In-flight profiles are only diagnostics; don't wait for them
+		pprofServer.Close()
+	}
 
-	// Cancel the main context to stop all operations
 	cancel()
-
-	// Wait for shutdown to complete or timeout
-	select {
-	case <-shutdownCtx.Done():
-		logger.Warn("Shutdown timeout exceeded")
-	case <-time.After(2 * time.Second):
-		logger.Info("Server shutdown complete")
+	if err := eventHandler.GetProcessor().Close(); err != nil {
+		logger.Warn("Failed to close processor", zap.Error(err))
 	}
+	logger.Info("Server shutdown complete")
 }
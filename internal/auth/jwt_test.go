@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testProvider is an identity provider serving OIDC discovery and a JWKS
+type testProvider struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	kid     string
+	fetches atomic.Int32
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{key: key, kid: "key-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": p.server.URL, "jwks_uri": p.server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		p.fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": p.kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// token signs claims with RS256, filling in the issuer and a valid expiry if unset
+func (p *testProvider) token(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	if _, ok := claims["iss"]; !ok {
+		claims["iss"] = p.server.URL
+	}
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	return signToken(t, map[string]string{"alg": "RS256", "kid": p.kid}, claims, func(digest []byte) []byte {
+		sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	})
+}
+
+func signToken(t *testing.T, header map[string]string, claims map[string]interface{}, sign func(digest []byte) []byte) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(digest[:]))
+}
+
+func TestVerify(t *testing.T) {
+	p := newTestProvider(t)
+	v, err := NewVerifier(Config{Issuer: p.server.URL, Audience: "matrx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	claims, err := v.Verify(ctx, p.token(t, map[string]interface{}{"sub": "alice", "aud": []string{"other", "matrx"}}))
+	if err != nil {
+		t.Fatalf("Expected a valid token, got %v", err)
+	}
+	if claims.Subject() != "alice" {
+		t.Errorf("Expected subject alice, got %q", claims.Subject())
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"wrong audience", map[string]interface{}{"aud": "someone-else"}},
+		{"no audience", map[string]interface{}{}},
+		{"wrong issuer", map[string]interface{}{"aud": "matrx", "iss": "https://evil.example"}},
+		{"not yet valid", map[string]interface{}{"aud": "matrx", "nbf": time.Now().Add(time.Hour).Unix()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(ctx, p.token(t, tt.claims)); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+
+	expired := p.token(t, map[string]interface{}{"aud": "matrx", "exp": time.Now().Add(-time.Hour).Unix()})
+	if _, err := v.Verify(ctx, expired); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired, got %v", err)
+	}
+
+	if p.fetches.Load() != 1 {
+		t.Errorf("Expected keys to be fetched once, got %d", p.fetches.Load())
+	}
+}
+
+func TestVerify_BadSignature(t *testing.T) {
+	p := newTestProvider(t)
+	v, _ := NewVerifier(Config{Issuer: p.server.URL})
+	ctx := context.Background()
+
+	token := p.token(t, map[string]interface{}{"sub": "alice"})
+	parts := strings.Split(token, ".")
+
+	// Swap in different claims under the original signature
+	forged := map[string]interface{}{"sub": "mallory", "iss": p.server.URL, "exp": time.Now().Add(time.Hour).Unix()}
+	data, _ := json.Marshal(forged)
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(data) + "." + parts[2]
+	if _, err := v.Verify(ctx, tampered); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected tampered token to be rejected, got %v", err)
+	}
+
+	// An unsigned token must never be accepted
+	none := parts[0] + "." + parts[1] + "."
+	none = strings.Replace(none, parts[0], base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"key-1"}`)), 1)
+	if _, err := v.Verify(ctx, none); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected alg none to be rejected, got %v", err)
+	}
+
+	if _, err := v.Verify(ctx, "not-a-token"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected malformed token to be rejected, got %v", err)
+	}
+}
+
+func TestVerify_UnknownKey(t *testing.T) {
+	p := newTestProvider(t)
+	v, _ := NewVerifier(Config{Issuer: p.server.URL, JWKSURL: p.server.URL + "/keys"})
+	ctx := context.Background()
+
+	if _, err := v.Verify(ctx, p.token(t, map[string]interface{}{})); err != nil {
+		t.Fatalf("Expected a valid token, got %v", err)
+	}
+
+	// A token from a rolled key triggers a refetch, but not more than once per interval
+	p.kid = "key-2"
+	token := p.token(t, map[string]interface{}{})
+	p.kid = "key-1"
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Expected unknown key to be rejected, got %v", err)
+		}
+	}
+	if p.fetches.Load() != 1 {
+		t.Errorf("Expected refetches to be rate limited, got %d fetches", p.fetches.Load())
+	}
+}
+
+func TestVerifySignature_ECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := "header.claims"
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	if err := verifySignature("ES256", &key.PublicKey, signed, sig); err != nil {
+		t.Errorf("Expected a valid ES256 signature, got %v", err)
+	}
+	if err := verifySignature("RS256", &key.PublicKey, signed, sig); err == nil {
+		t.Error("Expected RS256 to be rejected for an EC key")
+	}
+	if err := verifySignature("HS256", &key.PublicKey, signed, sig); err == nil {
+		t.Error("Expected HMAC algorithms to be rejected")
+	}
+}
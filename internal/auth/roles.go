@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Role is a level of access to the HTTP API. Each role includes the ones below it.
+type Role int
+
+const (
+	RoleNone   Role = iota
+	RoleRead        // list apps, read schemas, previews and stats
+	RoleRender      // render, validate, benchmark and call schema handlers
+	RoleAdmin       // reload the app registry
+)
+
+// String returns the role name used in config and claims
+func (r Role) String() string {
+	switch r {
+	case RoleRead:
+		return "read"
+	case RoleRender:
+		return "render"
+	case RoleAdmin:
+		return "admin"
+	}
+	return "none"
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "read":
+		return RoleRead, nil
+	case "render":
+		return RoleRender, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return RoleNone, fmt.Errorf("unknown role %q", name)
+}
+
+// RoleMapper turns a token's claims into a role
+type RoleMapper struct {
+	claim   []string        // path to the claim holding groups or roles, e.g. realm_access.roles
+	mapping map[string]Role // claim value to role; nil maps role names to themselves
+}
+
+// NewRoleMapper returns a mapper reading the claim at the dotted path claim, and
+// granting roles by mapping, given as comma-separated value=role pairs such as
+// "renderer-admins=admin,renderer-users=render". An empty mapping accepts the
+// role names themselves as claim values.
+func NewRoleMapper(claim, mapping string) (*RoleMapper, error) {
+	if claim == "" {
+		claim = "roles"
+	}
+	m := &RoleMapper{claim: strings.Split(claim, ".")}
+
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		value, name, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("role mapping %q must be value=role", pair)
+		}
+		role, err := ParseRole(name)
+		if err != nil {
+			return nil, err
+		}
+		if m.mapping == nil {
+			m.mapping = make(map[string]Role)
+		}
+		m.mapping[strings.TrimSpace(value)] = role
+	}
+	return m, nil
+}
+
+// Role returns the highest role granted by claims
+func (m *RoleMapper) Role(claims Claims) Role {
+	var value interface{} = map[string]interface{}(claims)
+	for _, key := range m.claim {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return RoleNone
+		}
+		value = obj[key]
+	}
+
+	// Claims hold either a list of strings or a space-separated string, like "scope"
+	var values []string
+	switch v := value.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	best := RoleNone
+	for _, v := range values {
+		role, ok := m.mapping[v]
+		if m.mapping == nil {
+			parsed, err := ParseRole(v)
+			role, ok = parsed, err == nil
+		}
+		if ok && role > best {
+			best = role
+		}
+	}
+	return best
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string
+	Role    Role
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the request's caller
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller set by WithPrincipal
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRoleMapper(t *testing.T) {
+	direct, err := NewRoleMapper("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := NewRoleMapper("realm_access.roles", "renderer-admins=admin, renderer-users=render")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		mapper *RoleMapper
+		claims Claims
+		want   Role
+	}{
+		{"role names", direct, Claims{"roles": []interface{}{"read", "render"}}, RoleRender},
+		{"space separated", direct, Claims{"roles": "read admin"}, RoleAdmin},
+		{"unknown names", direct, Claims{"roles": []interface{}{"owner"}}, RoleNone},
+		{"missing claim", direct, Claims{"sub": "alice"}, RoleNone},
+		{"mapped nested claim", mapped, Claims{"realm_access": map[string]interface{}{"roles": []interface{}{"renderer-users"}}}, RoleRender},
+		{"mapping ignores role names", mapped, Claims{"realm_access": map[string]interface{}{"roles": []interface{}{"admin"}}}, RoleNone},
+		{"claim path through a non-object", mapped, Claims{"realm_access": "admin"}, RoleNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapper.Role(tt.claims); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := NewRoleMapper("", "group"); err == nil {
+		t.Error("Expected an error for a pair without a role")
+	}
+	if _, err := NewRoleMapper("", "group=superuser"); err == nil {
+		t.Error("Expected an error for an unknown role")
+	}
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	if _, ok := PrincipalFromContext(context.Background()); ok {
+		t.Error("Expected no principal on a bare context")
+	}
+	ctx := WithPrincipal(context.Background(), Principal{Subject: "alice", Role: RoleRead})
+	if p, ok := PrincipalFromContext(ctx); !ok || p.Subject != "alice" || p.Role != RoleRead {
+		t.Errorf("Unexpected principal %+v", p)
+	}
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -12,32 +15,145 @@ type Config struct {
 	Server   ServerConfig
 	Pixlet   PixletConfig
 	Redis    RedisConfig
+	Auth     AuthConfig
 	LogLevel string
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
-	Port         int
-	ReadTimeout  int
-	WriteTimeout int
+	Port                 int
+	ReadTimeout          int
+	WriteTimeout         int
+	ShutdownTimeout      int    // Default deadline in seconds for each shutdown stage (default: 10)
+	ShutdownDelay        int    // Seconds /readyz reports not ready before draining starts (default: 0)
+	ShutdownDrainTimeout int    // Seconds accepted render jobs get to finish (default: ShutdownTimeout)
+	ShutdownHTTPTimeout  int    // Seconds open requests get once the pool has drained (default: ShutdownTimeout)
+	PprofAddr            string // Listen address of the pprof admin server, e.g. "127.0.0.1:6060"; empty disables it
+	GRPCPort             int    // Port of the gRPC Renderer service; 0 disables it (default: 0)
+	CORSAllowedOrigins   string // Comma-separated browser origins allowed to call the API, or "*"; empty disables CORS
+	CORSAllowedMethods   string // Comma-separated methods allowed in cross-origin requests (default: GET,POST,DELETE)
+	CORSAllowedHeaders   string // Comma-separated request headers allowed in cross-origin requests (default: Content-Type,Authorization)
+	CORSMaxAge           int    // Seconds browsers may cache a preflight response (default: 600)
+	RateLimitRead        int    // Requests per second per client to listing and schema endpoints; 0 is unlimited (default: 0)
+	RateLimitReadBurst   int    // Requests a client may make at once to listing endpoints (default: RateLimitRead)
+	RateLimitRender      int    // Requests per second per client to render, preview and frame endpoints; 0 is unlimited (default: 0)
+	RateLimitRenderBurst int    // Requests a client may make at once to render endpoints (default: RateLimitRender)
+	RateLimitTrustProxy  bool   // Key anonymous clients by X-Forwarded-For instead of the connection address (default: false)
+	RateLimitShared      bool   // Keep client buckets in Redis so limits hold across replicas (default: false)
+	TLSCertFile          string // PEM certificate chain served over HTTPS; empty with no autocert domains serves plain HTTP
+	TLSKeyFile           string // PEM private key for TLSCertFile
+	TLSAutocertDomains   string // Comma-separated host names to obtain Let's Encrypt certificates for, instead of TLSCertFile
+	TLSAutocertCacheDir  string // Directory keeping obtained certificates across restarts; required with TLSAutocertDomains
+	TLSAutocertEmail     string // Optional contact address given to the ACME CA
+	TLSClientCAFile      string // PEM CA bundle client certificates are verified against; empty disables mTLS
+	TLSClientAuth        string // "require" rejects API requests without a verified client certificate, "optional" only verifies presented ones (default: require)
 }
 
 // PixletConfig holds Pixlet-related configuration
 type PixletConfig struct {
 	AppsPath               string
+	FontsPath              string // Optional directory of extra .bdf fonts registered with the runtime
 	SecretEncryptionKeyB64 string // Base64 encoded secret keyset for Pixlet
 	KeyEncryptionKeyB64    string // Base64 encoded key encryption key for Pixlet
-	RenderWorkers          int    // Number of concurrent render workers (default: 4)
+	RenderWorkers          int    // Number of concurrent render workers always running (default: 4)
+	RenderWorkersMax       int    // Upper bound when scaling workers with load; 0 disables scaling (default: 0)
+	WorkerScaleUpWaitMs    int    // Queue wait in milliseconds that adds a worker (default: 250)
+	WorkerIdleTimeout      int    // Seconds an extra worker may stay idle before exiting (default: 30)
+	QueueSize              int    // Jobs buffered per priority queue; 0 uses 2x max workers (default: 0)
+	QueueFullPolicy        string // "reject" fails jobs when the queue is full, "block" waits for room (default: reject)
+	WorkerAffinity         bool   // Route each app's jobs to the same worker, spilling over when it is busy (default: false)
+	QuarantineWorkers      int    // Workers reserved for apps that keep rendering slowly; 0 disables quarantine (default: 0)
+	QuarantineThresholdMs  int    // Render time in milliseconds that counts as slow; timeouts always do (default: 10000)
+	QuarantineStrikes      int    // Consecutive slow renders that quarantine an app (default: 3)
+	QuarantineDuration     int    // Seconds an app stays quarantined (default: 600)
 	RenderTimeout          int    // Render timeout in seconds (default: 30)
+	AdaptiveTimeout        bool   // Time out each app at a multiple of its recent p95, up to RenderTimeout (default: false)
+	AdaptiveTimeoutMin     int    // Floor in seconds for adaptive timeouts (default: 2)
+	SchemaWorkers          int    // Workers for schema loads and schema handler calls (default: 2)
+	SchemaTimeout          int    // Seconds a schema call may wait for a worker and run (default: 10)
+	HTTPHostRate           int    // Outbound app requests per second to one host; 0 is unlimited (default: 0)
+	HTTPHostBurst          int    // Outbound requests a host may receive at once (default: HTTPHostRate)
+	HTTPAppRate            int    // Outbound requests per second from one app; 0 is unlimited (default: 0)
+	HTTPAppBurst           int    // Outbound requests an app may send at once (default: HTTPAppRate)
+	HTTPBreakerFailures    int    // Consecutive failures that stop calls to a host; 0 disables (default: 5)
+	HTTPBreakerCooldown    int    // Seconds calls to a failing host are rejected (default: 30)
+	HTTPMaxConnsPerHost    int    // Outbound connections per host; 0 is unlimited (default: 0)
+	HTTPIdleConnsPerHost   int    // Idle outbound connections kept per host (default: 16)
+	HTTPRateLimitShared    bool   // Keep outbound host and app buckets in Redis so rates hold across replicas (default: false)
+	MaxExecutionSteps      int    // Starlark steps per render thread before it is aborted; 0 is unlimited (default: 0)
+	MaxHeapGrowthMB        int    // Process heap growth in MB while a render runs before it is aborted; 0 is unlimited (default: 0)
+	AppletCacheSize        int    // Number of loaded applets kept in memory, 0 disables caching (default: 64)
+	CacheScope             string // Scope of keys apps store with their cache module: global, app or device (default: app)
+	Warmup                 string // Startup warm-up: "load" pre-loads every app, "render" also dry-renders it (default: off)
+	RenderDedupe           bool   // Share one render between concurrent identical jobs (default: true)
+	RenderCacheTTL         int    // Seconds encoded output is cached, overridable per app; 0 disables (default: 0)
+	RequestIdempotencyTTL  int    // Seconds a rendered request UUID is remembered so redeliveries reuse its result; 0 disables (default: 300)
+	WebPLossy              bool   // Encode lossy instead of lossless WebP (default: false)
+	WebPQuality            int    // WebP quality 1-100 (default: 75)
+	WebPMethod             int    // WebP compression method 1-6, higher is smaller but slower (default: 4)
+	MaxFrameCount          int    // Maximum frames painted per render, overridable per app in its manifest (default: 2000)
+	DefaultWidth           int    // Device width used when a request doesn't specify one (default: 64)
+	DefaultHeight          int    // Device height used when a request doesn't specify one (default: 32)
+	AllowedSizes           string // Comma-separated WIDTHxHEIGHT whitelist, e.g. "64x32,128x64" (default: any size)
+	DeviceProfilesPath     string // Optional YAML file of per-device color profiles
+	AllowDestructive       bool   // Allow deleting apps and replacing them on install (default: false)
+}
+
+// Default Redis names
+const (
+	DefaultStreamKey      = "matrx:render_requests"
+	DefaultControlChannel = "matrx:control"
+)
+
+// DefaultConsumerName returns the hostname, which is stable across restarts of a
+// pod or host, as the consumer name of this instance
+func DefaultConsumerName() string {
+	if hostname, _ := os.Hostname(); hostname != "" {
+		return hostname
+	}
+	return "matrx-renderer"
 }
 
 // RedisConfig holds Redis-related configuration
 type RedisConfig struct {
-	Addr          string
-	Password      string
-	DB            int
-	ConsumerGroup string // Consumer group name for streams
-	ConsumerName  string // Consumer name (unique per instance)
+	Addr                  string
+	Password              string
+	DB                    int
+	ConsumerGroup         string // Consumer group name for streams
+	ConsumerName          string // Consumer name (unique per instance), as looked up by readiness diagnostics (default: hostname)
+	StreamKey             string // Stream render requests are read from (default: matrx:render_requests)
+	StreamMaxLen          int    // Approximate entry cap on the render requests stream; 0 disables (default: 0)
+	StreamMaxAge          int    // Seconds stream entries are kept; 0 disables (default: 0)
+	StreamTrimInterval    int    // Seconds between stream trims (default: 60)
+	StreamMetricsInterval int    // Seconds between samples of stream and consumer group metrics; 0 disables (default: 15)
+	CacheFillWaitMs       int    // Milliseconds an applet cache miss waits for another render refilling the key; 0 disables (default: 2000)
+	ControlChannel        string // Pub/sub channel replicas take commands such as apps_updated from; empty disables (default: matrx:control)
+
+	// Connection pool, per replica and, in a cluster, per node
+	PoolSize       int // Connections kept per pool (default: 10)
+	PoolTimeoutMs  int // Milliseconds a command waits for a free connection when all are busy (default: 30000)
+	DialTimeoutMs  int // Milliseconds allowed to open a connection (default: 5000)
+	ReadTimeoutMs  int // Milliseconds allowed for a reply; -1 waits forever (default: 3000)
+	WriteTimeoutMs int // Milliseconds allowed to send a command; -1 waits forever (default: 3000)
+
+	// Sentinel: when SentinelMasterName is set, the master is discovered through
+	// SentinelAddrs and Addr is ignored
+	SentinelMasterName string   // Name of the master monitored by Sentinel
+	SentinelAddrs      []string // Sentinel host:port addresses
+	SentinelPassword   string   // Password for the Sentinels themselves, if different
+
+	// ClusterAddrs, when set, connects to a Redis Cluster through these seed
+	// nodes instead of Addr. Cluster has no databases, so DB is ignored.
+	ClusterAddrs []string
+}
+
+// AuthConfig holds HTTP API authentication configuration
+type AuthConfig struct {
+	JWTIssuer   string // Identity provider issuer URL; empty disables authentication
+	JWTAudience string // Audience tokens must be issued for; empty skips the check
+	JWKSURL     string // Signing keys URL (default: discovered from the issuer)
+	RolesClaim  string // Dotted path to the claim holding roles or groups (default: roles)
+	RoleMapping string // Comma-separated claim value=role pairs; empty accepts role names as-is
 }
 
 // Load loads configuration from environment variables
@@ -45,29 +161,144 @@ func Load() (*Config, error) {
 	// Load .env file if it exists (optional)
 	_ = godotenv.Load()
 
+	shutdownTimeout := getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT", 10)
+
 	cfg := &Config{
 		Server: ServerConfig{
-			Port:         getEnvAsInt("SERVER_PORT", 8080),
-			ReadTimeout:  getEnvAsInt("SERVER_READ_TIMEOUT", 10),
-			WriteTimeout: getEnvAsInt("SERVER_WRITE_TIMEOUT", 10),
+			Port:                 getEnvAsInt("SERVER_PORT", 8080),
+			ReadTimeout:          getEnvAsInt("SERVER_READ_TIMEOUT", 10),
+			WriteTimeout:         getEnvAsInt("SERVER_WRITE_TIMEOUT", 10),
+			ShutdownTimeout:      shutdownTimeout,
+			ShutdownDelay:        getEnvAsInt("SERVER_SHUTDOWN_DELAY", 0),
+			ShutdownDrainTimeout: getEnvAsInt("SERVER_SHUTDOWN_DRAIN_TIMEOUT", shutdownTimeout),
+			ShutdownHTTPTimeout:  getEnvAsInt("SERVER_SHUTDOWN_HTTP_TIMEOUT", shutdownTimeout),
+			PprofAddr:            getEnv("SERVER_PPROF_ADDR", ""),
+			GRPCPort:             getEnvAsInt("SERVER_GRPC_PORT", 0),
+			CORSAllowedOrigins:   getEnv("SERVER_CORS_ALLOWED_ORIGINS", ""),
+			CORSAllowedMethods:   getEnv("SERVER_CORS_ALLOWED_METHODS", "GET,POST,DELETE"),
+			CORSAllowedHeaders:   getEnv("SERVER_CORS_ALLOWED_HEADERS", "Content-Type,Authorization,Idempotency-Key"),
+			CORSMaxAge:           getEnvAsInt("SERVER_CORS_MAX_AGE", 600),
+			RateLimitRead:        getEnvAsInt("SERVER_RATE_LIMIT_READ", 0),
+			RateLimitReadBurst:   getEnvAsInt("SERVER_RATE_LIMIT_READ_BURST", 0),
+			RateLimitRender:      getEnvAsInt("SERVER_RATE_LIMIT_RENDER", 0),
+			RateLimitRenderBurst: getEnvAsInt("SERVER_RATE_LIMIT_RENDER_BURST", 0),
+			RateLimitTrustProxy:  getEnvAsBool("SERVER_RATE_LIMIT_TRUST_PROXY", false),
+			RateLimitShared:      getEnvAsBool("SERVER_RATE_LIMIT_SHARED", false),
+			TLSCertFile:          getEnv("SERVER_TLS_CERT_FILE", ""),
+			TLSKeyFile:           getEnv("SERVER_TLS_KEY_FILE", ""),
+			TLSAutocertDomains:   getEnv("SERVER_TLS_AUTOCERT_DOMAINS", ""),
+			TLSAutocertCacheDir:  getEnv("SERVER_TLS_AUTOCERT_CACHE_DIR", ""),
+			TLSAutocertEmail:     getEnv("SERVER_TLS_AUTOCERT_EMAIL", ""),
+			TLSClientCAFile:      getEnv("SERVER_TLS_CLIENT_CA_FILE", ""),
+			TLSClientAuth:        getEnv("SERVER_TLS_CLIENT_AUTH", "require"),
 		},
 		Pixlet: PixletConfig{
 			AppsPath:               getEnv("PIXLET_APPS_PATH", "/opt/apps"),
+			FontsPath:              getEnv("PIXLET_FONTS_PATH", ""),
 			SecretEncryptionKeyB64: getEnv("PIXLET_SECRET_KEYSET_B64", ""),
 			KeyEncryptionKeyB64:    getEnv("PIXLET_KEY_ENCRYPTION_KEY_B64", ""),
 			RenderWorkers:          getEnvAsInt("PIXLET_RENDER_WORKERS", 4),
+			RenderWorkersMax:       getEnvAsInt("PIXLET_RENDER_WORKERS_MAX", 0),
+			WorkerScaleUpWaitMs:    getEnvAsInt("PIXLET_WORKER_SCALE_UP_WAIT_MS", 250),
+			WorkerIdleTimeout:      getEnvAsInt("PIXLET_WORKER_IDLE_TIMEOUT", 30),
+			QueueSize:              getEnvAsInt("PIXLET_QUEUE_SIZE", 0),
+			QueueFullPolicy:        getEnv("PIXLET_QUEUE_FULL_POLICY", "reject"),
+			WorkerAffinity:         getEnvAsBool("PIXLET_WORKER_AFFINITY", false),
+			QuarantineWorkers:      getEnvAsInt("PIXLET_QUARANTINE_WORKERS", 0),
+			QuarantineThresholdMs:  getEnvAsInt("PIXLET_QUARANTINE_THRESHOLD_MS", 10000),
+			QuarantineStrikes:      getEnvAsInt("PIXLET_QUARANTINE_STRIKES", 3),
+			QuarantineDuration:     getEnvAsInt("PIXLET_QUARANTINE_DURATION", 600),
 			RenderTimeout:          getEnvAsInt("PIXLET_RENDER_TIMEOUT", 30),
+			AdaptiveTimeout:        getEnvAsBool("PIXLET_ADAPTIVE_TIMEOUT", false),
+			AdaptiveTimeoutMin:     getEnvAsInt("PIXLET_ADAPTIVE_TIMEOUT_MIN", 2),
+			SchemaWorkers:          getEnvAsInt("PIXLET_SCHEMA_WORKERS", 2),
+			SchemaTimeout:          getEnvAsInt("PIXLET_SCHEMA_TIMEOUT", 10),
+			HTTPHostRate:           getEnvAsInt("PIXLET_HTTP_HOST_RATE", 0),
+			HTTPHostBurst:          getEnvAsInt("PIXLET_HTTP_HOST_BURST", 0),
+			HTTPAppRate:            getEnvAsInt("PIXLET_HTTP_APP_RATE", 0),
+			HTTPAppBurst:           getEnvAsInt("PIXLET_HTTP_APP_BURST", 0),
+			HTTPBreakerFailures:    getEnvAsInt("PIXLET_HTTP_BREAKER_FAILURES", 5),
+			HTTPBreakerCooldown:    getEnvAsInt("PIXLET_HTTP_BREAKER_COOLDOWN", 30),
+			HTTPMaxConnsPerHost:    getEnvAsInt("PIXLET_HTTP_MAX_CONNS_PER_HOST", 0),
+			HTTPIdleConnsPerHost:   getEnvAsInt("PIXLET_HTTP_IDLE_CONNS_PER_HOST", 16),
+			HTTPRateLimitShared:    getEnvAsBool("PIXLET_HTTP_RATE_LIMIT_SHARED", false),
+			MaxExecutionSteps:      getEnvAsInt("PIXLET_MAX_EXECUTION_STEPS", 0),
+			MaxHeapGrowthMB:        getEnvAsInt("PIXLET_MAX_HEAP_GROWTH_MB", 0),
+			AppletCacheSize:        getEnvAsInt("PIXLET_APPLET_CACHE_SIZE", 64),
+			CacheScope:             getEnv("PIXLET_CACHE_SCOPE", "app"),
+			Warmup:                 getEnv("PIXLET_WARMUP", "off"),
+			RenderDedupe:           getEnvAsBool("PIXLET_RENDER_DEDUPE", true),
+			RenderCacheTTL:         getEnvAsInt("PIXLET_RENDER_CACHE_TTL", 0),
+			RequestIdempotencyTTL:  getEnvAsInt("PIXLET_REQUEST_IDEMPOTENCY_TTL", 300),
+			WebPLossy:              getEnvAsBool("PIXLET_WEBP_LOSSY", false),
+			WebPQuality:            getEnvAsInt("PIXLET_WEBP_QUALITY", 75),
+			WebPMethod:             getEnvAsInt("PIXLET_WEBP_METHOD", 4),
+			MaxFrameCount:          getEnvAsInt("PIXLET_MAX_FRAME_COUNT", 2000),
+			DefaultWidth:           getEnvAsInt("PIXLET_DEFAULT_WIDTH", 64),
+			DefaultHeight:          getEnvAsInt("PIXLET_DEFAULT_HEIGHT", 32),
+			AllowedSizes:           getEnv("PIXLET_ALLOWED_SIZES", ""),
+			DeviceProfilesPath:     getEnv("PIXLET_DEVICE_PROFILES_PATH", ""),
+			AllowDestructive:       getEnvAsBool("PIXLET_ALLOW_DESTRUCTIVE", false),
 		},
 		Redis: RedisConfig{
-			Addr:          getRedisAddr(),
-			Password:      getEnv("REDIS_PASSWORD", ""),
-			DB:            getEnvAsInt("REDIS_DB", 0),
-			ConsumerGroup: getEnv("REDIS_CONSUMER_GROUP", "matrx-renderer-group"),
-			ConsumerName:  getEnv("REDIS_CONSUMER_NAME", ""),
+			Addr:                  getRedisAddr(),
+			Password:              getEnv("REDIS_PASSWORD", ""),
+			DB:                    getEnvAsInt("REDIS_DB", 0),
+			ConsumerGroup:         getEnv("REDIS_CONSUMER_GROUP", "matrx-renderer-group"),
+			ConsumerName:          getEnv("REDIS_CONSUMER_NAME", DefaultConsumerName()),
+			StreamKey:             getEnv("REDIS_STREAM_KEY", DefaultStreamKey),
+			StreamMaxLen:          getEnvAsInt("REDIS_STREAM_MAXLEN", 0),
+			StreamMaxAge:          getEnvAsInt("REDIS_STREAM_MAX_AGE", 0),
+			StreamTrimInterval:    getEnvAsInt("REDIS_STREAM_TRIM_INTERVAL", 60),
+			StreamMetricsInterval: getEnvAsInt("REDIS_STREAM_METRICS_INTERVAL", 15),
+			CacheFillWaitMs:       getEnvAsInt("REDIS_CACHE_FILL_WAIT_MS", 2000),
+			ControlChannel:        getEnvOrEmpty("REDIS_CONTROL_CHANNEL", DefaultControlChannel),
+			PoolSize:              getEnvAsInt("REDIS_POOL_SIZE", 10),
+			PoolTimeoutMs:         getEnvAsInt("REDIS_POOL_TIMEOUT_MS", 30000),
+			DialTimeoutMs:         getEnvAsInt("REDIS_DIAL_TIMEOUT_MS", 5000),
+			ReadTimeoutMs:         getEnvAsInt("REDIS_READ_TIMEOUT_MS", 3000),
+			WriteTimeoutMs:        getEnvAsInt("REDIS_WRITE_TIMEOUT_MS", 3000),
+			SentinelMasterName:    getEnv("REDIS_SENTINEL_MASTER", ""),
+			SentinelAddrs:         getEnvAsList("REDIS_SENTINEL_ADDRS"),
+			SentinelPassword:      getEnv("REDIS_SENTINEL_PASSWORD", ""),
+			ClusterAddrs:          getEnvAsList("REDIS_CLUSTER_ADDRS"),
+		},
+		Auth: AuthConfig{
+			JWTIssuer:   getEnv("AUTH_JWT_ISSUER", ""),
+			JWTAudience: getEnv("AUTH_JWT_AUDIENCE", ""),
+			JWKSURL:     getEnv("AUTH_JWKS_URL", ""),
+			RolesClaim:  getEnv("AUTH_ROLES_CLAIM", "roles"),
+			RoleMapping: getEnv("AUTH_ROLE_MAPPING", ""),
 		},
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 	}
 
+	if cfg.Redis.SentinelMasterName != "" && len(cfg.Redis.SentinelAddrs) == 0 {
+		return nil, errors.New("REDIS_SENTINEL_ADDRS is required with REDIS_SENTINEL_MASTER")
+	}
+	switch cfg.Pixlet.CacheScope {
+	case "global", "app", "device":
+	default:
+		return nil, fmt.Errorf("invalid PIXLET_CACHE_SCOPE %q: want global, app or device", cfg.Pixlet.CacheScope)
+	}
+	switch cfg.Pixlet.QueueFullPolicy {
+	case "reject", "block":
+	default:
+		return nil, fmt.Errorf("invalid PIXLET_QUEUE_FULL_POLICY %q: want reject or block", cfg.Pixlet.QueueFullPolicy)
+	}
+	if cfg.Redis.SentinelMasterName != "" && len(cfg.Redis.ClusterAddrs) > 0 {
+		return nil, errors.New("set REDIS_SENTINEL_MASTER or REDIS_CLUSTER_ADDRS, not both")
+	}
+	if cfg.Redis.PoolSize < 1 {
+		return nil, errors.New("REDIS_POOL_SIZE must be at least 1")
+	}
+	if cfg.Pixlet.WebPQuality < 1 || cfg.Pixlet.WebPQuality > 100 {
+		return nil, fmt.Errorf("PIXLET_WEBP_QUALITY must be between 1 and 100, got %d", cfg.Pixlet.WebPQuality)
+	}
+	if cfg.Pixlet.WebPMethod < 1 || cfg.Pixlet.WebPMethod > 6 {
+		return nil, fmt.Errorf("PIXLET_WEBP_METHOD must be between 1 and 6, got %d", cfg.Pixlet.WebPMethod)
+	}
+
 	return cfg, nil
 }
 
@@ -79,6 +310,15 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvOrEmpty is getEnv, except that a variable set but empty overrides the
+// default, so a setting with a default can be turned off
+func getEnvOrEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 // getEnvAsInt gets an environment variable as int or returns a default value
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
@@ -89,6 +329,28 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsBool gets an environment variable as bool or returns a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList gets a comma-separated environment variable as a list, skipping
+// empty items
+func getEnvAsList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // getRedisAddr gets Redis address, supporting both REDIS_URL and REDIS_ADDR formats
 func getRedisAddr() string {
 	// Check for REDIS_URL first (format: redis://host:port)
@@ -50,6 +50,33 @@ func TestGetEnvAsInt(t *testing.T) {
 	})
 }
 
+func TestGetEnvAsBool(t *testing.T) {
+	t.Run("valid bool", func(t *testing.T) {
+		os.Setenv("TEST_BOOL", "true")
+		defer os.Unsetenv("TEST_BOOL")
+
+		if got := getEnvAsBool("TEST_BOOL", false); !got {
+			t.Errorf("got %v, want true", got)
+		}
+	})
+
+	t.Run("invalid bool returns default", func(t *testing.T) {
+		os.Setenv("TEST_BOOL_BAD", "maybe")
+		defer os.Unsetenv("TEST_BOOL_BAD")
+
+		if got := getEnvAsBool("TEST_BOOL_BAD", true); !got {
+			t.Errorf("got %v, want true", got)
+		}
+	})
+
+	t.Run("unset returns default", func(t *testing.T) {
+		os.Unsetenv("TEST_BOOL_MISSING")
+		if got := getEnvAsBool("TEST_BOOL_MISSING", false); got {
+			t.Errorf("got %v, want false", got)
+		}
+	})
+}
+
 func TestGetRedisAddr(t *testing.T) {
 	// Save and clear all redis env vars
 	origURL := os.Getenv("REDIS_URL")
@@ -96,6 +123,184 @@ func TestGetRedisAddr(t *testing.T) {
 	})
 }
 
+func TestGetEnvAsList(t *testing.T) {
+	os.Setenv("TEST_LIST", " a:26379, ,b:26379,")
+	defer os.Unsetenv("TEST_LIST")
+
+	got := getEnvAsList("TEST_LIST")
+	if len(got) != 2 || got[0] != "a:26379" || got[1] != "b:26379" {
+		t.Errorf("got %q, want [a:26379 b:26379]", got)
+	}
+
+	os.Unsetenv("TEST_LIST_MISSING")
+	if got := getEnvAsList("TEST_LIST_MISSING"); len(got) != 0 {
+		t.Errorf("got %q, want none", got)
+	}
+}
+
+func TestLoad_Sentinel(t *testing.T) {
+	os.Setenv("REDIS_SENTINEL_MASTER", "mymaster")
+	defer os.Unsetenv("REDIS_SENTINEL_MASTER")
+
+	t.Run("requires sentinel addresses", func(t *testing.T) {
+		os.Unsetenv("REDIS_SENTINEL_ADDRS")
+		if _, err := Load(); err == nil {
+			t.Error("Expected an error without REDIS_SENTINEL_ADDRS")
+		}
+	})
+
+	t.Run("loads sentinel addresses", func(t *testing.T) {
+		os.Setenv("REDIS_SENTINEL_ADDRS", "s1:26379,s2:26379")
+		defer os.Unsetenv("REDIS_SENTINEL_ADDRS")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if cfg.Redis.SentinelMasterName != "mymaster" || len(cfg.Redis.SentinelAddrs) != 2 {
+			t.Errorf("got %+v", cfg.Redis)
+		}
+	})
+
+	t.Run("rejects sentinel with cluster", func(t *testing.T) {
+		os.Setenv("REDIS_SENTINEL_ADDRS", "s1:26379")
+		os.Setenv("REDIS_CLUSTER_ADDRS", "c1:6379")
+		defer os.Unsetenv("REDIS_SENTINEL_ADDRS")
+		defer os.Unsetenv("REDIS_CLUSTER_ADDRS")
+
+		if _, err := Load(); err == nil {
+			t.Error("Expected an error with both Sentinel and Cluster")
+		}
+	})
+}
+
+func TestLoad_CacheScope(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Pixlet.CacheScope != "app" {
+		t.Errorf("Expected app scoping by default, got %q", cfg.Pixlet.CacheScope)
+	}
+
+	os.Setenv("PIXLET_CACHE_SCOPE", "user")
+	defer os.Unsetenv("PIXLET_CACHE_SCOPE")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for an unknown cache scope")
+	}
+}
+
+func TestLoad_AllowDestructive(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Pixlet.AllowDestructive {
+		t.Error("Expected destructive app operations to be off by default")
+	}
+
+	os.Setenv("PIXLET_ALLOW_DESTRUCTIVE", "true")
+	defer os.Unsetenv("PIXLET_ALLOW_DESTRUCTIVE")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.Pixlet.AllowDestructive {
+		t.Error("Expected PIXLET_ALLOW_DESTRUCTIVE=true to allow destructive app operations")
+	}
+}
+
+func TestLoad_QueueFullPolicy(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Pixlet.QueueFullPolicy != "reject" {
+		t.Errorf("Expected reject by default, got %q", cfg.Pixlet.QueueFullPolicy)
+	}
+
+	os.Setenv("PIXLET_QUEUE_FULL_POLICY", "drop")
+	defer os.Unsetenv("PIXLET_QUEUE_FULL_POLICY")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for an unknown queue full policy")
+	}
+}
+
+func TestLoad_WebP(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Pixlet.WebPQuality != 75 || cfg.Pixlet.WebPMethod != 4 {
+		t.Errorf("Expected quality 75 and method 4 by default, got %d and %d", cfg.Pixlet.WebPQuality, cfg.Pixlet.WebPMethod)
+	}
+
+	for _, env := range []struct{ key, value string }{
+		{"PIXLET_WEBP_QUALITY", "0"},
+		{"PIXLET_WEBP_QUALITY", "101"},
+		{"PIXLET_WEBP_METHOD", "0"},
+		{"PIXLET_WEBP_METHOD", "7"},
+	} {
+		os.Setenv(env.key, env.value)
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected an error for %s=%s", env.key, env.value)
+		}
+		os.Unsetenv(env.key)
+	}
+}
+
+func TestDefaultConsumerName(t *testing.T) {
+	hostname, _ := os.Hostname()
+	if got := DefaultConsumerName(); got == "" || (hostname != "" && got != hostname) {
+		t.Errorf("DefaultConsumerName() = %q, want the hostname %q", got, hostname)
+	}
+	if cfg, err := Load(); err != nil || cfg.Redis.ConsumerName != DefaultConsumerName() {
+		t.Errorf("Expected the default consumer name, got %v", err)
+	}
+}
+
+func TestLoad_ControlChannel(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Redis.ControlChannel != DefaultControlChannel {
+		t.Errorf("Expected %q by default, got %q", DefaultControlChannel, cfg.Redis.ControlChannel)
+	}
+
+	os.Setenv("REDIS_CONTROL_CHANNEL", "")
+	defer os.Unsetenv("REDIS_CONTROL_CHANNEL")
+	if cfg, _ = Load(); cfg.Redis.ControlChannel != "" {
+		t.Errorf("Expected an empty variable to disable the channel, got %q", cfg.Redis.ControlChannel)
+	}
+}
+
+func TestLoad_RedisPool(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Redis.PoolSize != 10 || cfg.Redis.PoolTimeoutMs != 30000 || cfg.Redis.DialTimeoutMs != 5000 || cfg.Redis.ReadTimeoutMs != 3000 || cfg.Redis.WriteTimeoutMs != 3000 {
+		t.Errorf("Unexpected defaults: %+v", cfg.Redis)
+	}
+
+	os.Setenv("REDIS_POOL_SIZE", "64")
+	os.Setenv("REDIS_READ_TIMEOUT_MS", "500")
+	defer os.Unsetenv("REDIS_POOL_SIZE")
+	defer os.Unsetenv("REDIS_READ_TIMEOUT_MS")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Redis.PoolSize != 64 || cfg.Redis.ReadTimeoutMs != 500 {
+		t.Errorf("got %+v", cfg.Redis)
+	}
+
+	os.Setenv("REDIS_POOL_SIZE", "0")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for an empty pool")
+	}
+}
+
 func setOrUnset(key, val string) {
 	if val == "" {
 		os.Unsetenv(key)
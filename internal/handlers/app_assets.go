@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+// appAssetCacheControl lets browsers and proxies keep app artwork for an hour
+const appAssetCacheControl = "public, max-age=3600"
+
+// appAssetTypes are the image types served from app directories. Anything else,
+// such as the app's source or manifest, stays private.
+var appAssetTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// handleAppIcon handles GET /apps/{id}/icon - serves the image named by the
+// manifest's icon field
+func (h *AppHandler) handleAppIcon(w http.ResponseWriter, r *http.Request, app *models.AppManifest) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if app.Icon == "" {
+		writeError(w, r, http.StatusNotFound, "App has no icon")
+		return
+	}
+	h.serveAppAsset(w, r, app, app.Icon)
+}
+
+// handleAppAsset handles GET /apps/{id}/assets/{name} - serves an image from the
+// app directory, such as a screenshot listed in the manifest
+func (h *AppHandler) handleAppAsset(w http.ResponseWriter, r *http.Request, app *models.AppManifest, name string) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	h.serveAppAsset(w, r, app, name)
+}
+
+// serveAppAsset serves name from the app directory if it is an image that
+// resolves inside it. ServeContent answers conditional and range requests.
+func (h *AppHandler) serveAppAsset(w http.ResponseWriter, r *http.Request, app *models.AppManifest, name string) {
+	contentType, ok := appAssetTypes[strings.ToLower(filepath.Ext(name))]
+	if !ok || !filepath.IsLocal(filepath.FromSlash(name)) {
+		writeError(w, r, http.StatusNotFound, "Asset not found")
+		return
+	}
+
+	// Resolve symlinks so a link can't reach outside the app directory
+	dir, err := filepath.EvalSymlinks(app.DirectoryPath)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, "Asset not found")
+		return
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, "Asset not found")
+		return
+	}
+	if rel, err := filepath.Rel(dir, path); err != nil || !filepath.IsLocal(rel) {
+		writeError(w, r, http.StatusNotFound, "Asset not found")
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, "Asset not found")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		writeError(w, r, http.StatusNotFound, "Asset not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", appAssetCacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+
+	h.logger.Debug("Served app asset",
+		zap.String("app_id", app.ID),
+		zap.String("asset", name))
+}
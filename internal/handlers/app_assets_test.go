@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupAssetHandler adds an icon, a screenshot and a symlink escaping the app
+// directory to the test app
+func setupAssetHandler(t *testing.T) (*AppHandler, []byte) {
+	t.Helper()
+	h := setupTestHandler(t)
+	app, _ := h.processor.GetAppRegistry().GetApp("test-app")
+
+	var icon bytes.Buffer
+	png.Encode(&icon, image.NewRGBA(image.Rect(0, 0, 8, 8)))
+	os.MkdirAll(filepath.Join(app.DirectoryPath, "screenshots"), 0755)
+	for _, name := range []string{"icon.png", "screenshots/one.png"} {
+		if err := os.WriteFile(filepath.Join(app.DirectoryPath, name), icon.Bytes(), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	outside := filepath.Join(t.TempDir(), "secret.png")
+	os.WriteFile(outside, []byte("secret"), 0644)
+	if err := os.Symlink(outside, filepath.Join(app.DirectoryPath, "escape.png")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	manifest, _ := os.ReadFile(filepath.Join(app.DirectoryPath, "manifest.yaml"))
+	manifest = append(manifest, "icon: icon.png\nscreenshots:\n  - screenshots/one.png\n"...)
+	os.WriteFile(filepath.Join(app.DirectoryPath, "manifest.yaml"), manifest, 0644)
+	if err := h.processor.RefreshAppRegistry(); err != nil {
+		t.Fatalf("Failed to refresh apps: %v", err)
+	}
+	return h, icon.Bytes()
+}
+
+func TestAppIcon(t *testing.T) {
+	h, icon := setupAssetHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/apps/test-app/icon", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", ct)
+	}
+	if w.Header().Get("Cache-Control") != appAssetCacheControl {
+		t.Errorf("Cache-Control = %q", w.Header().Get("Cache-Control"))
+	}
+	if !bytes.Equal(w.Body.Bytes(), icon) {
+		t.Error("Icon body does not match the file")
+	}
+
+	// Conditional requests are answered from Last-Modified
+	req = httptest.NewRequest(http.MethodGet, "/apps/test-app/icon", nil)
+	req.Header.Set("If-Modified-Since", w.Header().Get("Last-Modified"))
+	w = httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", w.Code)
+	}
+}
+
+func TestAppIcon_None(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/apps/test-app/icon", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+}
+
+func TestAppAsset(t *testing.T) {
+	h, _ := setupAssetHandler(t)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/apps/test-app/assets/screenshots/one.png", http.StatusOK},
+		{"/apps/test-app/assets/icon.png", http.StatusOK},
+		{"/apps/test-app/assets/missing.png", http.StatusNotFound},
+		{"/apps/test-app/assets/test-app.star", http.StatusNotFound},
+		{"/apps/test-app/assets/manifest.yaml", http.StatusNotFound},
+		{"/apps/test-app/assets/../test-app/icon.png", http.StatusNotFound},
+		{"/apps/test-app/assets/escape.png", http.StatusNotFound},
+		{"/apps/test-app/assets/screenshots", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		h.handleAppDetails(w, req)
+		if w.Code != tt.want {
+			t.Errorf("GET %s: expected %d, got %d", tt.path, tt.want, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/apps/test-app/assets/icon.png", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
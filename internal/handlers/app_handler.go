@@ -1,30 +1,40 @@
 package handlers
 
 import (
+	"archive/zip"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image/png"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"os"
+	"net/textproto"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/koios/matrx-renderer/api"
 	"github.com/koios/matrx-renderer/internal/pixlet"
 	"github.com/koios/matrx-renderer/pkg/models"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
-const (
-	defaultDeviceWidth  = 64
-	defaultDeviceHeight = 32
-)
-
 // AppHandler handles HTTP requests for app management
 type AppHandler struct {
 	processor *pixlet.Processor
 	validator *Validator
+	cors      *CORS // Origins besides our own allowed to open /ws
+	live      *liveSessions
+	jobs      *renderJobs
+	closing   chan struct{} // Closed by CloseStreams
+	closeOnce sync.Once
+	draining  atomic.Bool // Set by StartDraining
 	logger    *zap.Logger
 }
 
@@ -33,60 +43,326 @@ func NewAppHandler(processor *pixlet.Processor, logger *zap.Logger) *AppHandler
 	return &AppHandler{
 		processor: processor,
 		validator: NewValidator(processor, logger),
+		live:      newLiveSessions(),
+		jobs:      newRenderJobs(processor.JobStore(), logger),
+		closing:   make(chan struct{}),
 		logger:    logger,
 	}
 }
 
-// RegisterRoutes registers the app management routes
+// RegisterRoutes registers the app management routes under /v1, and at their
+// legacy unversioned paths
 func (h *AppHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/health", h.handleHealth)
-	mux.HandleFunc("/apps", h.handleApps)
-	mux.HandleFunc("/apps/refresh", h.handleAppsRefresh)
-	mux.HandleFunc("/apps/", h.handleAppDetails)
-	mux.HandleFunc("/swagger.json", h.handleSwagger)
+	routes := http.NewServeMux()
+	routes.HandleFunc("/health", h.handleHealth)
+	routes.HandleFunc("/livez", h.handleLivez)
+	routes.HandleFunc("/readyz", h.handleReadyz)
+	routes.HandleFunc("/apps", h.handleApps)
+	routes.HandleFunc("/apps/refresh", h.handleAppsRefresh)
+	routes.HandleFunc("/apps/search", h.handleAppSearch)
+	routes.HandleFunc("/apps/", h.handleAppDetails)
+	routes.HandleFunc("/swagger.json", h.handleSwagger)
+	routes.HandleFunc("/docs", h.handleDocs)
+	routes.Handle("/metrics", promhttp.Handler())
+	routes.HandleFunc("/stats", h.handleStats)
+	routes.HandleFunc("/admin/queues", h.handleAdminQueues)
+	routes.HandleFunc("/admin/cache/stats", h.handleAdminCacheStats)
+	routes.HandleFunc("/admin/cache/", h.handleAdminCacheFlush)
+	routes.HandleFunc("/ws", h.handleWebSocket)
+	routes.HandleFunc("/render-jobs/", h.handleRenderJob)
+
+	api := withAPIVersion(routes)
+	mux.Handle(apiPrefix+"/", http.StripPrefix(apiPrefix, api))
+	mux.Handle("/", api)
 }
 
-// handleHealth handles GET /health - returns service health status
+// healthDegradingChecks are readiness checks whose failure leaves the service
+// degraded rather than unhealthy. Redis only backs the caches, so renders
+// continue without it, just slower.
+var healthDegradingChecks = map[string]bool{"redis": true}
+
+// handleHealth handles GET /health - service status for dashboards and
+// operators. Unlike the probes it grades the readiness checks: "degraded" when
+// only a cache dependency is down, "unhealthy" (503) when renders can't be
+// served at all.
 func (h *AppHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Simple health check - just return OK if we can respond
+	_, checks := h.processor.Readiness(r.Context())
+	status := healthStatus(checks)
+	code := http.StatusOK
+	if status == "unhealthy" {
+		code = http.StatusServiceUnavailable
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "healthy",
-		"service": "matrx-renderer",
-		"version": "1.0.0",
+		"status":      status,
+		"service":     "matrx-renderer",
+		"version":     "1.0.0",
+		"api_version": APIVersion,
+		"checks":      checks,
 	})
 }
 
-// handleApps handles GET /apps - returns list of all apps
+// healthStatus grades readiness checks as "healthy", "degraded" or "unhealthy"
+func healthStatus(checks []pixlet.ReadinessCheck) string {
+	status := "healthy"
+	for _, c := range checks {
+		switch {
+		case c.OK:
+		case healthDegradingChecks[c.Name]:
+			status = "degraded"
+		default:
+			return "unhealthy"
+		}
+	}
+	return status
+}
+
+// handleLivez handles GET /livez - the liveness probe. It only shows the
+// process is serving requests; dependencies are left to /readyz, so an outage
+// elsewhere doesn't get the instance restarted.
+func (h *AppHandler) handleLivez(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// StartDraining makes /readyz report not ready for the rest of the process's
+// life, so load balancers stop routing new requests here ahead of shutdown
+func (h *AppHandler) StartDraining() {
+	h.draining.Store(true)
+}
+
+// ReadinessResponse is returned by GET /readyz
+type ReadinessResponse struct {
+	Status string                  `json:"status"` // "ready" or "not_ready"
+	Checks []pixlet.ReadinessCheck `json:"checks"`
+}
+
+// handleReadyz handles GET /readyz - the readiness probe. It answers 503 while
+// the apps path can't be loaded, the worker pool is shutting down or Redis is
+// unreachable, and once StartDraining is called, so traffic is routed to other
+// instances.
+func (h *AppHandler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ready, checks := h.processor.Readiness(r.Context())
+	if h.draining.Load() {
+		ready = false
+		checks = append(checks, pixlet.ReadinessCheck{Name: "shutdown", Error: "server is shutting down"})
+	}
+	response := ReadinessResponse{Status: "ready", Checks: checks}
+	status := http.StatusOK
+	if !ready {
+		response.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+		h.logger.Warn("Readiness check failed", zap.Any("checks", checks))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	h.writeJSON(w, status, response)
+}
+
+// handleStats handles GET /stats - returns processor and worker pool statistics
+func (h *AppHandler) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.processor.Stats(r.Context())); err != nil {
+		h.logger.Error("Failed to encode stats response", zap.Error(err))
+	}
+}
+
+// handleAdminQueues handles GET /admin/queues - reports the render requests
+// stream down to each consumer's pending entries, so a backlog can be diagnosed
+// without redis-cli
+func (h *AppHandler) handleAdminQueues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	stats, err := h.processor.QueueStats(r.Context())
+	if errors.Is(err, pixlet.ErrNoRedis) {
+		writeError(w, r, http.StatusNotFound, "No render queue without Redis")
+		return
+	}
+	if err != nil {
+		h.logger.Warn("Failed to inspect render queue", zap.Error(err))
+		writeError(w, r, http.StatusServiceUnavailable, "Failed to inspect render queue")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("Failed to encode queue stats response", zap.Error(err))
+	}
+}
+
+// handleAdminCacheStats handles GET /admin/cache/stats - reports hits, misses
+// and errors of the keys apps cache, per app, and with Redis how many keys each
+// app has there
+func (h *AppHandler) handleAdminCacheStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	stats, err := h.processor.AppCacheStats(r.Context())
+	if err != nil {
+		h.logger.Warn("Failed to inspect app cache", zap.Error(err))
+		writeError(w, r, http.StatusServiceUnavailable, "Failed to inspect app cache")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("Failed to encode cache stats response", zap.Error(err))
+	}
+}
+
+// handleAdminCacheFlush handles DELETE /admin/cache/{app_id} - deletes an app's
+// cached keys and rendered output from Redis, e.g. after its upstream API
+// changed shape
+func (h *AppHandler) handleAdminCacheFlush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	appID := strings.TrimPrefix(r.URL.Path, "/admin/cache/")
+	if appID == "" || strings.Contains(appID, "/") {
+		writeError(w, r, http.StatusNotFound, "Not found")
+		return
+	}
+
+	deleted, err := h.processor.FlushAppCache(r.Context(), appID)
+	if errors.Is(err, pixlet.ErrNoRedis) {
+		writeError(w, r, http.StatusNotFound, "No shared app cache without Redis")
+		return
+	}
+	if err != nil {
+		h.logger.Warn("Failed to flush app cache", zap.String("app_id", appID), zap.Error(err))
+		writeError(w, r, http.StatusServiceUnavailable, "Failed to flush app cache")
+		return
+	}
+
+	h.logger.Info("Flushed app cache", zap.String("app_id", appID), zap.Int64("keys", deleted))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"app_id":       appID,
+		"deleted_keys": deleted,
+	})
+}
+
+// appListing is an app's manifest plus its runtime status, as served on /apps
+type appListing struct {
+	*models.AppManifest
+	Quarantined      bool       `json:"quarantined,omitempty"`      // renders run on the quarantine pool
+	QuarantinedUntil *time.Time `json:"quarantinedUntil,omitempty"` // when the app is released
+}
+
+func newAppListing(app *models.AppManifest, quarantined map[string]time.Time) appListing {
+	listing := appListing{AppManifest: app}
+	if until, ok := quarantined[app.ID]; ok {
+		listing.Quarantined = true
+		listing.QuarantinedUntil = &until
+	}
+	return listing
+}
+
+// handleApps handles GET /apps - returns apps matching the query's filters, sorted
+// and paged, with the number that matched in X-Total-Count - and POST /apps,
+// which installs an app bundle
 func (h *AppHandler) handleApps(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		h.handleAppInstall(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query, err := parseAppQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	registry := h.processor.GetAppRegistry()
-	apps := registry.GetAppsList()
+	apps, total := query.apply(registry.GetAppsList())
+
+	quarantined := h.processor.QuarantinedApps()
+	listings := make([]appListing, len(apps))
+	for i, app := range apps {
+		listings[i] = newAppListing(app, quarantined)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(apps); err != nil {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if err := json.NewEncoder(w).Encode(listings); err != nil {
 		h.logger.Error("Failed to encode apps response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
-	h.logger.Debug("Served apps list", zap.Int("count", len(apps)))
+	h.logger.Debug("Served apps list", zap.Int("count", len(apps)), zap.Int("total", total))
+}
+
+// handleAppSearch handles GET /apps/search - returns apps ranked by how well they
+// match the q parameter
+func (h *AppHandler) handleAppSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, r, http.StatusBadRequest, "q is required")
+		return
+	}
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			writeError(w, r, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	matches := searchApps(h.processor.GetAppRegistry().GetAppsList(), query, limit)
+	quarantined := h.processor.QuarantinedApps()
+	results := make([]appSearchResult, len(matches))
+	for i, match := range matches {
+		results[i] = appSearchResult{appListing: newAppListing(match.app, quarantined), Score: match.score}
+	}
+
+	h.writeJSON(w, http.StatusOK, results)
+	h.logger.Debug("Served app search",
+		zap.String("query", query),
+		zap.Int("results", len(results)))
 }
 
 // handleAppsRefresh handles POST /apps/refresh - reloads the app registry
 func (h *AppHandler) handleAppsRefresh(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -95,10 +371,15 @@ func (h *AppHandler) handleAppsRefresh(w http.ResponseWriter, r *http.Request) {
 	// Reload the app registry from the filesystem
 	if err := h.processor.RefreshAppRegistry(); err != nil {
 		h.logger.Error("Failed to refresh app registry", zap.Error(err))
-		http.Error(w, "Failed to refresh apps", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to refresh apps")
 		return
 	}
 
+	// Other replicas reload too, from the same apps path
+	if err := h.processor.AnnounceAppsUpdated(r.Context()); err != nil {
+		h.logger.Warn("Failed to announce app refresh to other replicas", zap.Error(err))
+	}
+
 	registry := h.processor.GetAppRegistry()
 	apps := registry.GetAppsList()
 
@@ -111,7 +392,7 @@ func (h *AppHandler) handleAppsRefresh(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		h.logger.Error("Failed to encode refresh response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
@@ -122,13 +403,16 @@ func (h *AppHandler) handleAppsRefresh(w http.ResponseWriter, r *http.Request) {
 // - GET /apps/{id} - returns specific app or 404
 // - GET /apps/{id}/schema - returns the app's schema
 // - POST /apps/{id}/call_handler - calls a schema handler
+// - GET /apps/{id}/frames - streams rendered frames
+// - GET /apps/{id}/frames.zip - returns rendered frames as a ZIP archive
+// - POST /apps/{id}/benchmark - renders repeatedly and reports timings
 func (h *AppHandler) handleAppDetails(w http.ResponseWriter, r *http.Request) {
 	// Parse the path: /apps/{id} or /apps/{id}/schema or /apps/{id}/call_handler
 	path := strings.TrimPrefix(r.URL.Path, "/apps/")
 	pathParts := strings.Split(path, "/")
 
 	if len(pathParts) == 0 || pathParts[0] == "" {
-		http.Error(w, "App ID required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "App ID required")
 		return
 	}
 
@@ -137,7 +421,7 @@ func (h *AppHandler) handleAppDetails(w http.ResponseWriter, r *http.Request) {
 	app, exists := registry.GetApp(appID)
 
 	if !exists {
-		http.Error(w, "App not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "App not found")
 		return
 	}
 
@@ -161,10 +445,45 @@ func (h *AppHandler) handleAppDetails(w http.ResponseWriter, r *http.Request) {
 				h.handleAppRender(w, r, appID)
 				return
 			}
+		case "benchmark":
+			if r.Method == http.MethodPost {
+				h.handleAppBenchmark(w, r, appID)
+				return
+			}
+		case "frames":
+			h.handleAppFrames(w, r, appID)
+			return
+		case "frames.zip":
+			h.handleAppFramesZip(w, r, appID)
+			return
+		case "simulator":
+			if len(pathParts) == 2 {
+				h.handleAppSimulator(w, r, app)
+				return
+			}
+		case "icon":
+			if len(pathParts) == 2 {
+				h.handleAppIcon(w, r, app)
+				return
+			}
+		case "assets":
+			if len(pathParts) > 2 {
+				h.handleAppAsset(w, r, app, strings.Join(pathParts[2:], "/"))
+				return
+			}
+		case "live":
+			if len(pathParts) == 2 {
+				h.handleAppLive(w, r, appID)
+				return
+			}
+			if len(pathParts) == 3 && pathParts[2] != "" {
+				h.handleAppLiveConfig(w, r, appID, pathParts[2])
+				return
+			}
 		default:
 			if strings.HasPrefix(pathParts[1], "preview.") {
-				if r.Method != http.MethodGet {
-					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				if r.Method != http.MethodGet && r.Method != http.MethodPost {
+					writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 					return
 				}
 				format := strings.TrimPrefix(pathParts[1], "preview.")
@@ -177,9 +496,9 @@ func (h *AppHandler) handleAppDetails(w http.ResponseWriter, r *http.Request) {
 	// Handle GET /apps/{id} - return app details
 	if r.Method == http.MethodGet && len(pathParts) == 1 {
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(app); err != nil {
+		if err := json.NewEncoder(w).Encode(newAppListing(app, h.processor.QuarantinedApps())); err != nil {
 			h.logger.Error("Failed to encode app response", zap.Error(err))
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, "Internal server error")
 			return
 		}
 
@@ -187,13 +506,18 @@ func (h *AppHandler) handleAppDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Method == http.MethodDelete && len(pathParts) == 1 {
+		h.handleAppDelete(w, r, appID)
+		return
+	}
+
 	// If none of the above matched, return method not allowed or not found
 	if len(pathParts) > 1 {
-		http.Error(w, "Endpoint not found", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, "Endpoint not found")
 		return
 	}
 
-	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 }
 
 // handleAppSchema handles GET /apps/{id}/schema - returns the app's schema as JSON
@@ -206,11 +530,11 @@ func (h *AppHandler) handleAppSchema(w http.ResponseWriter, r *http.Request, app
 			zap.Error(err))
 
 		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "App not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "App not found")
 			return
 		}
 
-		http.Error(w, "Failed to get app schema", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to get app schema")
 		return
 	}
 
@@ -220,7 +544,7 @@ func (h *AppHandler) handleAppSchema(w http.ResponseWriter, r *http.Request, app
 		h.logger.Error("Failed to encode schema response",
 			zap.String("app_id", appID),
 			zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
@@ -247,17 +571,17 @@ func (h *AppHandler) handleCallSchemaHandler(w http.ResponseWriter, r *http.Requ
 		h.logger.Error("Failed to decode call handler request",
 			zap.String("app_id", appID),
 			zap.Error(err))
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid JSON body")
 		return
 	}
 
 	// Validate required fields
 	if request.HandlerName == "" {
-		http.Error(w, "handler_name is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "handler_name is required")
 		return
 	}
 	if request.Config == nil {
-		http.Error(w, "config is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "config is required")
 		return
 	}
 
@@ -268,7 +592,7 @@ func (h *AppHandler) handleCallSchemaHandler(w http.ResponseWriter, r *http.Requ
 		if field != nil && field.Type == "oauth2" {
 			validationErrors := h.validator.ValidateOAuth2HandlerCall(*field, request.Data)
 			if len(validationErrors) > 0 {
-				h.respondValidationFailure(w, nil, validationErrors)
+				h.respondValidationFailure(w, r, nil, validationErrors)
 				return
 			}
 		}
@@ -284,19 +608,19 @@ func (h *AppHandler) handleCallSchemaHandler(w http.ResponseWriter, r *http.Requ
 
 		// Handle specific errors
 		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "App not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "App not found")
 			return
 		}
 		if errors.Is(err, pixlet.ErrSchemaNotDefined) {
-			http.Error(w, "App does not define a schema", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "App does not define a schema")
 			return
 		}
 		if strings.Contains(err.Error(), "handler") {
-			http.Error(w, "Schema handler error: "+err.Error(), http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, "Schema handler error: "+err.Error())
 			return
 		}
 
-		http.Error(w, "Failed to call schema handler", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to call schema handler")
 		return
 	}
 
@@ -311,7 +635,7 @@ func (h *AppHandler) handleCallSchemaHandler(w http.ResponseWriter, r *http.Requ
 			zap.String("app_id", appID),
 			zap.String("handler_name", request.HandlerName),
 			zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
@@ -329,8 +653,9 @@ type ValidateSchemaResponse struct {
 
 // RenderResponse represents the response from the HTTP render endpoint
 type RenderResponse struct {
-	Result           *models.RenderResult   `json:"result"`
-	NormalizedConfig map[string]interface{} `json:"normalized_config"`
+	Result           *models.RenderResult            `json:"result,omitempty"`
+	Results          map[string]*models.RenderResult `json:"results,omitempty"` // keyed by "WIDTHxHEIGHT" when sizes is set
+	NormalizedConfig map[string]interface{}          `json:"normalized_config"`
 }
 
 // handleValidateSchema handles POST /apps/{id}/schema - validates config against schema
@@ -340,7 +665,7 @@ func (h *AppHandler) handleValidateSchema(w http.ResponseWriter, r *http.Request
 		h.logger.Error("Failed to decode validate schema request",
 			zap.String("app_id", appID),
 			zap.Error(err))
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Invalid JSON body")
 		return
 	}
 
@@ -350,10 +675,10 @@ func (h *AppHandler) handleValidateSchema(w http.ResponseWriter, r *http.Request
 			zap.String("app_id", appID),
 			zap.Error(err))
 		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "App not found", http.StatusNotFound)
+			writeError(w, r, http.StatusNotFound, "App not found")
 			return
 		}
-		http.Error(w, "Failed to get app schema", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to get app schema")
 		return
 	}
 
@@ -362,7 +687,7 @@ func (h *AppHandler) handleValidateSchema(w http.ResponseWriter, r *http.Request
 		h.logger.Error("Failed to validate schema",
 			zap.String("app_id", appID),
 			zap.Error(err))
-		http.Error(w, "Failed to validate config", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, "Failed to validate config")
 		return
 	}
 
@@ -380,55 +705,46 @@ func (h *AppHandler) handleValidateSchema(w http.ResponseWriter, r *http.Request
 		zap.Int("error_count", len(validationErrors)))
 }
 
+// maxIdempotencyKeyLength bounds Idempotency-Key, which becomes part of a Redis key
+const maxIdempotencyKeyLength = 255
+
 // handleAppRender handles POST /apps/{id}/render - renders an app with the provided configuration
 func (h *AppHandler) handleAppRender(w http.ResponseWriter, r *http.Request, appID string) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	config, err := decodeConfigBody(r)
-	if err != nil {
-		h.logger.Error("Failed to decode render request body",
-			zap.String("app_id", appID),
-			zap.Error(err))
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+	normalizedConfig, ok := h.validateRenderConfig(w, r, appID)
+	if !ok {
 		return
 	}
 
-	appSchema, err := h.processor.GetAppSchema(r.Context(), appID)
+	device, err := h.parseDevice(r)
 	if err != nil {
-		h.logger.Error("Failed to get app schema for render",
-			zap.String("app_id", appID),
-			zap.Error(err))
-		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "App not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to get app schema", http.StatusInternalServerError)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
+	if device.ID == "" {
+		device.ID = "http-render"
+	}
 
-	normalizedConfig, validationErrors, err := h.validator.ValidateConfig(r.Context(), appID, config, appSchema)
+	renderOpts, err := parseRenderOptions(r)
 	if err != nil {
-		h.logger.Error("Failed to validate render config",
-			zap.String("app_id", appID),
-			zap.Error(err))
-		http.Error(w, "Failed to validate config", http.StatusInternalServerError)
-		return
-	}
-	if len(validationErrors) > 0 {
-		h.respondValidationFailure(w, normalizedConfig, validationErrors)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	device, err := h.parseDevice(r)
+	sizes, err := parseSizes(r.URL.Query().Get("sizes"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	if device.ID == "" {
-		device.ID = "http-render"
+	for _, size := range sizes {
+		if err := h.processor.CheckDeviceSize(size); err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
 	}
 
 	renderParams := addDisplayDimensions(normalizedConfig, device)
@@ -440,14 +756,68 @@ func (h *AppHandler) handleAppRender(w http.ResponseWriter, r *http.Request, app
 		Device: device,
 		Params: renderParams,
 	}
+	if !renderOpts.RenderTime.IsZero() {
+		request.RenderTime = &renderOpts.RenderTime
+	}
+	request.Encoding = renderOpts.Encoding
+	request.DebugOverlay = renderOpts.DebugOverlay
+	request.IdempotencyKey = r.Header.Get("Idempotency-Key")
+	if len(request.IdempotencyKey) > maxIdempotencyKeyLength {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+		return
+	}
 
-	result, err := h.processor.RenderApp(r.Context(), request)
+	raw, err := wantsRawRender(r)
 	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	async, err := parseAsync(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.IdempotencyKey != "" && (async || raw || len(sizes) > 0) {
+		writeError(w, r, http.StatusBadRequest, "Idempotency-Key is only supported for JSON renders of one size")
+		return
+	}
+	if async {
+		if raw || len(sizes) > 0 {
+			writeError(w, r, http.StatusBadRequest, "Raw output and sizes are not supported with async")
+			return
+		}
+		h.startRenderJob(w, r, request, normalizedConfig)
+		return
+	}
+
+	if len(sizes) > 0 {
+		if raw {
+			writeError(w, r, http.StatusBadRequest, "Raw output is not supported with sizes")
+			return
+		}
+		h.handleAppRenderBatch(w, r, request, sizes, normalizedConfig)
+		return
+	}
+
+	if raw {
+		h.writeRawRender(w, r, request, renderOpts)
+		return
+	}
+
+	// Only keyed requests are remembered; generated UUIDs never repeat
+	render := h.processor.RenderApp
+	if request.IdempotencyKey != "" {
+		render = h.processor.RenderAppOnce
+	}
+	result, err := render(r.Context(), request)
+	replayed := errors.Is(err, pixlet.ErrDuplicateRequest)
+	if err != nil && !replayed {
 		h.logger.Error("Failed to render app",
 			zap.String("app_id", appID),
 			zap.String("device_id", device.ID),
 			zap.Error(err))
-		http.Error(w, "Failed to render app", http.StatusInternalServerError)
+		h.writeRenderError(w, r, err, "Failed to render app")
 		return
 	}
 
@@ -456,6 +826,9 @@ func (h *AppHandler) handleAppRender(w http.ResponseWriter, r *http.Request, app
 		NormalizedConfig: normalizedConfig,
 	}
 
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
 	h.writeJSON(w, http.StatusOK, response)
 
 	h.logger.Info("Rendered app via HTTP",
@@ -463,59 +836,191 @@ func (h *AppHandler) handleAppRender(w http.ResponseWriter, r *http.Request, app
 		zap.String("device_id", device.ID))
 }
 
-// handleAppPreview handles GET /apps/{id}/preview.{webp|gif} - renders and streams binary data using defaults
-func (h *AppHandler) handleAppPreview(w http.ResponseWriter, r *http.Request, appID, format string) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleAppBenchmark handles POST /apps/{id}/benchmark - renders the app repeatedly with
+// the provided configuration and reports latency percentiles, output sizes and errors
+func (h *AppHandler) handleAppBenchmark(w http.ResponseWriter, r *http.Request, appID string) {
+	iterations := pixlet.DefaultBenchmarkIterations
+	if raw := r.URL.Query().Get("iterations"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > pixlet.MaxBenchmarkIterations {
+			writeError(w, r, http.StatusBadRequest, fmt.Sprintf("iterations must be between 1 and %d", pixlet.MaxBenchmarkIterations))
+			return
+		}
+		iterations = n
+	}
+
+	normalizedConfig, ok := h.validateRenderConfig(w, r, appID)
+	if !ok {
 		return
 	}
 
-	format = strings.ToLower(strings.TrimSpace(format))
-	if format != "webp" {
-		http.Error(w, "Unsupported preview format. Use .webp", http.StatusNotFound)
+	device, err := h.parseDevice(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
+	if device.ID == "" {
+		device.ID = "http-benchmark"
+	}
 
-	appSchema, err := h.processor.GetAppSchema(r.Context(), appID)
+	renderOpts, err := parseRenderOptions(r)
 	if err != nil {
-		h.logger.Error("Failed to get app schema for preview",
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	request := &models.RenderRequest{
+		Type:         "render_request",
+		AppID:        appID,
+		Device:       device,
+		Params:       addDisplayDimensions(normalizedConfig, device),
+		Encoding:     renderOpts.Encoding,
+		DebugOverlay: renderOpts.DebugOverlay,
+	}
+	if !renderOpts.RenderTime.IsZero() {
+		request.RenderTime = &renderOpts.RenderTime
+	}
+
+	result, err := h.processor.Benchmark(r.Context(), request, iterations)
+	if err != nil {
+		h.logger.Error("Failed to benchmark app",
 			zap.String("app_id", appID),
 			zap.Error(err))
-		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, "App not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to get app schema", http.StatusInternalServerError)
+		h.writeRenderError(w, r, err, "Failed to benchmark app")
 		return
 	}
 
-	normalizedConfig, _, err := h.validator.ValidateConfig(r.Context(), appID, nil, appSchema)
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// writeRenderError answers a failed render: 429 with Retry-After when the render
+// queue is full, so clients back off, 503 while the service shuts down, and 500
+// with the given message otherwise
+func (h *AppHandler) writeRenderError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	status, message := renderError(err, message)
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "1")
+	}
+	writeError(w, r, status, message)
+}
+
+// renderError returns the status and message a failed render is answered with,
+// falling back to 500 and message
+func renderError(err error, message string) (int, string) {
+	switch {
+	case errors.Is(err, pixlet.ErrQueueFull):
+		return http.StatusTooManyRequests, "Render queue is full, retry later"
+	case errors.Is(err, pixlet.ErrPoolStopped):
+		return http.StatusServiceUnavailable, "Renderer is shutting down"
+	case errors.Is(err, pixlet.ErrRequestInProgress):
+		return http.StatusConflict, "A request with this Idempotency-Key is still rendering, retry later"
+	case errors.Is(err, pixlet.ErrIdempotencyKeyMismatch):
+		return http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request"
+	}
+	return http.StatusInternalServerError, message
+}
+
+// writeRawRender renders a request and writes the encoded WebP bytes directly instead of
+// base64 inside JSON. Apps that return no screens produce 204 No Content.
+func (h *AppHandler) writeRawRender(w http.ResponseWriter, r *http.Request, request *models.RenderRequest, renderOpts pixlet.RenderOptions) {
+	webpData, err := h.processor.RenderPreview(r.Context(), request.AppID, request.Params, request.Device, "webp", renderOpts)
 	if err != nil {
-		h.logger.Error("Failed to validate preview config",
-			zap.String("app_id", appID),
+		h.logger.Error("Failed to render app",
+			zap.String("app_id", request.AppID),
+			zap.String("device_id", request.Device.ID),
 			zap.Error(err))
-		http.Error(w, "Failed to validate config", http.StatusInternalServerError)
+		h.writeRenderError(w, r, err, "Failed to render app")
 		return
 	}
 
-	device, err := h.parseDevice(r)
+	publishRender(h.processor.Results(), request.UUID, request.AppID, request.Device.ID, webpData)
+
+	w.Header().Set("Cache-Control", "no-store")
+	if len(webpData) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.Header().Set("Content-Type", "image/webp")
+		w.Header().Set("Content-Length", strconv.Itoa(len(webpData)))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(webpData); err != nil {
+			h.logger.Error("Failed to write render response",
+				zap.String("app_id", request.AppID),
+				zap.Error(err))
+		}
+	}
+
+	h.logger.Info("Rendered app via HTTP (raw)",
+		zap.String("app_id", request.AppID),
+		zap.String("device_id", request.Device.ID),
+		zap.Int("output_size", len(webpData)))
+}
+
+// handleAppRenderBatch renders one request at several sizes, reusing the loaded applet
+func (h *AppHandler) handleAppRenderBatch(w http.ResponseWriter, r *http.Request, request *models.RenderRequest, sizes []models.Size, normalizedConfig map[string]interface{}) {
+	// display_width/display_height are set per size by the worker
+	params := make(map[string]interface{}, len(normalizedConfig))
+	for key, value := range normalizedConfig {
+		params[key] = value
+	}
+	request.Params = params
+
+	results, err := h.processor.RenderAppBatch(r.Context(), request, sizes)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.logger.Error("Failed to batch render app",
+			zap.String("app_id", request.AppID),
+			zap.String("device_id", request.Device.ID),
+			zap.Error(err))
+		h.writeRenderError(w, r, err, "Failed to render app")
 		return
 	}
-	if device.ID == "" {
-		device.ID = fmt.Sprintf("preview-%s", format)
+
+	h.writeJSON(w, http.StatusOK, RenderResponse{
+		Results:          results,
+		NormalizedConfig: normalizedConfig,
+	})
+
+	h.logger.Info("Batch rendered app via HTTP",
+		zap.String("app_id", request.AppID),
+		zap.String("device_id", request.Device.ID),
+		zap.Int("sizes", len(sizes)))
+}
+
+// handleAppPreview handles GET and POST /apps/{id}/preview.{webp|gif} - renders and
+// streams binary data. GET renders with defaults unless config values are passed as
+// query parameters; POST takes the config at the root of a JSON body.
+func (h *AppHandler) handleAppPreview(w http.ResponseWriter, r *http.Request, appID, format string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != "webp" {
+		writeError(w, r, http.StatusNotFound, "Unsupported preview format. Use .webp")
+		return
+	}
+
+	config, err := previewConfig(r)
+	if err != nil {
+		h.logger.Error("Failed to decode preview request body",
+			zap.String("app_id", appID),
+			zap.Error(err))
+		writeError(w, r, http.StatusBadRequest, "Invalid JSON body")
+		return
 	}
 
-	previewParams := addDisplayDimensions(normalizedConfig, device)
+	previewParams, device, renderOpts, ok := h.prepareRender(w, r, appID, fmt.Sprintf("preview-%s", format), config)
+	if !ok {
+		return
+	}
 
-	previewBytes, err := h.processor.RenderPreview(r.Context(), appID, previewParams, device, format)
+	previewBytes, err := h.processor.RenderPreview(r.Context(), appID, previewParams, device, format, renderOpts)
 	if err != nil {
 		h.logger.Error("Failed to render preview",
 			zap.String("app_id", appID),
 			zap.String("format", format),
 			zap.Error(err))
-		http.Error(w, "Failed to render preview", http.StatusInternalServerError)
+		h.writeRenderError(w, r, err, "Failed to render preview")
 		return
 	}
 
@@ -534,13 +1039,316 @@ func (h *AppHandler) handleAppPreview(w http.ResponseWriter, r *http.Request, ap
 		zap.String("device_id", device.ID))
 }
 
-func (h *AppHandler) respondValidationFailure(w http.ResponseWriter, normalizedConfig map[string]interface{}, validationErrors []ValidationError) {
-	response := ValidateSchemaResponse{
-		Valid:            false,
+// handleAppFrames handles GET /apps/{id}/frames - streams each frame as a PNG part of a
+// multipart/mixed response as soon as it is painted, using schema defaults
+func (h *AppHandler) handleAppFrames(w http.ResponseWriter, r *http.Request, appID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	params, device, renderOpts, ok := h.prepareDefaultRender(w, r, appID, "frames")
+	if !ok {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	mw := multipart.NewWriter(w)
+	started := false
+
+	err := h.processor.StreamFrames(r.Context(), appID, params, device, renderOpts, func(frame pixlet.Frame) error {
+		if !started {
+			w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", "image/png")
+		header.Set("X-Frame-Index", strconv.Itoa(frame.Index))
+		header.Set("X-Frame-Delay-Ms", strconv.FormatInt(frame.Delay.Milliseconds(), 10))
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(part, frame.Image); err != nil {
+			return fmt.Errorf("error encoding PNG frame: %w", err)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("Failed to stream frames",
+			zap.String("app_id", appID),
+			zap.Bool("started", started),
+			zap.Error(err))
+		if !started {
+			h.writeRenderError(w, r, err, "Failed to render frames")
+		}
+		return
+	}
+
+	if !started {
+		// App returned no frames; still answer with a well-formed empty multipart body
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+	}
+	if err := mw.Close(); err != nil {
+		h.logger.Error("Failed to finish frames response",
+			zap.String("app_id", appID),
+			zap.Error(err))
+	}
+
+	h.logger.Info("Streamed frames via HTTP",
+		zap.String("app_id", appID),
+		zap.String("device_id", device.ID))
+}
+
+// FramesManifest describes the frames contained in a frames.zip export
+type FramesManifest struct {
+	AppID           string               `json:"app_id"`
+	Width           int                  `json:"width"`
+	Height          int                  `json:"height"`
+	FrameCount      int                  `json:"frame_count"`
+	TotalDurationMs int64                `json:"total_duration_ms"`
+	Frames          []FrameManifestEntry `json:"frames"`
+}
+
+// FrameManifestEntry describes a single frame in a frames.zip export
+type FrameManifestEntry struct {
+	File    string `json:"file"`
+	DelayMs int64  `json:"delay_ms"`
+}
+
+// handleAppFramesZip handles GET /apps/{id}/frames.zip - returns every rendered frame as
+// an individual PNG plus a manifest.json of frame delays, using schema defaults
+func (h *AppHandler) handleAppFramesZip(w http.ResponseWriter, r *http.Request, appID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	params, device, renderOpts, ok := h.prepareDefaultRender(w, r, appID, "frames-zip")
+	if !ok {
+		return
+	}
+
+	manifest := FramesManifest{
+		AppID:  appID,
+		Width:  device.Width,
+		Height: device.Height,
+		Frames: []FrameManifestEntry{},
+	}
+
+	zw := zip.NewWriter(w)
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", appID+"-frames.zip"))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	err := h.processor.StreamFrames(r.Context(), appID, params, device, renderOpts, func(frame pixlet.Frame) error {
+		start()
+
+		name := fmt.Sprintf("frame_%04d.png", frame.Index)
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(fw, frame.Image); err != nil {
+			return fmt.Errorf("error encoding PNG frame: %w", err)
+		}
+
+		manifest.Frames = append(manifest.Frames, FrameManifestEntry{
+			File:    name,
+			DelayMs: frame.Delay.Milliseconds(),
+		})
+		manifest.TotalDurationMs += frame.Delay.Milliseconds()
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("Failed to export frames",
+			zap.String("app_id", appID),
+			zap.Bool("started", started),
+			zap.Error(err))
+		if !started {
+			h.writeRenderError(w, r, err, "Failed to render frames")
+		}
+		return
+	}
+
+	start()
+	manifest.FrameCount = len(manifest.Frames)
+
+	mf, err := zw.Create("manifest.json")
+	if err == nil {
+		encoder := json.NewEncoder(mf)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(manifest)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		h.logger.Error("Failed to finish frames archive",
+			zap.String("app_id", appID),
+			zap.Error(err))
+		return
+	}
+
+	h.logger.Info("Exported frames via HTTP",
+		zap.String("app_id", appID),
+		zap.String("device_id", device.ID),
+		zap.Int("frame_count", manifest.FrameCount))
+}
+
+// validateRenderConfig decodes the config at the root of the request body and
+// validates it against the app's schema. On failure it writes the error response
+// and returns ok=false.
+func (h *AppHandler) validateRenderConfig(w http.ResponseWriter, r *http.Request, appID string) (map[string]interface{}, bool) {
+	config, err := decodeConfigBody(r)
+	if err != nil {
+		h.logger.Error("Failed to decode render request body",
+			zap.String("app_id", appID),
+			zap.Error(err))
+		writeError(w, r, http.StatusBadRequest, "Invalid JSON body")
+		return nil, false
+	}
+
+	appSchema, err := h.processor.GetAppSchema(r.Context(), appID)
+	if err != nil {
+		h.logger.Error("Failed to get app schema for render",
+			zap.String("app_id", appID),
+			zap.Error(err))
+		if strings.Contains(err.Error(), "not found") {
+			writeError(w, r, http.StatusNotFound, "App not found")
+			return nil, false
+		}
+		writeError(w, r, http.StatusInternalServerError, "Failed to get app schema")
+		return nil, false
+	}
+
+	normalizedConfig, validationErrors, err := h.validator.ValidateConfig(r.Context(), appID, config, appSchema)
+	if err != nil {
+		h.logger.Error("Failed to validate render config",
+			zap.String("app_id", appID),
+			zap.Error(err))
+		writeError(w, r, http.StatusInternalServerError, "Failed to validate config")
+		return nil, false
+	}
+	if len(validationErrors) > 0 {
+		h.respondValidationFailure(w, r, normalizedConfig, validationErrors)
+		return nil, false
+	}
+
+	return normalizedConfig, true
+}
+
+// prepareDefaultRender resolves schema defaults, device dimensions and render options
+// for GET render endpoints. On failure it writes the error response and returns ok=false.
+func (h *AppHandler) prepareDefaultRender(w http.ResponseWriter, r *http.Request, appID, defaultDeviceID string) (map[string]interface{}, models.Device, pixlet.RenderOptions, bool) {
+	return h.prepareRender(w, r, appID, defaultDeviceID, nil)
+}
+
+// prepareRender is prepareDefaultRender for a caller-supplied config. A nil config
+// renders with schema defaults; any other config must pass validation, or a 422
+// with the field errors is written.
+func (h *AppHandler) prepareRender(w http.ResponseWriter, r *http.Request, appID, defaultDeviceID string, config map[string]interface{}) (map[string]interface{}, models.Device, pixlet.RenderOptions, bool) {
+	appSchema, err := h.processor.GetAppSchema(r.Context(), appID)
+	if err != nil {
+		h.logger.Error("Failed to get app schema for preview",
+			zap.String("app_id", appID),
+			zap.Error(err))
+		if strings.Contains(err.Error(), "not found") {
+			writeError(w, r, http.StatusNotFound, "App not found")
+			return nil, models.Device{}, pixlet.RenderOptions{}, false
+		}
+		writeError(w, r, http.StatusInternalServerError, "Failed to get app schema")
+		return nil, models.Device{}, pixlet.RenderOptions{}, false
+	}
+
+	normalizedConfig, validationErrors, err := h.validator.ValidateConfig(r.Context(), appID, config, appSchema)
+	if err != nil {
+		h.logger.Error("Failed to validate preview config",
+			zap.String("app_id", appID),
+			zap.Error(err))
+		writeError(w, r, http.StatusInternalServerError, "Failed to validate config")
+		return nil, models.Device{}, pixlet.RenderOptions{}, false
+	}
+	// Defaults render even when the schema has required fields without one
+	if config != nil && len(validationErrors) > 0 {
+		h.respondValidationFailure(w, r, normalizedConfig, validationErrors)
+		return nil, models.Device{}, pixlet.RenderOptions{}, false
+	}
+
+	device, err := h.parseDevice(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return nil, models.Device{}, pixlet.RenderOptions{}, false
+	}
+	if device.ID == "" {
+		device.ID = defaultDeviceID
+	}
+
+	renderOpts, err := parseRenderOptions(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return nil, models.Device{}, pixlet.RenderOptions{}, false
+	}
+
+	return addDisplayDimensions(normalizedConfig, device), device, renderOpts, true
+}
+
+// previewQueryParams are the query parameters previews read themselves; any
+// other parameter of a GET preview is a config value
+var previewQueryParams = map[string]bool{
+	"width":         true,
+	"height":        true,
+	"device_id":     true,
+	"render_time":   true,
+	"debug_overlay": true,
+	"webp_lossless": true,
+	"webp_quality":  true,
+	"webp_method":   true,
+	"access_token":  true,
+}
+
+// previewConfig returns the config a preview request supplies: the JSON body of
+// a POST, or the flat query parameters of a GET other than previewQueryParams.
+// It is nil for a GET without config values, which renders with defaults.
+func previewConfig(r *http.Request) (map[string]interface{}, error) {
+	if r.Method == http.MethodPost {
+		return decodeConfigBody(r)
+	}
+
+	var config map[string]interface{}
+	for key, values := range r.URL.Query() {
+		if previewQueryParams[key] || len(values) == 0 {
+			continue
+		}
+		if config == nil {
+			config = make(map[string]interface{})
+		}
+		config[key] = values[len(values)-1]
+	}
+	return config, nil
+}
+
+func (h *AppHandler) respondValidationFailure(w http.ResponseWriter, r *http.Request, normalizedConfig map[string]interface{}, validationErrors []ValidationError) {
+	writeErrorDetails(w, r, http.StatusUnprocessableEntity, "Config failed validation", validationFailureDetails{
 		Errors:           validationErrors,
 		NormalizedConfig: normalizedConfig,
-	}
-	h.writeJSON(w, http.StatusUnprocessableEntity, response)
+	})
 }
 
 func addDisplayDimensions(config map[string]interface{}, device models.Device) map[string]interface{} {
@@ -555,14 +1363,18 @@ func addDisplayDimensions(config map[string]interface{}, device models.Device) m
 
 func (h *AppHandler) parseDevice(r *http.Request) (models.Device, error) {
 	query := r.URL.Query()
-	width, err := parseDimension(query.Get("width"), defaultDeviceWidth)
+	defaultSize := h.processor.DefaultDeviceSize()
+	width, err := parseDimension(query.Get("width"), defaultSize.Width)
 	if err != nil {
 		return models.Device{}, fmt.Errorf("invalid width: %w", err)
 	}
-	height, err := parseDimension(query.Get("height"), defaultDeviceHeight)
+	height, err := parseDimension(query.Get("height"), defaultSize.Height)
 	if err != nil {
 		return models.Device{}, fmt.Errorf("invalid height: %w", err)
 	}
+	if err := h.processor.CheckDeviceSize(models.Size{Width: width, Height: height}); err != nil {
+		return models.Device{}, err
+	}
 
 	return models.Device{
 		ID:     query.Get("device_id"),
@@ -571,6 +1383,127 @@ func (h *AppHandler) parseDevice(r *http.Request) (models.Device, error) {
 	}, nil
 }
 
+// parseRenderOptions reads optional render overrides from the query string.
+// render_time accepts an RFC3339 timestamp or Unix seconds; webp_lossless,
+// webp_quality and webp_method override the configured WebP encoder settings;
+// debug_overlay stamps render metadata onto the output.
+func parseRenderOptions(r *http.Request) (pixlet.RenderOptions, error) {
+	var opts pixlet.RenderOptions
+	query := r.URL.Query()
+
+	if raw := strings.TrimSpace(query.Get("render_time")); raw != "" {
+		if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			opts.RenderTime = time.Unix(seconds, 0).UTC()
+		} else {
+			renderTime, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return opts, fmt.Errorf("invalid render_time: must be RFC3339 or Unix seconds")
+			}
+			opts.RenderTime = renderTime
+		}
+	}
+
+	if raw := strings.TrimSpace(query.Get("debug_overlay")); raw != "" {
+		overlay, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, fmt.Errorf("invalid debug_overlay: must be true or false")
+		}
+		opts.DebugOverlay = overlay
+	}
+
+	encoding, err := parseEncodingOptions(query)
+	if err != nil {
+		return opts, err
+	}
+	opts.Encoding = encoding
+	return opts, nil
+}
+
+// parseEncodingOptions reads WebP encoder overrides, returning nil when none are set
+func parseEncodingOptions(query url.Values) (*models.EncodingOptions, error) {
+	var encoding models.EncodingOptions
+	var check pixlet.WebPOptions
+	set := false
+
+	if raw := strings.TrimSpace(query.Get("webp_lossless")); raw != "" {
+		lossless, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webp_lossless: must be true or false")
+		}
+		encoding.Lossless = &lossless
+		set = true
+	}
+	if raw := strings.TrimSpace(query.Get("webp_quality")); raw != "" {
+		quality, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webp_quality: must be an integer")
+		}
+		encoding.Quality = &quality
+		check.Quality = quality
+		set = true
+	}
+	if raw := strings.TrimSpace(query.Get("webp_method")); raw != "" {
+		method, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webp_method: must be an integer")
+		}
+		encoding.Method = &method
+		check.Method = method
+		set = true
+	}
+
+	if !set {
+		return nil, nil
+	}
+	if err := check.Validate(); err != nil {
+		return nil, err
+	}
+	return &encoding, nil
+}
+
+// wantsRawRender reports whether the client asked for raw WebP bytes, either with
+// ?raw=true or an Accept header listing image/webp
+func wantsRawRender(r *http.Request) (bool, error) {
+	if raw := strings.TrimSpace(r.URL.Query().Get("raw")); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return false, fmt.Errorf("invalid raw: must be true or false")
+		}
+		return value, nil
+	}
+
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err == nil && mediaType == "image/webp" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// parseSizes parses a comma-separated list of WIDTHxHEIGHT sizes, e.g. "64x32,128x64"
+func parseSizes(raw string) ([]models.Size, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	seen := make(map[models.Size]bool)
+	var sizes []models.Size
+	for _, part := range strings.Split(raw, ",") {
+		size, err := models.ParseSize(part)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[size] {
+			seen[size] = true
+			sizes = append(sizes, size)
+		}
+	}
+	return sizes, nil
+}
+
 func parseDimension(raw string, defaultVal int) (int, error) {
 	if strings.TrimSpace(raw) == "" {
 		return defaultVal, nil
@@ -627,20 +1560,11 @@ func (h *AppHandler) writeJSON(w http.ResponseWriter, status int, payload interf
 // handleSwagger handles GET /swagger.json - returns OpenAPI specification
 func (h *AppHandler) handleSwagger(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Read the swagger.json file from the api directory
-	swaggerPath := "api/swagger.json"
-	swaggerData, err := os.ReadFile(swaggerPath)
-	if err != nil {
-		h.logger.Error("Failed to read swagger.json", zap.Error(err))
-		http.Error(w, "Swagger specification not found", http.StatusNotFound)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(swaggerData)
+	w.Write(api.Swagger)
 }
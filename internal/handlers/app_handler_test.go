@@ -1,14 +1,20 @@
 package handlers
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"image/png"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/koios/matrx-renderer/internal/config"
 	"github.com/koios/matrx-renderer/internal/pixlet"
@@ -92,7 +98,8 @@ packageName: apps.test-app
 	}
 
 	cfg := &config.PixletConfig{
-		AppsPath: tempDir,
+		AppsPath:         tempDir,
+		AllowDestructive: true,
 	}
 	logger := zap.NewNop()
 	processor := pixlet.NewProcessor(cfg, logger)
@@ -131,6 +138,113 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestHealth_Unhealthy(t *testing.T) {
+	h := setupTestHandler(t)
+	h.processor.Stop()
+
+	w := httptest.NewRecorder()
+	h.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 with the pool stopped, got %d", w.Code)
+	}
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["status"] != "unhealthy" || resp["checks"] == nil {
+		t.Errorf("Expected unhealthy with checks, got %v", resp)
+	}
+}
+
+func TestHealthStatus(t *testing.T) {
+	ok := func(name string) pixlet.ReadinessCheck { return pixlet.ReadinessCheck{Name: name, OK: true} }
+	failed := func(name string) pixlet.ReadinessCheck { return pixlet.ReadinessCheck{Name: name, Error: "down"} }
+
+	tests := []struct {
+		name   string
+		checks []pixlet.ReadinessCheck
+		want   string
+	}{
+		{"all ok", []pixlet.ReadinessCheck{ok("apps"), ok("workers"), ok("redis")}, "healthy"},
+		{"redis down", []pixlet.ReadinessCheck{ok("apps"), ok("workers"), failed("redis")}, "degraded"},
+		{"workers down", []pixlet.ReadinessCheck{ok("apps"), failed("workers"), ok("redis")}, "unhealthy"},
+		{"apps and redis down", []pixlet.ReadinessCheck{failed("apps"), ok("workers"), failed("redis")}, "unhealthy"},
+	}
+	for _, tt := range tests {
+		if got := healthStatus(tt.checks); got != tt.want {
+			t.Errorf("%s: healthStatus = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLivez(t *testing.T) {
+	h := setupTestHandler(t)
+
+	w := httptest.NewRecorder()
+	h.handleLivez(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+
+	// Liveness doesn't depend on the worker pool
+	h.processor.Stop()
+	w = httptest.NewRecorder()
+	h.handleLivez(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 with the pool stopped, got %d", w.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	h := setupTestHandler(t)
+
+	w := httptest.NewRecorder()
+	h.handleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ReadinessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if resp.Status != "ready" || len(resp.Checks) == 0 {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	h.processor.Stop()
+	w = httptest.NewRecorder()
+	h.handleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 with the pool stopped, got %d", w.Code)
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Status != "not_ready" {
+		t.Errorf("Expected not_ready, got %q", resp.Status)
+	}
+}
+
+func TestReadyz_Draining(t *testing.T) {
+	h := setupTestHandler(t)
+	h.StartDraining()
+
+	w := httptest.NewRecorder()
+	h.handleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 while draining, got %d", w.Code)
+	}
+	var resp ReadinessResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	last := resp.Checks[len(resp.Checks)-1]
+	if last.Name != "shutdown" || last.OK {
+		t.Errorf("Expected a failed shutdown check, got %+v", resp.Checks)
+	}
+
+	// Liveness is unaffected, so the instance isn't restarted mid-drain
+	w = httptest.NewRecorder()
+	h.handleLivez(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 from /livez while draining, got %d", w.Code)
+	}
+}
+
 func TestHealth_WrongMethod(t *testing.T) {
 	h := setupTestHandler(t)
 
@@ -143,6 +257,135 @@ func TestHealth_WrongMethod(t *testing.T) {
 	}
 }
 
+// --- Metrics endpoint ---
+
+func TestMetrics(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	for _, name := range []string{"matrx_render_workers", "matrx_render_jobs_in_flight"} {
+		if !strings.Contains(w.Body.String(), name) {
+			t.Errorf("Expected %s in metrics output", name)
+		}
+	}
+}
+
+// --- Stats endpoint ---
+
+func TestStats(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var resp pixlet.Stats
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if resp.Apps != 1 {
+		t.Errorf("Expected 1 app, got %d", resp.Apps)
+	}
+	if resp.Pool.Workers == 0 {
+		t.Error("Expected live workers in pool stats")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/stats", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
+
+func TestAdminQueues(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	get := func(mux *http.ServeMux, method string) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, "/admin/queues", nil))
+		return w.Code
+	}
+	if code := get(mux, http.MethodGet); code != http.StatusNotFound {
+		t.Errorf("Expected 404 without Redis, got %d", code)
+	}
+	if code := get(mux, http.MethodPost); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", code)
+	}
+
+	processor := pixlet.NewProcessorWithRedis(&config.PixletConfig{AppsPath: t.TempDir()}, &config.RedisConfig{Addr: "127.0.0.1:1"}, zap.NewNop())
+	defer processor.Close()
+	defer processor.Stop()
+	redisMux := http.NewServeMux()
+	NewAppHandler(processor, zap.NewNop()).RegisterRoutes(redisMux)
+	if code := get(redisMux, http.MethodGet); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with Redis unreachable, got %d", code)
+	}
+}
+
+func TestAdminCache(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	serve := func(mux *http.ServeMux, method, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	// Without Redis the counts are this replica's alone, with no key counts
+	w := serve(mux, http.MethodGet, "/admin/cache/stats")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var stats pixlet.AppCacheStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if stats.Backend != "memory" || stats.Scope != "app" || stats.Total.Keys != nil {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if code := serve(mux, http.MethodDelete, "/admin/cache/clock").Code; code != http.StatusNotFound {
+		t.Errorf("Expected 404 flushing without Redis, got %d", code)
+	}
+	if code := serve(mux, http.MethodPost, "/admin/cache/stats").Code; code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", code)
+	}
+	if code := serve(mux, http.MethodGet, "/admin/cache/clock").Code; code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", code)
+	}
+
+	processor := pixlet.NewProcessorWithRedis(&config.PixletConfig{AppsPath: t.TempDir()}, &config.RedisConfig{Addr: "127.0.0.1:1"}, zap.NewNop())
+	defer processor.Close()
+	defer processor.Stop()
+	redisMux := http.NewServeMux()
+	NewAppHandler(processor, zap.NewNop()).RegisterRoutes(redisMux)
+	if code := serve(redisMux, http.MethodGet, "/admin/cache/stats").Code; code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with Redis unreachable, got %d", code)
+	}
+	if code := serve(redisMux, http.MethodDelete, "/admin/cache/clock").Code; code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 flushing with Redis unreachable, got %d", code)
+	}
+}
+
 // --- Apps list endpoint ---
 
 func TestApps(t *testing.T) {
@@ -165,10 +408,44 @@ func TestApps(t *testing.T) {
 	}
 }
 
+func TestApps_Query(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/apps?has_schema=true&sort=-name&limit=10", nil)
+	w := httptest.NewRecorder()
+	h.handleApps(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-Total-Count"); got != "1" {
+		t.Errorf("Expected X-Total-Count: 1, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/apps?has_schema=false", nil)
+	w = httptest.NewRecorder()
+	h.handleApps(w, req)
+	var apps []interface{}
+	if err := json.NewDecoder(w.Body).Decode(&apps); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if len(apps) != 0 || w.Header().Get("X-Total-Count") != "0" {
+		t.Errorf("Expected the app with a schema to be filtered out, got %d", len(apps))
+	}
+
+	for _, query := range []string{"limit=-1", "limit=5000", "offset=x", "sort=author", "has_schema=maybe"} {
+		req = httptest.NewRequest(http.MethodGet, "/apps?"+query, nil)
+		w = httptest.NewRecorder()
+		h.handleApps(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
 func TestApps_WrongMethod(t *testing.T) {
 	h := setupTestHandler(t)
 
-	req := httptest.NewRequest(http.MethodPost, "/apps", nil)
+	req := httptest.NewRequest(http.MethodPut, "/apps", nil)
 	w := httptest.NewRecorder()
 	h.handleApps(w, req)
 
@@ -199,6 +476,95 @@ func TestAppDetails(t *testing.T) {
 	}
 }
 
+func TestRequestID(t *testing.T) {
+	h := setupTestHandler(t)
+	handler := RequestID(http.HandlerFunc(h.handleAppDetails))
+
+	req := httptest.NewRequest(http.MethodGet, "/apps/test-app", nil)
+	req.Header.Set("X-Request-ID", "trace-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got != "trace-123" {
+		t.Errorf("Expected X-Request-ID to be echoed, got %q", got)
+	}
+
+	// IDs that aren't safe to log are replaced
+	req = httptest.NewRequest(http.MethodGet, "/apps/test-app", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got == "" || got == "bad id\n" {
+		t.Errorf("Expected a generated X-Request-ID, got %q", got)
+	}
+
+	// Error bodies carry the same ID
+	req = httptest.NewRequest(http.MethodGet, "/apps/missing", nil)
+	req.Header.Set("X-Request-ID", "trace-456")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error: %v", err)
+	}
+	if resp.RequestID != "trace-456" {
+		t.Errorf("Expected the request ID in the error body, got %q", resp.RequestID)
+	}
+}
+
+func TestAppBenchmark(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/apps/test-app/benchmark?iterations=3", strings.NewReader(`{"user_id":"bench"}`))
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp pixlet.BenchmarkResult
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if resp.AppID != "test-app" || resp.Iterations != 3 {
+		t.Errorf("Expected 3 iterations of test-app, got %+v", resp)
+	}
+}
+
+func TestAppBenchmark_InvalidIterations(t *testing.T) {
+	h := setupTestHandler(t)
+
+	for _, iterations := range []string{"0", "abc", "101"} {
+		req := httptest.NewRequest(http.MethodPost, "/apps/test-app/benchmark?iterations="+iterations, strings.NewReader(`{}`))
+		w := httptest.NewRecorder()
+		h.handleAppDetails(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("iterations=%s: expected 400, got %d", iterations, w.Code)
+		}
+	}
+}
+
+func TestAppListing_Quarantined(t *testing.T) {
+	app := &models.AppManifest{ID: "slow-app", Name: "Slow"}
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	data, err := json.Marshal(newAppListing(app, map[string]time.Time{"slow-app": until}))
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	var listing map[string]interface{}
+	if err := json.Unmarshal(data, &listing); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if listing["id"] != "slow-app" || listing["quarantined"] != true || listing["quarantinedUntil"] != "2030-01-01T00:00:00Z" {
+		t.Errorf("Unexpected listing: %s", data)
+	}
+
+	data, _ = json.Marshal(newAppListing(app, nil))
+	if strings.Contains(string(data), "quarantined") {
+		t.Errorf("Expected no quarantine fields for a healthy app: %s", data)
+	}
+}
+
 func TestAppDetails_NotFound(t *testing.T) {
 	h := setupTestHandler(t)
 
@@ -233,6 +599,109 @@ func TestAppSchema(t *testing.T) {
 	}
 }
 
+// --- Frames endpoint ---
+
+func TestAppFrames(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/apps/test-app/frames", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Expected multipart/mixed, got %q (%v)", w.Header().Get("Content-Type"), err)
+	}
+
+	reader := multipart.NewReader(w.Body, params["boundary"])
+	part, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("Expected at least one frame part: %v", err)
+	}
+	if part.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("Expected image/png part, got %q", part.Header.Get("Content-Type"))
+	}
+	if part.Header.Get("X-Frame-Index") != "0" {
+		t.Errorf("Expected first frame index 0, got %q", part.Header.Get("X-Frame-Index"))
+	}
+	img, err := png.Decode(part)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG frame: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 32 {
+		t.Errorf("Expected 64x32 frame, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestAppFrames_WrongMethod(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/apps/test-app/frames", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
+
+func TestAppFramesZip(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/apps/test-app/frames.zip", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Expected application/zip, got %q", ct)
+	}
+
+	body := w.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("Failed to open zip: %v", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	if _, ok := files["frame_0000.png"]; !ok {
+		t.Error("Expected frame_0000.png in archive")
+	}
+
+	mf, ok := files["manifest.json"]
+	if !ok {
+		t.Fatal("Expected manifest.json in archive")
+	}
+	rc, err := mf.Open()
+	if err != nil {
+		t.Fatalf("Failed to open manifest: %v", err)
+	}
+	defer rc.Close()
+
+	var manifest FramesManifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		t.Fatalf("Failed to decode manifest: %v", err)
+	}
+	if manifest.AppID != "test-app" {
+		t.Errorf("Expected app_id test-app, got %q", manifest.AppID)
+	}
+	if manifest.FrameCount != len(manifest.Frames) || manifest.FrameCount == 0 {
+		t.Errorf("Expected non-zero frame_count matching frames, got %d/%d", manifest.FrameCount, len(manifest.Frames))
+	}
+	if manifest.Frames[0].File != "frame_0000.png" {
+		t.Errorf("Expected first frame file frame_0000.png, got %q", manifest.Frames[0].File)
+	}
+}
+
 // --- Validate schema endpoint ---
 
 func TestValidateSchema_ValidConfig(t *testing.T) {
@@ -326,6 +795,44 @@ func TestAppsRefresh_WrongMethod(t *testing.T) {
 
 // --- parseDimension ---
 
+func TestWriteRenderError(t *testing.T) {
+	h := &AppHandler{}
+	r := httptest.NewRequest(http.MethodPost, "/apps/test-app/render", nil)
+
+	w := httptest.NewRecorder()
+	h.writeRenderError(w, r, fmt.Errorf("submit: %w", pixlet.ErrQueueFull), "Failed to render app")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for a full queue, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header for a full queue")
+	}
+
+	w = httptest.NewRecorder()
+	h.writeRenderError(w, r, pixlet.ErrPoolStopped, "Failed to render app")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while shutting down, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.writeRenderError(w, r, pixlet.ErrRequestInProgress, "Failed to render app")
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 while a keyed request renders, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.writeRenderError(w, r, pixlet.ErrIdempotencyKeyMismatch, "Failed to render app")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a reused key with another body, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.writeRenderError(w, r, fmt.Errorf("boom"), "Failed to render app")
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for other errors, got %d", w.Code)
+	}
+}
+
 func TestParseDimension(t *testing.T) {
 	tests := []struct {
 		raw     string
@@ -352,6 +859,284 @@ func TestParseDimension(t *testing.T) {
 	}
 }
 
+func TestParseSizes(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []models.Size
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"64x32", []models.Size{{Width: 64, Height: 32}}, false},
+		{"64x32, 128X64,64x32", []models.Size{{Width: 64, Height: 32}, {Width: 128, Height: 64}}, false},
+		{"64", nil, true},
+		{"0x32", nil, true},
+		{"64xabc", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSizes(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSizes(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseSizes(%q) = %v, want %v", tt.raw, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseSizes(%q)[%d] = %v, want %v", tt.raw, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestWantsRawRender(t *testing.T) {
+	tests := []struct {
+		query   string
+		accept  string
+		want    bool
+		wantErr bool
+	}{
+		{"", "", false, false},
+		{"", "application/json", false, false},
+		{"", "image/webp", true, false},
+		{"", "application/json;q=0.5, image/webp", true, false},
+		{"raw=true", "", true, false},
+		{"raw=false", "image/webp", false, false},
+		{"raw=yes", "", false, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/apps/test-app/render?"+tt.query, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		got, err := wantsRawRender(req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("wantsRawRender(%q, %q) error = %v, wantErr %v", tt.query, tt.accept, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("wantsRawRender(%q, %q) = %v, want %v", tt.query, tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestAppRender_RawWithSizes(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/apps/test-app/render?raw=true&sizes=64x32,128x64", bytes.NewReader([]byte(`{}`)))
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Code)
+	}
+}
+
+func TestAppRender_InvalidSizes(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/apps/test-app/render?sizes=wide", bytes.NewReader([]byte(`{}`)))
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Code)
+	}
+}
+
+func TestAppRender_IdempotencyKey(t *testing.T) {
+	app, _ := setupTestHandler(t).processor.GetAppRegistry().GetApp("test-app")
+	processor := pixlet.NewProcessor(&config.PixletConfig{AppsPath: filepath.Dir(app.DirectoryPath), RequestIdempotencyTTL: 60}, zap.NewNop())
+	defer processor.Stop()
+	h := NewAppHandler(processor, zap.NewNop())
+
+	render := func(query, key string) (*httptest.ResponseRecorder, RenderResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/apps/test-app/render"+query, bytes.NewReader([]byte(`{}`)))
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		h.handleAppDetails(w, req)
+		var response RenderResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	if w, _ := render("?async=true", "order-3"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an async keyed render, got %d", w.Code)
+	}
+	if w, _ := render("", strings.Repeat("k", maxIdempotencyKeyLength+1)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an overlong key, got %d", w.Code)
+	}
+
+	w, first := render("", "order-1")
+	if w.Code == http.StatusInternalServerError {
+		t.Skip("WebP encoder unavailable")
+	}
+	if w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "" || first.Result == nil {
+		t.Fatalf("Expected a fresh render, got %d %s", w.Code, w.Body.String())
+	}
+	w, retry := render("", "order-1")
+	if w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("Expected the retry to be replayed, got %d %v", w.Code, w.Header())
+	}
+	if retry.Result == nil || retry.Result.UUID != first.Result.UUID {
+		t.Errorf("Expected the first result, got %+v", retry.Result)
+	}
+	if w, _ := render("", "order-2"); w.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("Expected a new key to render again")
+	}
+}
+
+func TestPreviewConfig(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/apps/test-app/preview.webp?width=64&device_id=d1&render_time=1700000000", nil)
+	if config, err := previewConfig(req); err != nil || config != nil {
+		t.Errorf("Expected no config from render options, got %v, %v", config, err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/apps/test-app/preview.webp?user_id=alice&options=a&options=b&webp_quality=50", nil)
+	config, err := previewConfig(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(config) != 2 || config["user_id"] != "alice" || config["options"] != "b" {
+		t.Errorf("Unexpected config %v", config)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/apps/test-app/preview.webp", strings.NewReader(`{"user_id": "bob"}`))
+	config, err = previewConfig(req)
+	if err != nil || config["user_id"] != "bob" {
+		t.Errorf("Expected body config, got %v, %v", config, err)
+	}
+}
+
+func TestAppPreview_Config(t *testing.T) {
+	h := setupTestHandler(t)
+
+	tests := []struct {
+		name   string
+		method string
+		query  string
+		body   string
+		want   int // 0 accepts a render outcome
+	}{
+		{"defaults", http.MethodGet, "", "", 0},
+		{"query config", http.MethodGet, "?user_id=alice&width=64", "", 0},
+		{"unknown query field", http.MethodGet, "?colour=red", "", http.StatusUnprocessableEntity},
+		{"body config", http.MethodPost, "", `{"user_id": "alice"}`, 0},
+		{"unknown body field", http.MethodPost, "", `{"colour": "red"}`, http.StatusUnprocessableEntity},
+		{"invalid body", http.MethodPost, "", `{"user_id":`, http.StatusBadRequest},
+		{"wrong method", http.MethodPut, "", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/apps/test-app/preview.webp"+tt.query, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.handleAppDetails(w, req)
+
+			if tt.want == 0 {
+				// WebP encoding needs libwebp, so accept either outcome of the render
+				if w.Code != http.StatusOK && w.Code != http.StatusInternalServerError {
+					t.Errorf("Expected a render outcome, got %d: %s", w.Code, w.Body.String())
+				}
+				return
+			}
+			if w.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestParseRenderOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"render_time=1700000000", time.Unix(1700000000, 0).UTC(), false},
+		{"render_time=2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"render_time=yesterday", time.Time{}, true},
+		{"debug_overlay=true", time.Time{}, false},
+		{"debug_overlay=loud", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/apps/test-app/preview.webp?"+tt.query, nil)
+		got, err := parseRenderOptions(req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRenderOptions(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !got.RenderTime.Equal(tt.want) {
+			t.Errorf("parseRenderOptions(%q) = %v, want %v", tt.query, got.RenderTime, tt.want)
+		}
+	}
+}
+
+func TestParseEncodingOptions(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantNil  bool
+		lossless *bool
+		quality  *int
+		method   *int
+		wantErr  bool
+	}{
+		{query: "", wantNil: true},
+		{query: "webp_lossless=false&webp_quality=60&webp_method=6", lossless: boolPtr(false), quality: intPtr(60), method: intPtr(6)},
+		{query: "webp_quality=90", quality: intPtr(90)},
+		{query: "webp_lossless=sometimes", wantErr: true},
+		{query: "webp_quality=101", wantErr: true},
+		{query: "webp_method=fast", wantErr: true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/apps/test-app/preview.webp?"+tt.query, nil)
+		got, err := parseEncodingOptions(req.URL.Query())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseEncodingOptions(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if tt.wantNil {
+			if got != nil {
+				t.Errorf("parseEncodingOptions(%q) = %+v, want nil", tt.query, got)
+			}
+			continue
+		}
+		if !equalBoolPtr(got.Lossless, tt.lossless) || !equalIntPtr(got.Quality, tt.quality) || !equalIntPtr(got.Method, tt.method) {
+			t.Errorf("parseEncodingOptions(%q) = %+v", tt.query, got)
+		}
+	}
+}
+
+func boolPtr(v bool) *bool { return &v }
+func intPtr(v int) *int    { return &v }
+
+func equalBoolPtr(a, b *bool) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func equalIntPtr(a, b *int) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func TestAppFrames_InvalidRenderTime(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/apps/test-app/frames?render_time=soon", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Code)
+	}
+}
+
 // --- addDisplayDimensions ---
 
 func TestAddDisplayDimensions(t *testing.T) {
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"go.uber.org/zap"
+)
+
+// maxAppBundleSize is the largest app bundle accepted by POST /apps
+const maxAppBundleSize = 16 << 20
+
+// handleAppInstall handles POST /apps - installs the zip or tar.gz app bundle in
+// the request body. replace=true overwrites an installed app with the same ID;
+// test_render=true runs the app once before installing it.
+func (h *AppHandler) handleAppInstall(w http.ResponseWriter, r *http.Request) {
+	var opts pixlet.InstallOptions
+	for name, value := range map[string]*bool{"replace": &opts.Replace, "test_render": &opts.TestRender} {
+		raw := strings.TrimSpace(r.URL.Query().Get(name))
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid %s: must be true or false", name))
+			return
+		}
+		*value = parsed
+	}
+
+	bundle, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAppBundleSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("App bundle exceeds %d MB", maxAppBundleSize>>20))
+			return
+		}
+		writeError(w, r, http.StatusBadRequest, "Failed to read app bundle")
+		return
+	}
+
+	manifest, err := h.processor.InstallApp(r.Context(), bundle, opts)
+	if err != nil {
+		h.logger.Warn("Failed to install app", zap.Error(err))
+		switch {
+		case errors.Is(err, pixlet.ErrInvalidBundle):
+			writeError(w, r, http.StatusBadRequest, err.Error())
+		case errors.Is(err, pixlet.ErrInvalidApp):
+			writeError(w, r, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, pixlet.ErrAppExists):
+			writeError(w, r, http.StatusConflict, err.Error()+"; pass replace=true to overwrite it")
+		case errors.Is(err, pixlet.ErrDestructiveDisabled):
+			writeError(w, r, http.StatusForbidden, "Replacing apps is disabled on this server")
+		default:
+			writeError(w, r, http.StatusInternalServerError, "Failed to install app")
+		}
+		return
+	}
+
+	w.Header().Set("Location", apiPrefix+"/apps/"+manifest.ID)
+	h.writeJSON(w, http.StatusCreated, newAppListing(manifest, h.processor.QuarantinedApps()))
+
+	h.logger.Info("Installed app via HTTP",
+		zap.String("app_id", manifest.ID),
+		zap.Bool("replace", opts.Replace))
+}
+
+// handleAppDelete handles DELETE /apps/{id} - removes the app's directory from
+// the apps path and refreshes the registry
+func (h *AppHandler) handleAppDelete(w http.ResponseWriter, r *http.Request, appID string) {
+	if err := h.processor.DeleteApp(appID); err != nil {
+		h.logger.Warn("Failed to delete app", zap.String("app_id", appID), zap.Error(err))
+		switch {
+		case errors.Is(err, pixlet.ErrAppNotFound):
+			writeError(w, r, http.StatusNotFound, "App not found")
+		case errors.Is(err, pixlet.ErrDestructiveDisabled):
+			writeError(w, r, http.StatusForbidden, "Deleting apps is disabled on this server")
+		default:
+			writeError(w, r, http.StatusInternalServerError, "Failed to delete app")
+		}
+		return
+	}
+
+	h.logger.Info("Deleted app via HTTP", zap.String("app_id", appID))
+	w.WriteHeader(http.StatusNoContent)
+}
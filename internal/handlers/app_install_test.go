@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"go.uber.org/zap"
+)
+
+func testAppBundle(t *testing.T, id, name string) []byte {
+	t.Helper()
+	files := map[string]string{
+		"manifest.yaml": "id: " + id + "\nname: " + name + "\nsummary: test\ndesc: test\nauthor: test\nfileName: " + id + ".star\npackageName: apps." + id + "\n",
+		id + ".star": `
+load("render.star", "render")
+
+def main(config):
+    return render.Root(child=render.Text("Uploaded"))
+`,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func installApp(h *AppHandler, query string, bundle []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/apps"+query, bytes.NewReader(bundle))
+	req.Header.Set("Content-Type", "application/zip")
+	w := httptest.NewRecorder()
+	h.handleApps(w, req)
+	return w
+}
+
+func TestAppInstall(t *testing.T) {
+	h := setupTestHandler(t)
+
+	w := installApp(h, "", testAppBundle(t, "uploaded", "Uploaded"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if loc := w.Header().Get("Location"); loc != "/v1/apps/uploaded" {
+		t.Errorf("Location = %q, want /v1/apps/uploaded", loc)
+	}
+	var app map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&app); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if app["id"] != "uploaded" || app["name"] != "Uploaded" {
+		t.Errorf("Unexpected app: %v", app)
+	}
+
+	// The app is listed and renderable straight away
+	req := httptest.NewRequest(http.MethodGet, "/apps/uploaded", nil)
+	rec := httptest.NewRecorder()
+	h.handleAppDetails(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected installed app details, got %d", rec.Code)
+	}
+
+	w = installApp(h, "", testAppBundle(t, "uploaded", "Again"))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate app, got %d", w.Code)
+	}
+
+	w = installApp(h, "?replace=true&test_render=true", testAppBundle(t, "uploaded", "Again"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 replacing the app, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAppInstall_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		bundle func(t *testing.T) []byte
+		want   int
+	}{
+		{"invalid replace", "?replace=maybe", func(t *testing.T) []byte { return testAppBundle(t, "a", "a") }, http.StatusBadRequest},
+		{"not an archive", "", func(t *testing.T) []byte { return []byte("not a zip") }, http.StatusBadRequest},
+		{"invalid manifest", "", func(t *testing.T) []byte { return testAppBundle(t, "bad.id", "a") }, http.StatusUnprocessableEntity},
+		{"missing name", "", func(t *testing.T) []byte { return testAppBundle(t, "nameless", "") }, http.StatusUnprocessableEntity},
+		{"too large", "", func(t *testing.T) []byte { return make([]byte, maxAppBundleSize+1) }, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := setupTestHandler(t)
+			w := installApp(h, tt.query, tt.bundle(t))
+			if w.Code != tt.want {
+				t.Fatalf("Expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Message == "" {
+				t.Errorf("Expected an error envelope, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAppDelete(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodDelete, "/apps/test-app", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/apps/test-app", nil)
+	w = httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", w.Code)
+	}
+}
+
+func TestAppDelete_Disabled(t *testing.T) {
+	processor := pixlet.NewProcessor(&config.PixletConfig{AppsPath: t.TempDir()}, zap.NewNop())
+	h := NewAppHandler(processor, zap.NewNop())
+
+	if w := installApp(h, "", testAppBundle(t, "kept", "Kept")); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := installApp(h, "?replace=true", testAppBundle(t, "kept", "Kept")); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 replacing, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/apps/kept", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 deleting, got %d", w.Code)
+	}
+}
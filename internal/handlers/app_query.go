@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+)
+
+// maxAppsPageSize caps the limit parameter of GET /apps
+const maxAppsPageSize = 1000
+
+// appQuery is the filtering, sorting and paging requested on GET /apps
+type appQuery struct {
+	author    string
+	tag       string
+	hasSchema *bool
+	sortBy    string // "id" or "name"
+	desc      bool
+	offset    int
+	limit     int // 0 returns everything after offset
+}
+
+// parseAppQuery reads author, tag, has_schema, sort, offset and limit
+func parseAppQuery(values url.Values) (appQuery, error) {
+	q := appQuery{
+		author: strings.TrimSpace(values.Get("author")),
+		tag:    strings.TrimSpace(values.Get("tag")),
+		sortBy: "id",
+	}
+
+	if raw := values.Get("has_schema"); raw != "" {
+		hasSchema, err := strconv.ParseBool(raw)
+		if err != nil {
+			return q, fmt.Errorf("has_schema must be true or false")
+		}
+		q.hasSchema = &hasSchema
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		q.sortBy, q.desc = strings.TrimPrefix(raw, "-"), strings.HasPrefix(raw, "-")
+		if q.sortBy != "id" && q.sortBy != "name" {
+			return q, fmt.Errorf("sort must be id, name, -id or -name")
+		}
+	}
+
+	var err error
+	if q.offset, err = parseNonNegative(values, "offset"); err != nil {
+		return q, err
+	}
+	if q.limit, err = parseNonNegative(values, "limit"); err != nil {
+		return q, err
+	}
+	if q.limit > maxAppsPageSize {
+		return q, fmt.Errorf("limit must be at most %d", maxAppsPageSize)
+	}
+	return q, nil
+}
+
+func parseNonNegative(values url.Values, key string) (int, error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return n, nil
+}
+
+// apply filters and sorts apps, and returns the requested page along with the
+// number of apps that matched before paging
+func (q appQuery) apply(apps []*models.AppManifest) ([]*models.AppManifest, int) {
+	matched := make([]*models.AppManifest, 0, len(apps))
+	for _, app := range apps {
+		if q.matches(app) {
+			matched = append(matched, app)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if q.desc {
+			a, b = b, a
+		}
+		if q.sortBy == "name" {
+			if an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name); an != bn {
+				return an < bn
+			}
+		}
+		return a.ID < b.ID
+	})
+
+	total := len(matched)
+	if q.offset >= total {
+		return []*models.AppManifest{}, total
+	}
+	page := matched[q.offset:]
+	if q.limit > 0 && q.limit < len(page) {
+		page = page[:q.limit]
+	}
+	return page, total
+}
+
+func (q appQuery) matches(app *models.AppManifest) bool {
+	if q.author != "" && !strings.EqualFold(app.Author, q.author) {
+		return false
+	}
+	if q.hasSchema != nil && app.HasSchema != *q.hasSchema {
+		return false
+	}
+	if q.tag != "" {
+		for _, tag := range app.Tags {
+			if strings.EqualFold(tag, q.tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
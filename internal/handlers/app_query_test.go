@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+)
+
+func TestAppQuery(t *testing.T) {
+	apps := []*models.AppManifest{
+		{ID: "clock", Name: "Clock", Author: "Tidbyt", Tags: []string{"time"}, HasSchema: true},
+		{ID: "weather", Name: "Weather", Author: "tidbyt", Tags: []string{"Weather", "outdoors"}, HasSchema: true},
+		{ID: "nyan", Name: "Nyan Cat", Author: "someone"},
+		{ID: "bart", Name: "BART", Author: "someone", Tags: []string{"transit"}, HasSchema: true},
+	}
+
+	ids := func(apps []*models.AppManifest) []string {
+		out := make([]string, len(apps))
+		for i, app := range apps {
+			out[i] = app.ID
+		}
+		return out
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+		total int
+	}{
+		{"", []string{"bart", "clock", "nyan", "weather"}, 4},
+		{"sort=-id", []string{"weather", "nyan", "clock", "bart"}, 4},
+		{"sort=name", []string{"bart", "clock", "nyan", "weather"}, 4},
+		{"sort=-name", []string{"weather", "nyan", "clock", "bart"}, 4},
+		{"author=TIDBYT", []string{"clock", "weather"}, 2},
+		{"tag=weather", []string{"weather"}, 1},
+		{"has_schema=false", []string{"nyan"}, 1},
+		{"has_schema=true&author=someone", []string{"bart"}, 1},
+		{"limit=2", []string{"bart", "clock"}, 4},
+		{"limit=2&offset=2", []string{"nyan", "weather"}, 4},
+		{"offset=3", []string{"weather"}, 4},
+		{"offset=10", []string{}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			q, err := parseAppQuery(values)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			page, total := q.apply(apps)
+			got := ids(page)
+			if total != tt.total {
+				t.Errorf("Expected total %d, got %d", tt.total, total)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+)
+
+// Search result limits
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// searchFieldWeights rank matches in an app's id and name above its summary,
+// and its summary above its description
+var searchFieldWeights = []struct {
+	field  func(*models.AppManifest) string
+	weight float64
+}{
+	{func(a *models.AppManifest) string { return a.ID }, 4},
+	{func(a *models.AppManifest) string { return a.Name }, 4},
+	{func(a *models.AppManifest) string { return a.Summary }, 2},
+	{func(a *models.AppManifest) string { return a.Description }, 1},
+}
+
+// Scores for how well a term matches a text, before field weighting
+const (
+	scoreExact      = 1.0  // the whole text, e.g. "clock" for id clock
+	scorePrefix     = 0.8  // the start of the text
+	scoreWordPrefix = 0.6  // the start of a word in the text
+	scoreSubstring  = 0.4  // anywhere in the text
+	scoreTypo       = 0.3  // a word one edit away
+	scoreFuzzy      = 0.25 // letters in order, scaled by how close together they are
+)
+
+// maxFuzzySpread bounds how spread out a term's letters may be, as a multiple of
+// its length, for a fuzzy match
+const maxFuzzySpread = 3
+
+// appSearchResult is an app listing with its relevance to the query
+type appSearchResult struct {
+	appListing
+	Score float64 `json:"score"`
+}
+
+type scoredApp struct {
+	app   *models.AppManifest
+	score float64
+}
+
+// searchApps ranks apps against query. Every term of the query must match one
+// of an app's fields; an app's score sums each term's best weighted match.
+// Ties are broken by ID, so results are stable.
+func searchApps(apps []*models.AppManifest, query string, limit int) []scoredApp {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []scoredApp
+	for _, app := range apps {
+		total := 0.0
+		for _, term := range terms {
+			best := 0.0
+			for _, f := range searchFieldWeights {
+				best = max(best, matchScore(term, strings.ToLower(f.field(app)))*f.weight)
+			}
+			if best == 0 {
+				total = 0
+				break
+			}
+			total += best
+		}
+		if total > 0 {
+			results = append(results, scoredApp{app: app, score: total})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].app.ID < results[j].app.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// matchScore scores a lowercase term against lowercase text, or returns 0 if it
+// doesn't match at all
+func matchScore(term, text string) float64 {
+	switch {
+	case text == "":
+		return 0
+	case text == term:
+		return scoreExact
+	case strings.HasPrefix(text, term):
+		return scorePrefix
+	}
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if strings.HasPrefix(word, term) {
+			return scoreWordPrefix
+		}
+	}
+	if strings.Contains(text, term) {
+		return scoreSubstring
+	}
+
+	// Tolerate a typo in longer terms, where one edit is unlikely to be a different word
+	if len(term) >= 4 {
+		for _, word := range words {
+			if withinOneEdit(term, word) {
+				return scoreTypo
+			}
+		}
+	}
+
+	// Letters in order and close together, e.g. "wthr" in "weather"
+	if span := subsequenceSpan(term, text); span > 0 && span <= maxFuzzySpread*len(term) {
+		return scoreFuzzy * float64(len(term)) / float64(span)
+	}
+	return 0
+}
+
+// withinOneEdit reports whether a and b differ by at most one insertion,
+// deletion, substitution or swap of adjacent letters
+func withinOneEdit(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+
+	i := 0
+	for i < len(a) && a[i] == b[i] {
+		i++
+	}
+	if len(a) == len(b) {
+		if i >= len(a)-1 {
+			return true
+		}
+		if a[i] == b[i+1] && a[i+1] == b[i] {
+			return a[i+2:] == b[i+2:]
+		}
+		return a[i+1:] == b[i+1:]
+	}
+	return a[i:] == b[i+1:]
+}
+
+// subsequenceSpan returns the length of the shortest stretch of text containing
+// term's letters in order, or 0 if it has none
+func subsequenceSpan(term, text string) int {
+	best := 0
+	for start := 0; start < len(text); start++ {
+		if text[start] != term[0] {
+			continue
+		}
+		t := 0
+		for i := start; i < len(text); i++ {
+			if text[i] == term[t] {
+				t++
+				if t == len(term) {
+					if span := i - start + 1; best == 0 || span < best {
+						best = span
+					}
+					break
+				}
+			}
+		}
+	}
+	return best
+}
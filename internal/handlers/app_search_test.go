@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+)
+
+func TestSearchApps(t *testing.T) {
+	apps := []*models.AppManifest{
+		{ID: "clock", Name: "Clock", Summary: "Shows the time"},
+		{ID: "big-clock", Name: "Big Clock", Summary: "A large clock"},
+		{ID: "weather", Name: "Weather", Summary: "Current conditions", Description: "Forecast from your location"},
+		{ID: "bart", Name: "BART", Summary: "Bay Area transit departures", Description: "Shows the next trains"},
+		{ID: "sunrise", Name: "Sunrise Sunset", Summary: "Daylight times"},
+	}
+
+	ids := func(results []scoredApp) []string {
+		out := make([]string, len(results))
+		for i, r := range results {
+			out[i] = r.app.ID
+		}
+		return out
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"clock", []string{"clock", "big-clock"}}, // exact id beats a word in the name
+		{"CLO", []string{"clock", "big-clock"}},   // prefix, case-insensitive
+		{"weahter", []string{"weather"}},          // one typo
+		{"wthr", []string{"weather"}},             // letters in order
+		{"transit", []string{"bart"}},             // summary
+		{"trains", []string{"bart"}},              // description
+		{"big clock", []string{"big-clock"}},      // every term must match
+		{"time", []string{"clock", "sunrise"}},    // word prefix in summary
+		{"zzz", []string{}},                       // nothing
+		{"   ", []string{}},                       // no terms
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := ids(searchApps(apps, tt.query, 0))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+
+	if got := searchApps(apps, "clock", 1); len(got) != 1 || got[0].app.ID != "clock" {
+		t.Errorf("Expected the limit to keep the best match, got %v", ids(got))
+	}
+}
+
+func TestWithinOneEdit(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"weather", "weather", true},
+		{"weathr", "weather", true},
+		{"wezther", "weather", true},
+		{"weatherx", "weather", true},
+		{"wetaher", "weather", true},
+		{"wtaeher", "weather", false},
+		{"wea", "weather", false},
+	}
+	for _, tt := range tests {
+		if got := withinOneEdit(tt.a, tt.b); got != tt.want {
+			t.Errorf("withinOneEdit(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAppSearch(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/apps/search?q=test", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var results []map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if len(results) != 1 || results[0]["id"] != "test-app" || results[0]["score"] == nil {
+		t.Errorf("Expected test-app with a score, got %v", results)
+	}
+
+	for _, query := range []string{"", "?q=", "?q=test&limit=0", "?q=test&limit=500"} {
+		req = httptest.NewRequest(http.MethodGet, "/apps/search"+query, nil)
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/koios/matrx-renderer/internal/auth"
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+)
+
+// Authenticator requires a bearer token from the identity provider on API
+// requests and checks the role its claims grant against the route
+type Authenticator struct {
+	verifier *auth.Verifier
+	roles    *auth.RoleMapper
+	logger   *zap.Logger
+}
+
+// NewAuthenticator returns an Authenticator for cfg, or nil if no issuer is
+// configured and the API is open
+func NewAuthenticator(cfg config.AuthConfig, logger *zap.Logger) (*Authenticator, error) {
+	if cfg.JWTIssuer == "" {
+		return nil, nil
+	}
+
+	verifier, err := auth.NewVerifier(auth.Config{
+		Issuer:   cfg.JWTIssuer,
+		Audience: cfg.JWTAudience,
+		JWKSURL:  cfg.JWKSURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	roles, err := auth.NewRoleMapper(cfg.RolesClaim, cfg.RoleMapping)
+	if err != nil {
+		return nil, err
+	}
+	return &Authenticator{verifier: verifier, roles: roles, logger: logger}, nil
+}
+
+// Wrap returns next guarded by the authenticator. A nil authenticator returns
+// next unchanged.
+func (a *Authenticator) Wrap(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required := requiredRole(r)
+		if required == auth.RoleNone {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := requestToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="matrx-renderer"`)
+			writeError(w, r, http.StatusUnauthorized, "Bearer token required")
+			return
+		}
+
+		claims, err := a.verifier.Verify(r.Context(), token)
+		if err != nil {
+			a.logger.Debug("Rejected bearer token", zap.String("path", r.URL.Path), zap.Error(err))
+			description := "token is invalid"
+			if errors.Is(err, auth.ErrTokenExpired) {
+				description = "token is expired"
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="matrx-renderer", error="invalid_token", error_description="`+description+`"`)
+			writeError(w, r, http.StatusUnauthorized, "Invalid bearer token")
+			return
+		}
+
+		principal := auth.Principal{Subject: claims.Subject(), Role: a.roles.Role(claims)}
+		if principal.Role < required {
+			a.logger.Info("Denied request for insufficient role",
+				zap.String("subject", principal.Subject),
+				zap.Stringer("role", principal.Role),
+				zap.Stringer("required", required),
+				zap.String("path", r.URL.Path))
+			w.Header().Set("WWW-Authenticate", `Bearer realm="matrx-renderer", error="insufficient_scope"`)
+			writeError(w, r, http.StatusForbidden, "Role "+required.String()+" required")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// requiredRole returns the role a request needs. Health checks, metrics scrapes
+// and the API spec and docs stay open.
+func requiredRole(r *http.Request) auth.Role {
+	path := apiPath(r.URL.Path)
+	switch path {
+	case "/health", "/livez", "/readyz", "/metrics", "/swagger.json", "/docs":
+		return auth.RoleNone
+	case "/apps/refresh":
+		return auth.RoleAdmin
+	case "/apps":
+		if r.Method == http.MethodPost {
+			return auth.RoleAdmin
+		}
+	}
+	if strings.HasPrefix(path, "/admin/") {
+		return auth.RoleAdmin
+	}
+	// DELETE /apps/{id} uninstalls an app
+	if appID, ok := strings.CutPrefix(path, "/apps/"); ok && r.Method == http.MethodDelete && !strings.Contains(appID, "/") {
+		return auth.RoleAdmin
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return auth.RoleRender
+	}
+	return auth.RoleRead
+}
+
+// requestToken returns a request's bearer token. Browsers can't set headers on
+// WebSocket handshakes, so /ws also accepts it in an access_token query parameter.
+func requestToken(r *http.Request) (string, bool) {
+	if token, ok := parseBearer(r.Header.Get("Authorization")); ok {
+		return token, true
+	}
+	if apiPath(r.URL.Path) == "/ws" {
+		if token := r.URL.Query().Get("access_token"); token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// parseBearer extracts the token from an "Authorization: Bearer" header value
+func parseBearer(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
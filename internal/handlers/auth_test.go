@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/auth"
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+)
+
+// setupTestAuthenticator returns an authenticator trusting a test JWKS, and a
+// function issuing tokens with the given roles
+func setupTestAuthenticator(t *testing.T) (*Authenticator, func(roles ...string) string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(jwks.Close)
+
+	cfg := config.AuthConfig{
+		JWTIssuer:   "https://idp.example",
+		JWTAudience: "matrx",
+		JWKSURL:     jwks.URL,
+		RolesClaim:  "groups",
+		RoleMapping: "ops=admin,displays=render,viewers=read",
+	}
+	a, err := NewAuthenticator(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(roles ...string) string {
+		encode := func(v interface{}) string {
+			data, _ := json.Marshal(v)
+			return base64.RawURLEncoding.EncodeToString(data)
+		}
+		signed := encode(map[string]string{"alg": "RS256", "kid": "test"}) + "." + encode(map[string]interface{}{
+			"iss":    cfg.JWTIssuer,
+			"aud":    cfg.JWTAudience,
+			"sub":    "user-1",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"groups": roles,
+		})
+		digest := sha256.Sum256([]byte(signed))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	return a, issue
+}
+
+func TestAuthenticator(t *testing.T) {
+	a, issue := setupTestAuthenticator(t)
+
+	var principal auth.Principal
+	handler := a.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = auth.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"health is public", http.MethodGet, "/health", "", http.StatusOK},
+		{"metrics are public", http.MethodGet, "/metrics", "", http.StatusOK},
+		{"probes are public", http.MethodGet, "/v1/readyz", "", http.StatusOK},
+		{"docs are public", http.MethodGet, "/v1/docs", "", http.StatusOK},
+		{"missing token", http.MethodGet, "/apps", "", http.StatusUnauthorized},
+		{"garbage token", http.MethodGet, "/apps", "garbage", http.StatusUnauthorized},
+		{"no roles", http.MethodGet, "/apps", issue(), http.StatusForbidden},
+		{"reader lists apps", http.MethodGet, "/apps", issue("viewers"), http.StatusOK},
+		{"reader cannot render", http.MethodPost, "/apps/clock/render", issue("viewers"), http.StatusForbidden},
+		{"renderer renders", http.MethodPost, "/apps/clock/render", issue("displays"), http.StatusOK},
+		{"renderer cannot refresh", http.MethodPost, "/apps/refresh", issue("displays"), http.StatusForbidden},
+		{"admin refreshes", http.MethodPost, "/apps/refresh", issue("viewers", "ops"), http.StatusOK},
+		{"versioned refresh needs admin", http.MethodPost, "/v1/apps/refresh", issue("displays"), http.StatusForbidden},
+		{"reader cannot inspect queues", http.MethodGet, "/admin/queues", issue("displays"), http.StatusForbidden},
+		{"versioned health is public", http.MethodGet, "/v1/health", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("Expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusUnauthorized && !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("Expected a Bearer challenge, got %q", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	req.Header.Set("Authorization", "bearer "+issue("ops"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if principal.Subject != "user-1" || principal.Role != auth.RoleAdmin {
+		t.Errorf("Expected the principal on the request context, got %+v", principal)
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   string
+	}{
+		{"authorization header", "/apps", "Bearer abc", "abc"},
+		{"header wins on /ws", "/ws?access_token=query", "Bearer header", "header"},
+		{"query on /ws", "/ws?access_token=query", "", "query"},
+		{"query on versioned /ws", "/v1/ws?access_token=query", "", "query"},
+		{"query ignored elsewhere", "/apps?access_token=query", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			got, ok := requestToken(req)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("Expected %q, got %q (%v)", tt.want, got, ok)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Disabled(t *testing.T) {
+	a, err := NewAuthenticator(config.AuthConfig{}, zap.NewNop())
+	if err != nil || a != nil {
+		t.Fatalf("Expected no authenticator without an issuer, got %v, %v", a, err)
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest(http.MethodPost, "/apps/refresh", nil)
+	w := httptest.NewRecorder()
+	a.Wrap(next).ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected requests through when disabled, got %d", w.Code)
+	}
+
+	if _, err := NewAuthenticator(config.AuthConfig{JWTIssuer: "https://idp.example", RoleMapping: "x=root"}, zap.NewNop()); err == nil {
+		t.Error("Expected an error for an invalid role mapping")
+	}
+}
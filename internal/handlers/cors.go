@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/koios/matrx-renderer/internal/config"
+)
+
+// corsExposedHeaders are response headers browser scripts may read beyond the
+// CORS-safelisted ones
+const corsExposedHeaders = "X-Request-ID, X-Total-Count, Retry-After, Content-Disposition, Content-Length, Idempotent-Replayed"
+
+// CORS lets browser apps on allowed origins call the API
+type CORS struct {
+	origins map[string]bool
+	any     bool // "*" was configured
+	methods string
+	headers string
+	maxAge  string
+}
+
+// NewCORS returns CORS middleware for cfg, or nil if no origins are allowed
+func NewCORS(cfg config.ServerConfig) *CORS {
+	origins := splitList(cfg.CORSAllowedOrigins)
+	if len(origins) == 0 {
+		return nil
+	}
+
+	c := &CORS{
+		origins: make(map[string]bool, len(origins)),
+		methods: strings.Join(splitList(strings.ToUpper(cfg.CORSAllowedMethods)), ", "),
+		headers: strings.Join(splitList(cfg.CORSAllowedHeaders), ", "),
+	}
+	for _, origin := range origins {
+		if origin == "*" {
+			c.any = true
+		}
+		c.origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	if cfg.CORSMaxAge > 0 {
+		c.maxAge = strconv.Itoa(cfg.CORSMaxAge)
+	}
+	return c
+}
+
+// Wrap returns next with CORS headers added for allowed origins. Preflight
+// requests are answered here without reaching next, so they need no credentials;
+// it belongs outside the Authenticator. A nil CORS returns next unchanged.
+func (c *CORS) Wrap(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Responses differ by origin, so shared caches must key on it
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !c.any && !c.origins[origin] {
+			// Leave the headers off; the browser blocks the response
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.any {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", c.methods)
+			if c.headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", c.headers)
+			}
+			if c.maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", c.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowsOrigin reports whether origin is allowed. A nil CORS allows none.
+func (c *CORS) allowsOrigin(origin string) bool {
+	return c != nil && (c.any || c.origins[strings.TrimSuffix(origin, "/")])
+}
+
+// splitList splits a comma-separated config value, dropping blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	c := NewCORS(config.ServerConfig{
+		CORSAllowedOrigins: "https://dash.example, https://admin.example/",
+		CORSAllowedMethods: "get,post",
+		CORSAllowedHeaders: "Content-Type,Authorization",
+		CORSMaxAge:         600,
+	})
+	reached := false
+	handler := c.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+
+	serve := func(method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		reached = false
+		req := httptest.NewRequest(method, "/apps/clock/preview.webp", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodGet, "https://dash.example", nil)
+	if !reached || w.Header().Get("Access-Control-Allow-Origin") != "https://dash.example" {
+		t.Errorf("Expected an allowed origin to be echoed, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if w.Header().Get("Vary") != "Origin" {
+		t.Errorf("Expected Vary: Origin, got %q", w.Header().Get("Vary"))
+	}
+
+	w = serve(http.MethodGet, "https://admin.example", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://admin.example" {
+		t.Error("Expected a configured trailing slash to be ignored")
+	}
+
+	w = serve(http.MethodGet, "https://evil.example", nil)
+	if !reached || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Expected a disallowed origin to get no CORS headers")
+	}
+
+	w = serve(http.MethodGet, "", nil)
+	if !reached || w.Header().Get("Vary") != "" {
+		t.Error("Expected same-origin requests to pass through untouched")
+	}
+
+	w = serve(http.MethodOptions, "https://dash.example", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "authorization",
+	})
+	if reached {
+		t.Error("Expected the preflight to be answered without reaching the handler")
+	}
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a preflight, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+		t.Errorf("Unexpected allowed methods %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, Authorization" {
+		t.Errorf("Unexpected allowed headers %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Unexpected max age %q", got)
+	}
+
+	w = serve(http.MethodOptions, "https://evil.example", map[string]string{"Access-Control-Request-Method": "POST"})
+	if reached || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Expected a disallowed preflight to be refused")
+	}
+}
+
+func TestCORS_Wildcard(t *testing.T) {
+	handler := NewCORS(config.ServerConfig{CORSAllowedOrigins: "*", CORSAllowedMethods: "GET"}).
+		Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/apps", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected *, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestCORS_PreflightBypassesAuth(t *testing.T) {
+	a, _ := setupTestAuthenticator(t)
+	handler := NewCORS(config.ServerConfig{CORSAllowedOrigins: "https://dash.example", CORSAllowedMethods: "GET,POST"}).
+		Wrap(a.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodOptions, "/apps/clock/schema", nil)
+	req.Header.Set("Origin", "https://dash.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected the preflight to succeed without a token, got %d", w.Code)
+	}
+
+	// Rejections still carry CORS headers so the dashboard can read the 401
+	req = httptest.NewRequest(http.MethodGet, "/apps", nil)
+	req.Header.Set("Origin", "https://dash.example")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Access-Control-Allow-Origin") != "https://dash.example" {
+		t.Errorf("Expected a 401 with CORS headers, got %d %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestNewCORS_Disabled(t *testing.T) {
+	if c := NewCORS(config.ServerConfig{CORSAllowedOrigins: " , "}); c != nil {
+		t.Error("Expected no CORS middleware without origins")
+	}
+}
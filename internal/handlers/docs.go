@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+//go:embed docs.html
+var docsHTML string
+
+// docsPage renders the embedded OpenAPI specification as browsable reference
+// docs, with no assets from outside the binary
+var docsPage = template.Must(template.New("docs").Parse(docsHTML))
+
+// handleDocs handles GET /docs - an HTML page listing the operations and
+// schemas in /swagger.json
+func (h *AppHandler) handleDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	nonce, err := cspNonce()
+	if err != nil {
+		h.logger.Error("Failed to create docs nonce", zap.Error(err))
+		writeError(w, r, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	var page bytes.Buffer
+	if err := docsPage.Execute(&page, struct{ Nonce string }{nonce}); err != nil {
+		h.logger.Error("Failed to render docs page", zap.Error(err))
+		writeError(w, r, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; connect-src 'self'; "+
+		"script-src 'nonce-"+nonce+"'; style-src 'nonce-"+nonce+"'; base-uri 'none'; form-action 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(page.Bytes())
+}
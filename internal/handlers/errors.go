@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/koios/matrx-renderer/internal/pixlet"
+)
+
+// maxRequestIDLength bounds client-supplied request IDs echoed into logs
+const maxRequestIDLength = 128
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Code      string      `json:"code"`                 // machine-readable, e.g. "not_found"
+	Message   string      `json:"message"`              // human-readable
+	RequestID string      `json:"request_id,omitempty"` // matches the X-Request-ID header
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// validationFailureDetails are the details of a 422 response for a config that
+// failed schema validation
+type validationFailureDetails struct {
+	Errors           []ValidationError      `json:"errors"`
+	NormalizedConfig map[string]interface{} `json:"normalized_config,omitempty"`
+}
+
+// errorCodes overrides the code derived from the status text
+var errorCodes = map[int]string{
+	http.StatusInternalServerError: "internal_error",
+	http.StatusUnprocessableEntity: "validation_failed",
+	http.StatusTooManyRequests:     "rate_limited",
+}
+
+// errorCode returns the envelope code for an HTTP status, e.g. "method_not_allowed"
+func errorCode(status int) string {
+	if code, ok := errorCodes[status]; ok {
+		return code
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// writeError writes a JSON error envelope with the code for status
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeErrorDetails(w, r, status, message, nil)
+}
+
+// writeErrorDetails writes a JSON error envelope with extra details
+func writeErrorDetails(w http.ResponseWriter, r *http.Request, status int, message string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:      errorCode(status),
+		Message:   message,
+		RequestID: pixlet.JobIDFromContext(r.Context()),
+		Details:   details,
+	})
+}
+
+// RequestID tags each request with the client's X-Request-ID, or a new one, and
+// echoes it in the response. Render jobs and error bodies carry the same ID, so
+// worker logs can be traced back to the request. It belongs outermost so every
+// response, including rejections, has an ID.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestID(r)
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(pixlet.WithJobID(r.Context(), id)))
+	})
+}
+
+// requestID returns the client's X-Request-ID if it is safe to log, or a new ID
+func requestID(r *http.Request) string {
+	id := r.Header.Get("X-Request-ID")
+	if id == "" || len(id) > maxRequestIDLength {
+		return pixlet.NewJobID()
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return pixlet.NewJobID()
+		}
+	}
+	return id
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/pixlet"
+)
+
+func TestWriteError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/apps/missing", nil)
+	req = req.WithContext(pixlet.WithJobID(req.Context(), "req-1"))
+	w := httptest.NewRecorder()
+	writeError(w, req, http.StatusNotFound, "App not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON, got %q", ct)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	want := ErrorResponse{Code: "not_found", Message: "App not found", RequestID: "req-1"}
+	if resp != want {
+		t.Errorf("Expected %+v, got %+v", want, resp)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:          "bad_request",
+		http.StatusMethodNotAllowed:    "method_not_allowed",
+		http.StatusUnprocessableEntity: "validation_failed",
+		http.StatusTooManyRequests:     "rate_limited",
+		http.StatusInternalServerError: "internal_error",
+		http.StatusServiceUnavailable:  "service_unavailable",
+		599:                            "error",
+	}
+	for status, want := range tests {
+		if got := errorCode(status); got != want {
+			t.Errorf("errorCode(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestValidationFailureEnvelope(t *testing.T) {
+	h := setupTestHandler(t)
+
+	body := bytes.NewBufferString(`{"unknown_field": true}`)
+	req := httptest.NewRequest(http.MethodPost, "/apps/test-app/render", body)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422 for an unknown field, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Code    string                   `json:"code"`
+		Details validationFailureDetails `json:"details"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if resp.Code != "validation_failed" || len(resp.Details.Errors) == 0 {
+		t.Errorf("Expected validation errors in details, got %+v", resp)
+	}
+}
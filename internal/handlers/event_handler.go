@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,6 +26,8 @@ func NewEventHandler(logger *zap.Logger, cfg *config.Config) *EventHandler {
 	if cfg.Redis.Addr != "" {
 		logger.Info("Initializing Pixlet processor with Redis cache",
 			zap.String("redis_addr", cfg.Redis.Addr),
+			zap.String("redis_sentinel_master", cfg.Redis.SentinelMasterName),
+			zap.Strings("redis_cluster_addrs", cfg.Redis.ClusterAddrs),
 			zap.Int("redis_db", cfg.Redis.DB))
 		pixletProcessor = pixlet.NewProcessorWithRedis(&cfg.Pixlet, &cfg.Redis, logger)
 	} else {
@@ -39,7 +42,9 @@ func NewEventHandler(logger *zap.Logger, cfg *config.Config) *EventHandler {
 	}
 }
 
-// Handle processes a render request event
+// Handle processes a render request event. A redelivered request that already
+// rendered returns its earlier result with pixlet.ErrDuplicateRequest, for the
+// caller to acknowledge without publishing again.
 func (h *EventHandler) Handle(ctx context.Context, request *models.RenderRequest) (*models.RenderResult, error) {
 	h.logger.Info("Processing render request",
 		zap.String("app_id", request.AppID),
@@ -75,7 +80,27 @@ func (h *EventHandler) Handle(ctx context.Context, request *models.RenderRequest
 		return errorResult(), fmt.Errorf("device.id is required")
 	}
 
-	result, err := h.pixletProcessor.RenderApp(ctx, request)
+	// Unknown apps fail here rather than after waiting for a worker
+	if _, ok := h.pixletProcessor.GetAppRegistry().GetApp(request.AppID); !ok {
+		h.logger.Error("Unknown app_id", zap.String("app_id", request.AppID))
+		return errorResult(), fmt.Errorf("%w: %s", pixlet.ErrAppNotFound, request.AppID)
+	}
+
+	// Worker logs carry the request UUID so they can be matched to this event
+	if request.UUID != "" {
+		ctx = pixlet.WithJobID(ctx, request.UUID)
+	}
+
+	// Queue-driven refreshes yield to interactive renders from the HTTP API.
+	// Redeliveries of a request that already rendered get its earlier result.
+	result, err := h.pixletProcessor.RenderAppOnce(pixlet.WithPriority(ctx, pixlet.PriorityBackground), request)
+	if errors.Is(err, pixlet.ErrDuplicateRequest) {
+		h.logger.Info("Skipped duplicate render request",
+			zap.String("uuid", request.UUID),
+			zap.String("app_id", request.AppID),
+			zap.String("device_id", request.Device.ID))
+		return result, err
+	}
 	if err != nil {
 		h.logger.Error("Render request failed",
 			zap.Error(err),
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestEventHandler_InvalidRequests(t *testing.T) {
+	h := NewEventHandler(zap.NewNop(), &config.Config{Pixlet: config.PixletConfig{AppsPath: t.TempDir()}})
+	defer h.GetProcessor().Stop()
+
+	valid := func() *models.RenderRequest {
+		return &models.RenderRequest{Type: "render_request", UUID: "u1", AppID: "missing", Device: models.Device{ID: "d1"}}
+	}
+	tests := []struct {
+		name   string
+		mutate func(r *models.RenderRequest)
+		want   error
+	}{
+		{"wrong type", func(r *models.RenderRequest) { r.Type = "ping" }, nil},
+		{"missing app", func(r *models.RenderRequest) { r.AppID = "" }, nil},
+		{"missing device", func(r *models.RenderRequest) { r.Device.ID = "" }, nil},
+		{"unknown app", func(r *models.RenderRequest) {}, pixlet.ErrAppNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := valid()
+			tt.mutate(request)
+			result, err := h.Handle(context.Background(), request)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+			if !result.Error || result.UUID != "u1" {
+				t.Errorf("Expected an error result for the request, got %+v", result)
+			}
+		})
+	}
+}
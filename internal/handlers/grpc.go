@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/koios/matrx-renderer/internal/auth"
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"github.com/koios/matrx-renderer/pkg/rendererpb"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxStreamRenders caps renders in flight on one RenderStream; further requests
+// wait for a slot, pushing back on the client
+const maxStreamRenders = 8
+
+// GRPCServer serves the Renderer gRPC service from the same processor and
+// validator as the HTTP API
+type GRPCServer struct {
+	rendererpb.UnimplementedRendererServer
+	processor *pixlet.Processor
+	validator *Validator
+	logger    *zap.Logger
+}
+
+// NewGRPCServer creates the Renderer service
+func NewGRPCServer(processor *pixlet.Processor, logger *zap.Logger) *GRPCServer {
+	return &GRPCServer{
+		processor: processor,
+		validator: NewValidator(processor, logger),
+		logger:    logger,
+	}
+}
+
+// Render validates and renders one request
+func (s *GRPCServer) Render(ctx context.Context, req *rendererpb.RenderRequest) (*rendererpb.RenderResult, error) {
+	return s.render(grpcJobContext(ctx, req), req)
+}
+
+// RenderStream renders requests as they arrive and sends each result when ready
+func (s *GRPCServer) RenderStream(stream rendererpb.Renderer_RenderStreamServer) error {
+	ctx := stream.Context()
+	var (
+		wg      sync.WaitGroup
+		sendMu  sync.Mutex
+		sendErr error
+		slots   = make(chan struct{}, maxStreamRenders)
+	)
+	defer wg.Wait()
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result, err := s.render(grpcJobContext(ctx, req), req)
+			if err != nil {
+				result = &rendererpb.RenderResult{
+					Uuid:        req.GetUuid(),
+					AppId:       req.GetAppId(),
+					DeviceId:    req.GetDevice().GetId(),
+					Error:       status.Convert(err).Message(),
+					ProcessedAt: timestamppb.Now(),
+				}
+			}
+
+			sendMu.Lock()
+			defer sendMu.Unlock()
+			if sendErr == nil {
+				sendErr = stream.Send(result)
+			}
+		}()
+	}
+}
+
+// render validates req against the app's schema and renders it as WebP
+func (s *GRPCServer) render(ctx context.Context, req *rendererpb.RenderRequest) (*rendererpb.RenderResult, error) {
+	normalizedConfig, err := s.validRenderConfig(ctx, req.GetAppId(), req.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	device := models.Device{
+		ID:     req.GetDevice().GetId(),
+		Width:  int(req.GetDevice().GetWidth()),
+		Height: int(req.GetDevice().GetHeight()),
+	}
+	if device.ID == "" {
+		device.ID = "grpc-render"
+	}
+	if device.Width == 0 || device.Height == 0 {
+		size := s.processor.DefaultDeviceSize()
+		device.Width, device.Height = size.Width, size.Height
+	}
+	if err := s.processor.CheckDeviceSize(models.Size{Width: device.Width, Height: device.Height}); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	opts, err := grpcRenderOptions(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	webp, err := s.processor.RenderPreview(ctx, req.GetAppId(), addDisplayDimensions(normalizedConfig, device), device, "webp", opts)
+	if err != nil {
+		s.logger.Error("Failed to render app",
+			zap.String("app_id", req.GetAppId()),
+			zap.String("device_id", device.ID),
+			zap.Error(err))
+		return nil, grpcRenderError(err)
+	}
+
+	publishRender(s.processor.Results(), pixlet.JobIDFromContext(ctx), req.GetAppId(), device.ID, webp)
+
+	normalized, err := toStruct(normalizedConfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to encode normalized config")
+	}
+
+	s.logger.Info("Rendered app via gRPC",
+		zap.String("app_id", req.GetAppId()),
+		zap.String("device_id", device.ID),
+		zap.Int("output_size", len(webp)))
+	return &rendererpb.RenderResult{
+		Uuid:             pixlet.JobIDFromContext(ctx),
+		AppId:            req.GetAppId(),
+		DeviceId:         device.ID,
+		Webp:             webp,
+		Skipped:          len(webp) == 0,
+		ProcessedAt:      timestamppb.Now(),
+		NormalizedConfig: normalized,
+	}, nil
+}
+
+// validRenderConfig returns the normalized config, or an error status if the
+// app is missing or the config fails validation
+func (s *GRPCServer) validRenderConfig(ctx context.Context, appID string, config *structpb.Struct) (map[string]interface{}, error) {
+	normalizedConfig, validationErrors, err := s.validate(ctx, appID, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(validationErrors) == 0 {
+		return normalizedConfig, nil
+	}
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(validationErrors))
+	for i, ve := range validationErrors {
+		violations[i] = &errdetails.BadRequest_FieldViolation{Field: ve.Field, Description: ve.Message}
+	}
+	st, detailErr := status.New(codes.InvalidArgument, "config failed validation").
+		WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if detailErr != nil {
+		return nil, status.Error(codes.InvalidArgument, "config failed validation")
+	}
+	return nil, st.Err()
+}
+
+// validate loads the app's schema and validates config against it
+func (s *GRPCServer) validate(ctx context.Context, appID string, config *structpb.Struct) (map[string]interface{}, []ValidationError, error) {
+	if appID == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	appSchema, err := s.processor.GetAppSchema(ctx, appID)
+	if err != nil {
+		return nil, nil, s.schemaError(appID, err)
+	}
+
+	normalizedConfig, validationErrors, err := s.validator.ValidateConfig(ctx, appID, config.AsMap(), appSchema)
+	if err != nil {
+		s.logger.Error("Failed to validate config",
+			zap.String("app_id", appID),
+			zap.Error(err))
+		return nil, nil, status.Error(codes.Internal, "failed to validate config")
+	}
+	return normalizedConfig, validationErrors, nil
+}
+
+// GetSchema returns an app's schema
+func (s *GRPCServer) GetSchema(ctx context.Context, req *rendererpb.GetSchemaRequest) (*rendererpb.GetSchemaResponse, error) {
+	if req.GetAppId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+	appSchema, err := s.processor.GetAppSchema(ctx, req.GetAppId())
+	if err != nil {
+		return nil, s.schemaError(req.GetAppId(), err)
+	}
+
+	schema, err := toStruct(appSchema)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to encode schema")
+	}
+	return &rendererpb.GetSchemaResponse{Schema: schema}, nil
+}
+
+// ValidateConfig checks a config against an app's schema
+func (s *GRPCServer) ValidateConfig(ctx context.Context, req *rendererpb.ValidateConfigRequest) (*rendererpb.ValidateConfigResponse, error) {
+	normalizedConfig, validationErrors, err := s.validate(ctx, req.GetAppId(), req.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, err := toStruct(normalizedConfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to encode normalized config")
+	}
+	resp := &rendererpb.ValidateConfigResponse{
+		Valid:            len(validationErrors) == 0,
+		NormalizedConfig: normalized,
+	}
+	for _, ve := range validationErrors {
+		resp.Errors = append(resp.Errors, &rendererpb.ValidationError{Field: ve.Field, Message: ve.Message, Code: ve.Code})
+	}
+	return resp, nil
+}
+
+// ListApps lists apps with the same filters, sorting and paging as GET /apps
+func (s *GRPCServer) ListApps(ctx context.Context, req *rendererpb.ListAppsRequest) (*rendererpb.ListAppsResponse, error) {
+	values := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set("author", req.GetAuthor())
+	set("tag", req.GetTag())
+	set("sort", req.GetSort())
+	if req.HasSchema != nil {
+		set("has_schema", strconv.FormatBool(req.GetHasSchema()))
+	}
+	set("offset", strconv.Itoa(int(req.GetOffset())))
+	set("limit", strconv.Itoa(int(req.GetLimit())))
+
+	query, err := parseAppQuery(values)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	apps, total := query.apply(s.processor.GetAppRegistry().GetAppsList())
+
+	quarantined := s.processor.QuarantinedApps()
+	resp := &rendererpb.ListAppsResponse{Total: int32(total)}
+	for _, app := range apps {
+		pb := &rendererpb.App{
+			Id:          app.ID,
+			Name:        app.Name,
+			Summary:     app.Summary,
+			Description: app.Description,
+			Author:      app.Author,
+			Tags:        app.Tags,
+			HasSchema:   app.HasSchema,
+		}
+		if until, ok := quarantined[app.ID]; ok {
+			pb.Quarantined = true
+			pb.QuarantinedUntil = timestamppb.New(until)
+		}
+		resp.Apps = append(resp.Apps, pb)
+	}
+	return resp, nil
+}
+
+// schemaError maps a schema load failure to a status
+func (s *GRPCServer) schemaError(appID string, err error) error {
+	if strings.Contains(err.Error(), "not found") {
+		return status.Error(codes.NotFound, "app not found")
+	}
+	s.logger.Error("Failed to get app schema",
+		zap.String("app_id", appID),
+		zap.Error(err))
+	return status.Error(codes.Internal, "failed to get app schema")
+}
+
+// grpcRenderError maps a render failure to a status, as writeRenderError does
+// for HTTP: RESOURCE_EXHAUSTED when the queue is full, UNAVAILABLE while the
+// service shuts down
+func grpcRenderError(err error) error {
+	switch {
+	case errors.Is(err, pixlet.ErrQueueFull):
+		return status.Error(codes.ResourceExhausted, "render queue is full, retry later")
+	case errors.Is(err, pixlet.ErrPoolStopped):
+		return status.Error(codes.Unavailable, "renderer is shutting down")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.Internal, "failed to render app: "+err.Error())
+}
+
+// grpcRenderOptions converts a request's render time, encoding and overlay settings
+func grpcRenderOptions(req *rendererpb.RenderRequest) (pixlet.RenderOptions, error) {
+	opts := pixlet.RenderOptions{DebugOverlay: req.GetDebugOverlay()}
+	if req.RenderTime != nil {
+		opts.RenderTime = req.GetRenderTime().AsTime()
+	}
+
+	if enc := req.GetEncoding(); enc != nil {
+		var encoding models.EncodingOptions
+		var check pixlet.WebPOptions
+		if enc.Lossless != nil {
+			lossless := enc.GetLossless()
+			encoding.Lossless = &lossless
+		}
+		if enc.Quality != nil {
+			quality := int(enc.GetQuality())
+			encoding.Quality = &quality
+			check.Quality = quality
+		}
+		if enc.Method != nil {
+			method := int(enc.GetMethod())
+			encoding.Method = &method
+			check.Method = method
+		}
+		if err := check.Validate(); err != nil {
+			return opts, err
+		}
+		opts.Encoding = &encoding
+	}
+	return opts, nil
+}
+
+// grpcJobContext tags ctx with the request's uuid, or a new ID, so worker logs
+// and the result carry it
+func grpcJobContext(ctx context.Context, req *rendererpb.RenderRequest) context.Context {
+	id := req.GetUuid()
+	if id == "" || len(id) > maxRequestIDLength {
+		id = pixlet.NewJobID()
+	}
+	return pixlet.WithJobID(ctx, id)
+}
+
+// toStruct converts a JSON-encodable value to a protobuf Struct
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %w", err)
+	}
+	return structpb.NewStruct(m)
+}
+
+// grpcMethodRoles are the roles each Renderer method requires when
+// authentication is enabled, matching the HTTP API
+var grpcMethodRoles = map[string]auth.Role{
+	rendererpb.Renderer_ListApps_FullMethodName:       auth.RoleRead,
+	rendererpb.Renderer_GetSchema_FullMethodName:      auth.RoleRead,
+	rendererpb.Renderer_ValidateConfig_FullMethodName: auth.RoleRender,
+	rendererpb.Renderer_Render_FullMethodName:         auth.RoleRender,
+	rendererpb.Renderer_RenderStream_FullMethodName:   auth.RoleRender,
+}
+
+// GRPCServerOptions returns interceptors enforcing the authenticator on gRPC
+// calls. A nil authenticator returns no options.
+func (a *Authenticator) GRPCServerOptions() []grpc.ServerOption {
+	if a == nil {
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := a.authorizeGRPC(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := a.authorizeGRPC(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+// authorizeGRPC verifies the bearer token in the call's "authorization" metadata
+// and returns ctx carrying the caller's principal
+func (a *Authenticator) authorizeGRPC(ctx context.Context, method string) (context.Context, error) {
+	required, known := grpcMethodRoles[method]
+	if !known {
+		required = auth.RoleAdmin
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	var ok bool
+	if values := md.Get("authorization"); len(values) > 0 {
+		token, ok = parseBearer(values[0])
+	}
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "bearer token required")
+	}
+
+	claims, err := a.verifier.Verify(ctx, token)
+	if err != nil {
+		a.logger.Debug("Rejected bearer token", zap.String("method", method), zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+	}
+
+	principal := auth.Principal{Subject: claims.Subject(), Role: a.roles.Role(claims)}
+	if principal.Role < required {
+		return nil, status.Error(codes.PermissionDenied, "role "+required.String()+" required")
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// authenticatedStream is a server stream whose context carries the principal
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
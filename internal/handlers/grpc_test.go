@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/koios/matrx-renderer/pkg/rendererpb"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// setupTestGRPC serves the Renderer service for the test app over an in-memory
+// connection and returns a client
+func setupTestGRPC(t *testing.T, opts ...grpc.ServerOption) rendererpb.RendererClient {
+	t.Helper()
+	h := setupTestHandler(t)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(opts...)
+	rendererpb.RegisterRendererServer(server, NewGRPCServer(h.processor, zap.NewNop()))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return rendererpb.NewRendererClient(conn)
+}
+
+func TestGRPCListApps(t *testing.T) {
+	client := setupTestGRPC(t)
+	ctx := context.Background()
+
+	resp, err := client.ListApps(ctx, &rendererpb.ListAppsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetTotal() != 1 || len(resp.GetApps()) != 1 || resp.GetApps()[0].GetId() != "test-app" {
+		t.Errorf("Expected test-app, got %v", resp)
+	}
+	if !resp.GetApps()[0].GetHasSchema() {
+		t.Error("Expected test-app to have a schema")
+	}
+
+	noSchema := false
+	resp, err = client.ListApps(ctx, &rendererpb.ListAppsRequest{HasSchema: &noSchema})
+	if err != nil || resp.GetTotal() != 0 {
+		t.Errorf("Expected no apps without a schema, got %v, %v", resp, err)
+	}
+
+	_, err = client.ListApps(ctx, &rendererpb.ListAppsRequest{Sort: "author"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected INVALID_ARGUMENT for a bad sort, got %v", err)
+	}
+}
+
+func TestGRPCGetSchema(t *testing.T) {
+	client := setupTestGRPC(t)
+	ctx := context.Background()
+
+	resp, err := client.GetSchema(ctx, &rendererpb.GetSchemaRequest{AppId: "test-app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetSchema().GetFields()["schema"] == nil {
+		t.Errorf("Expected schema fields, got %v", resp.GetSchema())
+	}
+
+	_, err = client.GetSchema(ctx, &rendererpb.GetSchemaRequest{AppId: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NOT_FOUND, got %v", err)
+	}
+}
+
+func TestGRPCValidateConfig(t *testing.T) {
+	client := setupTestGRPC(t)
+	ctx := context.Background()
+
+	config, _ := structpb.NewStruct(map[string]interface{}{"user_id": "alice"})
+	resp, err := client.ValidateConfig(ctx, &rendererpb.ValidateConfigRequest{AppId: "test-app", Config: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.GetValid() || resp.GetNormalizedConfig().GetFields()["user_id"].GetStringValue() != "alice" {
+		t.Errorf("Expected a valid config, got %v", resp)
+	}
+
+	config, _ = structpb.NewStruct(map[string]interface{}{"unknown_field": true})
+	resp, err = client.ValidateConfig(ctx, &rendererpb.ValidateConfigRequest{AppId: "test-app", Config: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetValid() || len(resp.GetErrors()) == 0 {
+		t.Errorf("Expected validation errors, got %v", resp)
+	}
+}
+
+func TestGRPCRender_InvalidConfig(t *testing.T) {
+	client := setupTestGRPC(t)
+	ctx := context.Background()
+
+	config, _ := structpb.NewStruct(map[string]interface{}{"unknown_field": true})
+	_, err := client.Render(ctx, &rendererpb.RenderRequest{AppId: "test-app", Config: config})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("Expected INVALID_ARGUMENT, got %v", err)
+	}
+	var violations int
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			violations += len(br.GetFieldViolations())
+		}
+	}
+	if violations == 0 {
+		t.Error("Expected field violations in the status details")
+	}
+
+	_, err = client.Render(ctx, &rendererpb.RenderRequest{AppId: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NOT_FOUND, got %v", err)
+	}
+
+	quality := int32(500)
+	_, err = client.Render(ctx, &rendererpb.RenderRequest{AppId: "test-app", Encoding: &rendererpb.EncodingOptions{Quality: &quality}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected INVALID_ARGUMENT for a bad quality, got %v", err)
+	}
+}
+
+func TestGRPCRenderStream(t *testing.T) {
+	client := setupTestGRPC(t)
+
+	stream, err := client.RenderStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := stream.Send(&rendererpb.RenderRequest{Uuid: id, AppId: "missing"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream.CloseSend()
+
+	seen := map[string]bool{}
+	for {
+		result, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected failures to be reported per result, got %v", err)
+		}
+		if result.GetError() == "" {
+			t.Errorf("Expected an error for %s", result.GetUuid())
+		}
+		seen[result.GetUuid()] = true
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Errorf("Expected a result for each request, got %v", seen)
+	}
+}
+
+func TestGRPCAuthentication(t *testing.T) {
+	a, issue := setupTestAuthenticator(t)
+	client := setupTestGRPC(t, a.GRPCServerOptions()...)
+
+	_, err := client.ListApps(context.Background(), &rendererpb.ListAppsRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected UNAUTHENTICATED without a token, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+issue("viewers"))
+	if _, err := client.ListApps(ctx, &rendererpb.ListAppsRequest{}); err != nil {
+		t.Errorf("Expected a reader to list apps, got %v", err)
+	}
+	_, err = client.Render(ctx, &rendererpb.RenderRequest{AppId: "test-app"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PERMISSION_DENIED for a reader rendering, got %v", err)
+	}
+
+	stream, err := client.RenderStream(ctx)
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PERMISSION_DENIED on the stream, got %v", err)
+	}
+}
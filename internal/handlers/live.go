@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"go.uber.org/zap"
+)
+
+const (
+	liveMaxInterval   = 3600             // Longest re-render interval, in seconds
+	liveKeepAlive     = 15 * time.Second // Comment sent on idle streams so proxies keep them open
+	liveSessionIDSize = 16               // Random bytes in a session ID
+)
+
+// liveSessions tracks open live preview streams, so configs posted to a
+// session reach the stream that re-renders them
+type liveSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*liveSession
+}
+
+// liveSession is one live preview stream
+type liveSession struct {
+	appID   string
+	configs chan map[string]interface{} // Latest validated config not yet rendered
+}
+
+// livePreview is the data of a "preview" event
+type livePreview struct {
+	Image      string    `json:"image"` // base64 encoded WebP; empty when skipped
+	Skipped    bool      `json:"skipped"`
+	RenderedAt time.Time `json:"rendered_at"`
+}
+
+// LiveConfigResponse is returned when a config is accepted for a live session
+type LiveConfigResponse struct {
+	SessionID        string                 `json:"session_id"`
+	NormalizedConfig map[string]interface{} `json:"normalized_config"`
+}
+
+func newLiveSessions() *liveSessions {
+	return &liveSessions{sessions: make(map[string]*liveSession)}
+}
+
+// open registers a session for appID and returns its ID
+func (l *liveSessions) open(appID string) (string, *liveSession, error) {
+	var b [liveSessionIDSize]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(b[:])
+	session := &liveSession{appID: appID, configs: make(chan map[string]interface{}, 1)}
+
+	l.mu.Lock()
+	l.sessions[id] = session
+	l.mu.Unlock()
+	return id, session, nil
+}
+
+// end removes a session once its stream finishes
+func (l *liveSessions) end(id string) {
+	l.mu.Lock()
+	delete(l.sessions, id)
+	l.mu.Unlock()
+}
+
+// update hands config to appID's session id, replacing any config its stream
+// hasn't rendered yet. It reports false if there is no such session.
+func (l *liveSessions) update(appID, id string, config map[string]interface{}) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	session, ok := l.sessions[id]
+	if !ok || session.appID != appID {
+		return false
+	}
+	select {
+	case <-session.configs:
+	default:
+	}
+	session.configs <- config
+	return true
+}
+
+// CloseStreams ends live preview and WebSocket streams, which otherwise stay
+// open until the client leaves. Call it when the server starts shutting down.
+func (h *AppHandler) CloseStreams() {
+	h.closeOnce.Do(func() { close(h.closing) })
+}
+
+// handleAppLive handles GET /apps/{id}/live - a server-sent event stream that
+// renders the app with schema defaults, then again whenever a config is posted
+// to the session and, with interval, every interval seconds
+func (h *AppHandler) handleAppLive(w http.ResponseWriter, r *http.Request, appID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	interval := 0
+	if raw := r.URL.Query().Get("interval"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > liveMaxInterval {
+			writeError(w, r, http.StatusBadRequest, fmt.Sprintf("interval must be between 0 and %d seconds", liveMaxInterval))
+			return
+		}
+		interval = n
+	}
+
+	params, device, renderOpts, ok := h.prepareDefaultRender(w, r, appID, "live")
+	if !ok {
+		return
+	}
+
+	sessionID, session, err := h.live.open(appID)
+	if err != nil {
+		h.logger.Error("Failed to create live session", zap.Error(err))
+		writeError(w, r, http.StatusInternalServerError, "Failed to create live session")
+		return
+	}
+	defer h.live.end(sessionID)
+
+	// Streams outlive the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	flusher, _ := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data interface{}) error {
+		body, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, body); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	render := func() error {
+		webp, err := h.processor.RenderPreview(r.Context(), appID, params, device, "webp", renderOpts)
+		if err != nil {
+			h.logger.Warn("Live preview render failed",
+				zap.String("app_id", appID),
+				zap.String("session_id", sessionID),
+				zap.Error(err))
+			status, message := renderError(err, "Failed to render app")
+			return send("error", ErrorResponse{
+				Code:      errorCode(status),
+				Message:   message,
+				RequestID: pixlet.JobIDFromContext(r.Context()),
+			})
+		}
+		return send("preview", livePreview{
+			Image:      base64.StdEncoding.EncodeToString(webp),
+			Skipped:    len(webp) == 0,
+			RenderedAt: time.Now(),
+		})
+	}
+
+	h.logger.Info("Live preview started",
+		zap.String("app_id", appID),
+		zap.String("session_id", sessionID),
+		zap.Int("interval", interval))
+
+	if err := send("session", map[string]string{"session_id": sessionID}); err != nil {
+		return
+	}
+	err = render()
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	keepAlive := time.NewTicker(liveKeepAlive)
+	defer keepAlive.Stop()
+
+	for err == nil {
+		select {
+		case config := <-session.configs:
+			params = addDisplayDimensions(config, device)
+			err = render()
+		case <-tick:
+			err = render()
+		case <-keepAlive.C:
+			if _, err = fmt.Fprint(w, ": keepalive\n\n"); err == nil && flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			err = r.Context().Err()
+		case <-h.closing:
+			return
+		}
+	}
+
+	h.logger.Info("Live preview ended",
+		zap.String("app_id", appID),
+		zap.String("session_id", sessionID))
+}
+
+// handleAppLiveConfig handles POST /apps/{id}/live/{session} - validates the
+// config at the JSON root and hands it to the session's stream to render
+func (h *AppHandler) handleAppLiveConfig(w http.ResponseWriter, r *http.Request, appID, sessionID string) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	normalizedConfig, ok := h.validateRenderConfig(w, r, appID)
+	if !ok {
+		return
+	}
+
+	if !h.live.update(appID, sessionID, normalizedConfig) {
+		writeError(w, r, http.StatusNotFound, "Live session not found")
+		return
+	}
+
+	h.writeJSON(w, http.StatusAccepted, LiveConfigResponse{
+		SessionID:        sessionID,
+		NormalizedConfig: normalizedConfig,
+	})
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is one server-sent event read from a live stream
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readSSEEvent reads the next event, skipping comments
+func readSSEEvent(t *testing.T, reader *bufio.Reader) (sseEvent, error) {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return event, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.name != "":
+			return event, nil
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// openLiveStream starts a live preview of the test app and returns its session ID
+func openLiveStream(t *testing.T, h *AppHandler, server *httptest.Server) (string, *bufio.Reader) {
+	t.Helper()
+	resp, err := http.Get(server.URL + "/v1/apps/test-app/live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	event, err := readSSEEvent(t, reader)
+	if err != nil || event.name != "session" {
+		t.Fatalf("Expected a session event first, got %+v, %v", event, err)
+	}
+	var session map[string]string
+	if err := json.Unmarshal([]byte(event.data), &session); err != nil || session["session_id"] == "" {
+		t.Fatalf("Expected a session ID, got %s", event.data)
+	}
+	return session["session_id"], reader
+}
+
+// expectRenderEvent reads the event for a render, which is a preview or, when
+// the WebP encoder is unavailable, an error
+func expectRenderEvent(t *testing.T, reader *bufio.Reader) {
+	t.Helper()
+	event, err := readSSEEvent(t, reader)
+	if err != nil {
+		t.Fatalf("Expected a render event, got %v", err)
+	}
+	if event.name != "preview" && event.name != "error" {
+		t.Fatalf("Expected a preview or error event, got %+v", event)
+	}
+}
+
+func TestLivePreview(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	sessionID, reader := openLiveStream(t, h, server)
+	expectRenderEvent(t, reader)
+
+	post := func(path, body string) *http.Response {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("/v1/apps/test-app/live/"+sessionID, `{"user_id": "alice"}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202 for a valid config, got %d", resp.StatusCode)
+	}
+	expectRenderEvent(t, reader)
+
+	if resp := post("/v1/apps/test-app/live/"+sessionID, `{"unknown_field": true}`); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an invalid config, got %d", resp.StatusCode)
+	}
+	if resp := post("/v1/apps/test-app/live/unknown", `{}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown session, got %d", resp.StatusCode)
+	}
+
+	// Shutdown ends the stream
+	h.CloseStreams()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, reader)
+		done <- err
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected CloseStreams to end the stream")
+	}
+	if resp := post("/v1/apps/test-app/live/"+sessionID, `{}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected the ended session to be gone, got %d", resp.StatusCode)
+	}
+}
+
+func TestLivePreview_Interval(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, interval := range []string{"-1", "abc", "3601"} {
+		req := httptest.NewRequest(http.MethodGet, "/apps/test-app/live?interval="+interval, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for interval %s, got %d", interval, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/apps/test-app/live", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST to the stream, got %d", w.Code)
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// NewPprofMux returns a mux serving the runtime profiling endpoints under
+// /debug/pprof/. It is meant for a separate admin listener that isn't exposed
+// publicly, since profiles reveal internals and CPU profiles are expensive.
+func NewPprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/auth"
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"go.uber.org/zap"
+)
+
+// rateLimitSweepInterval is how often idle client buckets are dropped
+const rateLimitSweepInterval = time.Minute
+
+// RateLimiter gives each client a token bucket per endpoint class, so one
+// dashboard polling previews can't starve everyone else of render workers.
+// Clients are keyed by token subject when authenticated, otherwise by IP.
+type RateLimiter struct {
+	read       *clientBuckets // listings, schemas, stats
+	render     *clientBuckets // anything that runs an app
+	trustProxy bool
+	shared     *pixlet.SharedRateLimiter // Buckets in Redis; nil keeps them in memory
+	logger     *zap.Logger
+}
+
+// NewRateLimiter returns a limiter for cfg, or nil if both budgets are unlimited
+func NewRateLimiter(cfg config.ServerConfig, logger *zap.Logger) *RateLimiter {
+	if cfg.RateLimitRead <= 0 && cfg.RateLimitRender <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		read:       newClientBuckets(cfg.RateLimitRead, cfg.RateLimitReadBurst),
+		render:     newClientBuckets(cfg.RateLimitRender, cfg.RateLimitRenderBurst),
+		trustProxy: cfg.RateLimitTrustProxy,
+		logger:     logger,
+	}
+}
+
+// UseShared keeps client buckets in Redis, so each client's budget holds across
+// every replica. If Redis can't be reached, requests are limited per process.
+// It does nothing on a nil limiter.
+func (l *RateLimiter) UseShared(shared *pixlet.SharedRateLimiter) {
+	if l == nil {
+		return
+	}
+	l.shared = shared
+}
+
+// Wrap returns next with rate limiting applied. It must run inside the
+// Authenticator to key on token subjects. A nil limiter returns next unchanged.
+func (l *RateLimiter) Wrap(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := endpointClass(r)
+		var buckets *clientBuckets
+		switch class {
+		case "read":
+			buckets = l.read
+		case "render":
+			buckets = l.render
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := l.clientKey(r)
+		if wait := l.take(r.Context(), class, buckets, client); wait > 0 {
+			l.logger.Debug("Rate limited request",
+				zap.String("client", client),
+				zap.String("path", r.URL.Path),
+				zap.Duration("retry_after", wait))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, r, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take spends one of client's tokens for an endpoint class, in Redis when shared
+// and reachable, otherwise in memory. See clientBuckets.take.
+func (l *RateLimiter) take(ctx context.Context, class string, buckets *clientBuckets, client string) time.Duration {
+	if l.shared != nil && buckets.rate > 0 {
+		wait, err := l.shared.Take(ctx, "http:"+class+":"+client, buckets.rate, buckets.burst)
+		if err == nil {
+			return wait
+		}
+		l.logger.Debug("Shared rate limit unavailable, limiting locally", zap.Error(err))
+	}
+	return buckets.take(client, time.Now())
+}
+
+// endpointClass returns "render" for endpoints that run an app, "read" for other
+// API endpoints, and "" for probes and scrapes, which are never limited
+func endpointClass(r *http.Request) string {
+	path := apiPath(r.URL.Path)
+	switch path {
+	case "/health", "/livez", "/readyz", "/metrics":
+		return ""
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, "/apps/"), "/")
+	if !strings.HasPrefix(path, "/apps/") || len(parts) < 2 {
+		return "read"
+	}
+	switch action := parts[1]; {
+	case action == "render", action == "benchmark", action == "call_handler",
+		action == "frames", action == "frames.zip", action == "live", strings.HasPrefix(action, "preview."):
+		return "render"
+	}
+	return "read"
+}
+
+// clientKey identifies the caller: the token subject when authenticated,
+// otherwise the client IP
+func (l *RateLimiter) clientKey(r *http.Request) string {
+	if p, ok := auth.PrincipalFromContext(r.Context()); ok && p.Subject != "" {
+		return "sub:" + p.Subject
+	}
+
+	if l.trustProxy {
+		// The first address is the original client; proxies append theirs
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return "ip:" + ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// clientBuckets holds a token bucket per client, all with the same rate
+type clientBuckets struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*clientBucket
+	swept   time.Time
+}
+
+type clientBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newClientBuckets(rate, burst int) *clientBuckets {
+	if burst <= 0 {
+		burst = rate
+	}
+	return &clientBuckets{
+		rate:    float64(rate),
+		burst:   float64(burst),
+		buckets: make(map[string]*clientBucket),
+		swept:   time.Now(),
+	}
+}
+
+// take spends one of client's tokens. It returns zero if the request may go
+// ahead, or how long until a token is available. A zero rate never limits.
+func (s *clientBuckets) take(client string, now time.Time) time.Duration {
+	if s.rate <= 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.swept) >= rateLimitSweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[client]
+	if !ok {
+		b = &clientBucket{tokens: s.burst, last: now}
+		s.buckets[client] = b
+	}
+	b.tokens = math.Min(s.burst, b.tokens+now.Sub(b.last).Seconds()*s.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / s.rate * float64(time.Second))
+}
+
+// sweep drops buckets that have refilled completely; a new bucket starts full,
+// so forgetting them changes nothing. Callers must hold s.mu.
+func (s *clientBuckets) sweep(now time.Time) {
+	s.swept = now
+	for client, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*s.rate >= s.burst {
+			delete(s.buckets, client)
+		}
+	}
+}
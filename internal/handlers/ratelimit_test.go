@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/auth"
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"go.uber.org/zap"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(config.ServerConfig{RateLimitRead: 1, RateLimitReadBurst: 3, RateLimitRender: 1}, zap.NewNop())
+	handler := l.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(method, path, remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := serve(http.MethodGet, "/apps", "10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("Expected request %d within the burst to pass, got %d", i, w.Code)
+		}
+	}
+	w := serve(http.MethodGet, "/apps", "10.0.0.1:5678")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 after the burst, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After: 1, got %q", w.Header().Get("Retry-After"))
+	}
+
+	// Budgets are separate per class and per client
+	if w := serve(http.MethodPost, "/apps/clock/render", "10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected the render budget to be separate, got %d", w.Code)
+	}
+	if w := serve(http.MethodGet, "/apps/clock/preview.webp", "10.0.0.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected previews to share the render budget, got %d", w.Code)
+	}
+	if w := serve(http.MethodGet, "/apps", "10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected another client to have its own budget, got %d", w.Code)
+	}
+	if w := serve(http.MethodGet, "/health", "10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected health checks to be exempt, got %d", w.Code)
+	}
+}
+
+func TestRateLimiter_SharedUnavailable(t *testing.T) {
+	processor := pixlet.NewProcessorWithRedis(&config.PixletConfig{AppsPath: t.TempDir()}, &config.RedisConfig{Addr: "127.0.0.1:1"}, zap.NewNop())
+	defer processor.Close()
+	defer processor.Stop()
+
+	var nilLimiter *RateLimiter
+	nilLimiter.UseShared(processor.SharedRateLimiter())
+
+	// With Redis unreachable, clients are still limited by this process
+	l := NewRateLimiter(config.ServerConfig{RateLimitRead: 1}, zap.NewNop())
+	l.UseShared(processor.SharedRateLimiter())
+	handler := l.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/apps", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("Request %d: got %d, want %d", i, w.Code, want)
+		}
+	}
+}
+
+func TestRateLimiter_ClientKey(t *testing.T) {
+	l := NewRateLimiter(config.ServerConfig{RateLimitRead: 1}, zap.NewNop())
+
+	req := httptest.NewRequest(http.MethodGet, "/apps", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.9")
+	if got := l.clientKey(req); got != "ip:10.0.0.1" {
+		t.Errorf("Expected X-Forwarded-For to be ignored by default, got %q", got)
+	}
+
+	l.trustProxy = true
+	if got := l.clientKey(req); got != "ip:203.0.113.7" {
+		t.Errorf("Expected the original client from X-Forwarded-For, got %q", got)
+	}
+
+	req = req.WithContext(auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice", Role: auth.RoleRead}))
+	if got := l.clientKey(req); got != "sub:alice" {
+		t.Errorf("Expected authenticated clients to be keyed by subject, got %q", got)
+	}
+}
+
+func TestClientBuckets(t *testing.T) {
+	b := newClientBuckets(2, 2)
+	now := time.Now()
+
+	b.take("a", now)
+	b.take("a", now)
+	if wait := b.take("a", now); wait != 500*time.Millisecond {
+		t.Errorf("Expected a 500ms wait at 2/s, got %s", wait)
+	}
+	if wait := b.take("a", now.Add(500*time.Millisecond)); wait != 0 {
+		t.Errorf("Expected a token after refilling, got wait %s", wait)
+	}
+
+	b.take("b", now)
+	b.sweep(now.Add(2 * time.Second))
+	if len(b.buckets) != 0 {
+		t.Errorf("Expected refilled buckets to be swept, %d left", len(b.buckets))
+	}
+
+	if NewRateLimiter(config.ServerConfig{}, zap.NewNop()) != nil {
+		t.Error("Expected no limiter without budgets")
+	}
+}
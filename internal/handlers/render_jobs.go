@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/auth"
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+const (
+	renderJobRetention     = 10 * time.Minute // How long finished jobs can be polled
+	renderJobSweepInterval = time.Minute      // How often expired jobs are dropped
+	renderJobIDSize        = 16               // Random bytes in a job ID
+	renderJobStoreTimeout  = 2 * time.Second  // Bounds sharing a finished job through Redis
+)
+
+// Render job statuses
+const (
+	RenderJobPending   = "pending" // queued or rendering
+	RenderJobSucceeded = "succeeded"
+	RenderJobFailed    = "failed"
+	RenderJobCancelled = "cancelled"
+)
+
+// RenderJob is the state of an asynchronous render, as returned by
+// GET /render-jobs/{id}
+type RenderJob struct {
+	ID               string                 `json:"id"`
+	Status           string                 `json:"status"`
+	AppID            string                 `json:"app_id"`
+	DeviceID         string                 `json:"device_id"`
+	CreatedAt        time.Time              `json:"created_at"`
+	FinishedAt       *time.Time             `json:"finished_at,omitempty"`
+	Result           *models.RenderResult   `json:"result,omitempty"` // set once succeeded
+	Error            *ErrorResponse         `json:"error,omitempty"`  // set once failed
+	NormalizedConfig map[string]interface{} `json:"normalized_config"`
+}
+
+// renderJobs holds asynchronous render jobs until they expire. With Redis the
+// jobs are shared through it, so any replica can report or delete them; the
+// accepting replica still holds what it needs to cancel the render.
+type renderJobs struct {
+	mu     sync.Mutex
+	jobs   map[string]*renderJobEntry
+	swept  time.Time
+	shared *pixlet.JobStore // nil keeps jobs in this replica only
+	logger *zap.Logger
+}
+
+type renderJobEntry struct {
+	job    RenderJob
+	owner  string // Token subject of the creator; empty without authentication
+	cancel context.CancelFunc
+}
+
+// sharedRenderJob is a job as stored in Redis
+type sharedRenderJob struct {
+	Job   RenderJob `json:"job"`
+	Owner string    `json:"owner"`
+}
+
+func newRenderJobs(shared *pixlet.JobStore, logger *zap.Logger) *renderJobs {
+	return &renderJobs{jobs: make(map[string]*renderJobEntry), swept: time.Now(), shared: shared, logger: logger}
+}
+
+// create registers a pending job for request
+func (s *renderJobs) create(ctx context.Context, request *models.RenderRequest, normalizedConfig map[string]interface{}, owner string, cancel context.CancelFunc, now time.Time) (RenderJob, error) {
+	var b [renderJobIDSize]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return RenderJob{}, err
+	}
+
+	entry := &renderJobEntry{
+		job: RenderJob{
+			ID:               hex.EncodeToString(b[:]),
+			Status:           RenderJobPending,
+			AppID:            request.AppID,
+			DeviceID:         request.Device.ID,
+			CreatedAt:        now,
+			NormalizedConfig: normalizedConfig,
+		},
+		owner:  owner,
+		cancel: cancel,
+	}
+
+	s.mu.Lock()
+	if now.Sub(s.swept) >= renderJobSweepInterval {
+		s.sweep(now)
+	}
+	s.jobs[entry.job.ID] = entry
+	s.mu.Unlock()
+
+	if s.shared != nil {
+		if data, err := json.Marshal(sharedRenderJob{Job: entry.job, Owner: owner}); err == nil {
+			if err := s.shared.Put(ctx, entry.job.ID, data, renderJobRetention); err != nil {
+				s.logger.Warn("Failed to share render job; only this replica can report it",
+					zap.String("job_id", entry.job.ID), zap.Error(err))
+			}
+		}
+	}
+	return entry.job, nil
+}
+
+// finish records the outcome of a job's render. Jobs deleted in the meantime,
+// here or on another replica, are ignored.
+func (s *renderJobs) finish(id string, result *models.RenderResult, err error, requestID string, now time.Time) {
+	s.mu.Lock()
+	entry, ok := s.jobs[id]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+
+	job := &entry.job
+	job.FinishedAt = &now
+	switch {
+	case err == nil:
+		job.Status = RenderJobSucceeded
+		job.Result = result
+	case errors.Is(err, context.Canceled):
+		job.Status = RenderJobCancelled
+	default:
+		status, message := renderError(err, "Failed to render app")
+		job.Status = RenderJobFailed
+		job.Error = &ErrorResponse{Code: errorCode(status), Message: message, RequestID: requestID}
+	}
+	shared := sharedRenderJob{Job: *job, Owner: entry.owner}
+	s.mu.Unlock()
+
+	if s.shared == nil {
+		return
+	}
+	data, err := json.Marshal(shared)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), renderJobStoreTimeout)
+	defer cancel()
+	if _, err := s.shared.Update(ctx, id, data, renderJobRetention); err != nil {
+		s.logger.Warn("Failed to share render job result", zap.String("job_id", id), zap.Error(err))
+	}
+}
+
+// get returns owner's job id, from Redis when jobs are shared and it answers
+func (s *renderJobs) get(ctx context.Context, id, owner string) (RenderJob, bool) {
+	if s.shared != nil {
+		if shared, ok, err := s.getShared(ctx, id); err == nil {
+			if !ok || shared.Owner != owner {
+				return RenderJob{}, false
+			}
+			return shared.Job, true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.jobs[id]
+	if !ok || entry.owner != owner {
+		return RenderJob{}, false
+	}
+	return entry.job, true
+}
+
+// getShared reads job id from Redis
+func (s *renderJobs) getShared(ctx context.Context, id string) (sharedRenderJob, bool, error) {
+	data, ok, err := s.shared.Get(ctx, id)
+	if err != nil {
+		s.logger.Warn("Failed to read shared render job", zap.String("job_id", id), zap.Error(err))
+		return sharedRenderJob{}, false, err
+	}
+	if !ok {
+		return sharedRenderJob{}, false, nil
+	}
+	var shared sharedRenderJob
+	if err := json.Unmarshal(data, &shared); err != nil {
+		return sharedRenderJob{}, false, err
+	}
+	return shared, true, nil
+}
+
+// remove cancels owner's job id if it is still pending and forgets it. A job
+// accepted by another replica is only forgotten: its render runs on, and its
+// result is dropped.
+func (s *renderJobs) remove(ctx context.Context, id, owner string) bool {
+	s.mu.Lock()
+	entry, local := s.jobs[id]
+	if local {
+		if entry.owner != owner {
+			s.mu.Unlock()
+			return false
+		}
+		entry.cancel()
+		delete(s.jobs, id)
+	}
+	s.mu.Unlock()
+
+	if s.shared == nil {
+		return local
+	}
+	if !local {
+		shared, ok, err := s.getShared(ctx, id)
+		if err != nil || !ok || shared.Owner != owner {
+			return false
+		}
+	}
+	if err := s.shared.Delete(ctx, id); err != nil {
+		s.logger.Warn("Failed to delete shared render job", zap.String("job_id", id), zap.Error(err))
+	}
+	return true
+}
+
+// sweep drops jobs that finished longer than renderJobRetention ago. Callers
+// must hold s.mu.
+func (s *renderJobs) sweep(now time.Time) {
+	s.swept = now
+	for id, entry := range s.jobs {
+		if entry.job.FinishedAt != nil && now.Sub(*entry.job.FinishedAt) >= renderJobRetention {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// jobOwner identifies who may see a request's jobs
+func jobOwner(r *http.Request) string {
+	if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return p.Subject
+	}
+	return ""
+}
+
+// parseAsync reads the async query parameter of POST /apps/{id}/render
+func parseAsync(r *http.Request) (bool, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get("async"))
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid async: must be true or false")
+	}
+	return value, nil
+}
+
+// startRenderJob queues request as an asynchronous job and answers 202 with
+// the job, which the client polls at its Location
+func (h *AppHandler) startRenderJob(w http.ResponseWriter, r *http.Request, request *models.RenderRequest, normalizedConfig map[string]interface{}) {
+	// The render outlives the request, so it gets its own context
+	requestID := pixlet.JobIDFromContext(r.Context())
+	ctx, cancel := context.WithCancel(pixlet.WithJobID(context.Background(), requestID))
+
+	job, err := h.jobs.create(r.Context(), request, normalizedConfig, jobOwner(r), cancel, time.Now())
+	if err != nil {
+		cancel()
+		h.logger.Error("Failed to create render job", zap.Error(err))
+		writeError(w, r, http.StatusInternalServerError, "Failed to create render job")
+		return
+	}
+
+	go func() {
+		defer cancel()
+		result, err := h.processor.RenderApp(ctx, request)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			h.logger.Error("Render job failed",
+				zap.String("job_id", job.ID),
+				zap.String("app_id", request.AppID),
+				zap.String("device_id", request.Device.ID),
+				zap.Error(err))
+		}
+		h.jobs.finish(job.ID, result, err, requestID, time.Now())
+	}()
+
+	h.logger.Info("Queued render job",
+		zap.String("job_id", job.ID),
+		zap.String("app_id", request.AppID),
+		zap.String("device_id", request.Device.ID))
+
+	w.Header().Set("Location", apiPrefix+"/render-jobs/"+job.ID)
+	h.writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleRenderJob handles GET /render-jobs/{id} - returns a job's status and,
+// once finished, its result or error - and DELETE /render-jobs/{id} - cancels
+// the job if it is still pending and discards it
+func (h *AppHandler) handleRenderJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/render-jobs/")
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, r, http.StatusNotFound, "Render job not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		job, ok := h.jobs.get(r.Context(), id, jobOwner(r))
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "Render job not found")
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		h.writeJSON(w, http.StatusOK, job)
+	case http.MethodDelete:
+		if !h.jobs.remove(r.Context(), id, jobOwner(r)) {
+			writeError(w, r, http.StatusNotFound, "Render job not found")
+			return
+		}
+		h.logger.Info("Deleted render job", zap.String("job_id", id))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
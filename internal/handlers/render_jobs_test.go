@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/auth"
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestRenderJobs_Lifecycle(t *testing.T) {
+	jobs := newRenderJobs(nil, zap.NewNop())
+	now := time.Now()
+	request := &models.RenderRequest{AppID: "clock", Device: models.Device{ID: "panel"}}
+
+	cancelled := false
+	job, err := jobs.create(context.Background(), request, nil, "alice", func() { cancelled = true }, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != RenderJobPending || job.AppID != "clock" || job.DeviceID != "panel" || len(job.ID) != 2*renderJobIDSize {
+		t.Fatalf("Unexpected new job: %+v", job)
+	}
+
+	if _, ok := jobs.get(context.Background(), job.ID, "bob"); ok {
+		t.Error("Expected another subject not to see the job")
+	}
+
+	jobs.finish(job.ID, &models.RenderResult{UUID: "u"}, nil, "req", now)
+	got, ok := jobs.get(context.Background(), job.ID, "alice")
+	if !ok || got.Status != RenderJobSucceeded || got.Result.UUID != "u" || got.FinishedAt == nil {
+		t.Fatalf("Expected a succeeded job, got %+v", got)
+	}
+
+	if jobs.remove(context.Background(), job.ID, "bob") {
+		t.Error("Expected another subject not to delete the job")
+	}
+	if !jobs.remove(context.Background(), job.ID, "alice") || !cancelled {
+		t.Error("Expected the owner to delete and cancel the job")
+	}
+	if _, ok := jobs.get(context.Background(), job.ID, "alice"); ok {
+		t.Error("Expected the deleted job to be gone")
+	}
+	// Finishing a deleted job is a no-op
+	jobs.finish(job.ID, nil, nil, "req", now)
+}
+
+func TestRenderJobs_Failures(t *testing.T) {
+	jobs := newRenderJobs(nil, zap.NewNop())
+	now := time.Now()
+	request := &models.RenderRequest{AppID: "clock"}
+
+	tests := []struct {
+		name   string
+		err    error
+		status string
+		code   string
+	}{
+		{"queue full", pixlet.ErrQueueFull, RenderJobFailed, "rate_limited"},
+		{"render error", errors.New("boom"), RenderJobFailed, "internal_error"},
+		{"cancelled", context.Canceled, RenderJobCancelled, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, _ := jobs.create(context.Background(), request, nil, "", func() {}, now)
+			jobs.finish(job.ID, nil, tt.err, "req-1", now)
+			got, _ := jobs.get(context.Background(), job.ID, "")
+			if got.Status != tt.status {
+				t.Errorf("Expected %s, got %s", tt.status, got.Status)
+			}
+			if tt.code == "" && got.Error != nil {
+				t.Errorf("Expected no error, got %+v", got.Error)
+			}
+			if tt.code != "" && (got.Error == nil || got.Error.Code != tt.code || got.Error.RequestID != "req-1") {
+				t.Errorf("Expected error code %s, got %+v", tt.code, got.Error)
+			}
+		})
+	}
+}
+
+func TestRenderJobs_Sweep(t *testing.T) {
+	jobs := newRenderJobs(nil, zap.NewNop())
+	now := time.Now()
+	request := &models.RenderRequest{AppID: "clock"}
+
+	finished, _ := jobs.create(context.Background(), request, nil, "", func() {}, now)
+	jobs.finish(finished.ID, nil, nil, "", now)
+	pending, _ := jobs.create(context.Background(), request, nil, "", func() {}, now)
+
+	later := now.Add(renderJobRetention + renderJobSweepInterval)
+	jobs.create(context.Background(), request, nil, "", func() {}, later)
+
+	if _, ok := jobs.get(context.Background(), finished.ID, ""); ok {
+		t.Error("Expected the expired job to be swept")
+	}
+	if _, ok := jobs.get(context.Background(), pending.ID, ""); !ok {
+		t.Error("Expected the pending job to be kept")
+	}
+}
+
+func TestRenderJobs_SharedUnavailable(t *testing.T) {
+	processor := pixlet.NewProcessorWithRedis(&config.PixletConfig{AppsPath: t.TempDir()}, &config.RedisConfig{Addr: "127.0.0.1:1"}, zap.NewNop())
+	defer processor.Close()
+	defer processor.Stop()
+	if processor.JobStore() == nil {
+		t.Fatal("Expected a job store with Redis")
+	}
+
+	// With Redis down, jobs fall back to this replica's
+	jobs := newRenderJobs(processor.JobStore(), zap.NewNop())
+	ctx := context.Background()
+	now := time.Now()
+	job, err := jobs.create(ctx, &models.RenderRequest{AppID: "clock"}, nil, "alice", func() {}, now)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	jobs.finish(job.ID, &models.RenderResult{UUID: "u"}, nil, "req", now)
+	if got, ok := jobs.get(ctx, job.ID, "alice"); !ok || got.Status != RenderJobSucceeded {
+		t.Errorf("Expected the local job, got %+v, %v", got, ok)
+	}
+	if jobs.remove(ctx, job.ID, "bob") {
+		t.Error("Another owner should not delete the job")
+	}
+	if !jobs.remove(ctx, job.ID, "alice") {
+		t.Error("Expected the owner to delete the job")
+	}
+	if jobs.remove(ctx, "unknown", "alice") {
+		t.Error("Expected an unknown job not to be found")
+	}
+}
+
+func TestAsyncRender(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/apps/test-app/render?async=true", strings.NewReader(`{"user_id": "alice"}`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var job RenderJob
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != RenderJobPending || job.NormalizedConfig["user_id"] != "alice" {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+	location := w.Header().Get("Location")
+	if location != "/v1/render-jobs/"+job.ID {
+		t.Fatalf("Expected the job location, got %q", location)
+	}
+
+	// Poll until the render finishes; without a WebP encoder it may fail, but it
+	// must not stay pending
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status == RenderJobPending {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the job to finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 polling the job, got %d", w.Code)
+		}
+		json.Unmarshal(w.Body.Bytes(), &job)
+	}
+	if job.Status == RenderJobSucceeded && job.Result == nil {
+		t.Error("Expected a succeeded job to carry its result")
+	}
+	if job.Status == RenderJobFailed && job.Error == nil {
+		t.Error("Expected a failed job to carry its error")
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, location, nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting the job, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted job, got %d", w.Code)
+	}
+}
+
+func TestAsyncRender_BadRequests(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, query := range []string{"async=maybe", "async=true&raw=true", "async=true&sizes=64x32"} {
+		req := httptest.NewRequest(http.MethodPost, "/apps/test-app/render?"+query, strings.NewReader(`{}`))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, w.Code)
+		}
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, "/render-jobs/unknown", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s of an unknown job, got %d", method, w.Code)
+		}
+	}
+}
+
+func TestJobOwner(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/render-jobs/x", nil)
+	if got := jobOwner(req); got != "" {
+		t.Errorf("Expected no owner without a principal, got %q", got)
+	}
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Subject: "alice", Role: auth.RoleRead}))
+	if got := jobOwner(req); got != "alice" {
+		t.Errorf("Expected alice, got %q", got)
+	}
+}
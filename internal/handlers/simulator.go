@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+//go:embed simulator.html
+var simulatorHTML string
+
+// simulatorPage renders an app's WebP preview onto a simulated LED matrix
+var simulatorPage = template.Must(template.New("simulator").Parse(simulatorHTML))
+
+// simulatorData fills in simulatorPage
+type simulatorData struct {
+	Name    string
+	Summary string
+	Width   int
+	Height  int
+	Nonce   string // Allows the page's inline script and style under its CSP
+}
+
+// handleAppSimulator handles GET /apps/{id}/simulator - an HTML page that posts
+// the config and size chosen on it to preview.webp and paints the result as a
+// grid of LEDs, for app authors without hardware
+func (h *AppHandler) handleAppSimulator(w http.ResponseWriter, r *http.Request, app *models.AppManifest) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	nonce, err := cspNonce()
+	if err != nil {
+		h.logger.Error("Failed to create simulator nonce", zap.Error(err))
+		writeError(w, r, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	size := h.processor.DefaultDeviceSize()
+	data := simulatorData{
+		Name:    app.Name,
+		Summary: app.Summary,
+		Width:   size.Width,
+		Height:  size.Height,
+		Nonce:   nonce,
+	}
+	if data.Name == "" {
+		data.Name = app.ID
+	}
+
+	var page bytes.Buffer
+	if err := simulatorPage.Execute(&page, data); err != nil {
+		h.logger.Error("Failed to render simulator page", zap.String("app_id", app.ID), zap.Error(err))
+		writeError(w, r, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src 'self' blob:; connect-src 'self'; "+
+		"script-src 'nonce-"+data.Nonce+"'; style-src 'nonce-"+data.Nonce+"'; base-uri 'none'; form-action 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(page.Bytes())
+}
+
+// cspNonce returns a random nonce allowing a page's inline script and style
+// under its Content-Security-Policy. It is URL-safe base64, which templates
+// write into attributes unescaped.
+func cspNonce() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAppSimulator(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/apps/test-app/simulator", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "<h1>test-app</h1>") {
+		t.Error("Expected the app name in the page")
+	}
+	if !strings.Contains(body, `value="64"`) || !strings.Contains(body, `value="32"`) {
+		t.Error("Expected the default device size in the page")
+	}
+
+	// The inline script runs under the nonce in the CSP
+	csp := w.Header().Get("Content-Security-Policy")
+	match := regexp.MustCompile(`script-src 'nonce-([^']+)'`).FindStringSubmatch(csp)
+	if match == nil {
+		t.Fatalf("Expected a script nonce in the CSP, got %q", csp)
+	}
+	if !strings.Contains(body, `<script nonce="`+match[1]+`">`) {
+		t.Error("Expected the page script to carry the CSP nonce")
+	}
+
+	w = httptest.NewRecorder()
+	h.handleAppDetails(w, httptest.NewRequest(http.MethodGet, "/apps/test-app/simulator", nil))
+	if strings.Contains(w.Header().Get("Content-Security-Policy"), match[1]) {
+		t.Error("Expected a fresh nonce per request")
+	}
+}
+
+func TestAppSimulator_WrongMethod(t *testing.T) {
+	h := setupTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/apps/test-app/simulator", nil)
+	w := httptest.NewRecorder()
+	h.handleAppDetails(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// certReloadInterval is how often certificate files are checked for renewals
+const certReloadInterval = 30 * time.Second
+
+// TLS serves the API over HTTPS, with a certificate from files or from Let's
+// Encrypt, and optionally verifies client certificates
+type TLS struct {
+	config            *tls.Config
+	requireClientCert bool
+}
+
+// NewTLS returns TLS for cfg, or nil if no certificate or autocert domains are
+// configured and the API is served over plain HTTP
+func NewTLS(cfg config.ServerConfig, logger *zap.Logger) (*TLS, error) {
+	domains := splitList(cfg.TLSAutocertDomains)
+	usesFiles := cfg.TLSCertFile != "" || cfg.TLSKeyFile != ""
+	switch {
+	case !usesFiles && len(domains) == 0:
+		if cfg.TLSClientCAFile != "" {
+			return nil, errors.New("SERVER_TLS_CLIENT_CA_FILE needs SERVER_TLS_CERT_FILE or SERVER_TLS_AUTOCERT_DOMAINS")
+		}
+		return nil, nil
+	case usesFiles && len(domains) > 0:
+		return nil, errors.New("set SERVER_TLS_CERT_FILE or SERVER_TLS_AUTOCERT_DOMAINS, not both")
+	}
+
+	t := &TLS{config: &tls.Config{MinVersion: tls.VersionTLS12}}
+	if len(domains) > 0 {
+		if cfg.TLSAutocertCacheDir == "" {
+			return nil, errors.New("SERVER_TLS_AUTOCERT_CACHE_DIR is required with SERVER_TLS_AUTOCERT_DOMAINS")
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Cache:      autocert.DirCache(cfg.TLSAutocertCacheDir),
+			Email:      cfg.TLSAutocertEmail,
+		}
+		// Challenges are answered in the TLS handshake (TLS-ALPN-01), so no
+		// plain HTTP listener is needed
+		t.config.GetCertificate = manager.GetCertificate
+		t.config.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+	} else {
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			return nil, errors.New("SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set together")
+		}
+		certs, err := newCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		t.config.GetCertificate = certs.GetCertificate
+	}
+
+	if cfg.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates in %s", cfg.TLSClientCAFile)
+		}
+		// Certificates are always verified in the handshake; requiring one is
+		// left to Wrap so health probes can connect without
+		t.config.ClientCAs = pool
+		t.config.ClientAuth = tls.VerifyClientCertIfGiven
+		switch strings.ToLower(cfg.TLSClientAuth) {
+		case "", "require":
+			t.requireClientCert = true
+		case "optional":
+		default:
+			return nil, fmt.Errorf("invalid SERVER_TLS_CLIENT_AUTH %q: want require or optional", cfg.TLSClientAuth)
+		}
+	}
+	return t, nil
+}
+
+// Config returns the server's TLS configuration, or nil for a nil TLS
+func (t *TLS) Config() *tls.Config {
+	if t == nil {
+		return nil
+	}
+	return t.config
+}
+
+// Wrap returns next rejecting API requests without a verified client
+// certificate when one is required. Health probes stay open, since kubelets and
+// load balancers can't present one. A nil TLS returns next unchanged.
+func (t *TLS) Wrap(next http.Handler) http.Handler {
+	if t == nil || !t.requireClientCert {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			switch apiPath(r.URL.Path) {
+			case "/health", "/livez", "/readyz":
+			default:
+				writeError(w, r, http.StatusUnauthorized, "Client certificate required")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GRPCServerOptions returns the options serving gRPC over the same certificates.
+// gRPC has no probes to exempt, so a required client certificate is enforced in
+// the handshake. A nil TLS returns no options.
+func (t *TLS) GRPCServerOptions() []grpc.ServerOption {
+	if t == nil {
+		return nil
+	}
+
+	cfg := t.config.Clone()
+	if t.requireClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(cfg))}
+}
+
+// certReloader serves a certificate from files, picking up renewals written in
+// place, e.g. by cert-manager, without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time // Latest modification time of the files when cert was loaded
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile string, logger *zap.Logger) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger, checked: time.Now()}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load reads the key pair. Callers hold c.mu, or own c.
+func (c *certReloader) load() error {
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	c.cert, c.modTime = &cert, modTime
+	return nil
+}
+
+func (c *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. A renewal that fails to
+// load, e.g. because it is half written, is retried later while the previous
+// certificate is kept.
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now := time.Now(); now.Sub(c.checked) >= certReloadInterval {
+		c.checked = now
+		if modTime, err := c.filesModTime(); err == nil && !modTime.Equal(c.modTime) {
+			if err := c.load(); err != nil {
+				c.logger.Warn("Failed to reload TLS certificate", zap.Error(err))
+			} else {
+				c.logger.Info("Reloaded TLS certificate", zap.String("cert_file", c.certFile))
+			}
+		}
+	}
+	return c.cert, nil
+}
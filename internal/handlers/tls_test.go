@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+)
+
+// testPKI is a CA with a server and a client certificate issued by it
+type testPKI struct {
+	dir        string
+	caFile     string
+	certFile   string
+	keyFile    string
+	roots      *x509.CertPool
+	clientCert tls.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	p := &testPKI{dir: t.TempDir(), roots: x509.NewCertPool()}
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	p.roots.AddCert(ca)
+	p.caFile = p.writePEM(t, "ca.pem", "CERTIFICATE", caDER)
+
+	p.certFile, p.keyFile = p.issue(t, ca, caKey, "server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := p.issue(t, ca, caKey, "client", x509.ExtKeyUsageClientAuth)
+	if p.clientCert, err = tls.LoadX509KeyPair(clientCert, clientKey); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// issue writes a certificate for name signed by ca, and its key
+func (p *testPKI) issue(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, name string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return p.writePEM(t, name+".pem", "CERTIFICATE", der), p.writePEM(t, name+"-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func (p *testPKI) writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(p.dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewTLS_Disabled(t *testing.T) {
+	serverTLS, err := NewTLS(config.ServerConfig{TLSClientAuth: "require"}, zap.NewNop())
+	if err != nil || serverTLS != nil {
+		t.Fatalf("Expected no TLS, got %v, %v", serverTLS, err)
+	}
+	if serverTLS.Config() != nil || serverTLS.GRPCServerOptions() != nil {
+		t.Error("A nil TLS should have no config or gRPC options")
+	}
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	if serverTLS.Wrap(next) == nil {
+		t.Error("A nil TLS should return next")
+	}
+}
+
+func TestNewTLS_Invalid(t *testing.T) {
+	pki := newTestPKI(t)
+	tests := []struct {
+		name string
+		cfg  config.ServerConfig
+	}{
+		{"cert without key", config.ServerConfig{TLSCertFile: pki.certFile}},
+		{"missing cert file", config.ServerConfig{TLSCertFile: filepath.Join(pki.dir, "nope.pem"), TLSKeyFile: pki.keyFile}},
+		{"cert and autocert", config.ServerConfig{TLSCertFile: pki.certFile, TLSKeyFile: pki.keyFile, TLSAutocertDomains: "example.com", TLSAutocertCacheDir: pki.dir}},
+		{"autocert without cache", config.ServerConfig{TLSAutocertDomains: "example.com"}},
+		{"client CA without TLS", config.ServerConfig{TLSClientCAFile: pki.caFile}},
+		{"client CA not PEM", config.ServerConfig{TLSCertFile: pki.certFile, TLSKeyFile: pki.keyFile, TLSClientCAFile: pki.keyFile}},
+		{"invalid client auth", config.ServerConfig{TLSCertFile: pki.certFile, TLSKeyFile: pki.keyFile, TLSClientCAFile: pki.caFile, TLSClientAuth: "sometimes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTLS(tt.cfg, zap.NewNop()); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestNewTLS_Autocert(t *testing.T) {
+	serverTLS, err := NewTLS(config.ServerConfig{TLSAutocertDomains: "render.example.com", TLSAutocertCacheDir: t.TempDir()}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewTLS failed: %v", err)
+	}
+	if cfg := serverTLS.Config(); cfg.GetCertificate == nil || len(cfg.NextProtos) == 0 {
+		t.Errorf("Expected autocert to serve certificates, got %+v", cfg)
+	}
+}
+
+func TestTLS_ClientCertificates(t *testing.T) {
+	pki := newTestPKI(t)
+
+	for _, mode := range []string{"require", "optional"} {
+		t.Run(mode, func(t *testing.T) {
+			serverTLS, err := NewTLS(config.ServerConfig{
+				TLSCertFile:     pki.certFile,
+				TLSKeyFile:      pki.keyFile,
+				TLSClientCAFile: pki.caFile,
+				TLSClientAuth:   mode,
+			}, zap.NewNop())
+			if err != nil {
+				t.Fatalf("NewTLS failed: %v", err)
+			}
+			srv := httptest.NewUnstartedServer(serverTLS.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})))
+			// Not StartTLS, which would serve httptest's own certificate
+			srv.Listener = tls.NewListener(srv.Listener, serverTLS.Config())
+			srv.Start()
+			defer srv.Close()
+			url := "https://" + srv.Listener.Addr().String()
+
+			get := func(path string, certs ...tls.Certificate) int {
+				t.Helper()
+				client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pki.roots, Certificates: certs}}}
+				resp, err := client.Get(url + path)
+				if err != nil {
+					t.Fatalf("GET %s failed: %v", path, err)
+				}
+				resp.Body.Close()
+				return resp.StatusCode
+			}
+
+			wantAnonymous := http.StatusOK
+			if mode == "require" {
+				wantAnonymous = http.StatusUnauthorized
+			}
+			if code := get("/v1/apps"); code != wantAnonymous {
+				t.Errorf("Without a client certificate got %d, want %d", code, wantAnonymous)
+			}
+			if code := get("/v1/apps", pki.clientCert); code != http.StatusOK {
+				t.Errorf("With a client certificate got %d, want 200", code)
+			}
+			if code := get("/livez"); code != http.StatusOK {
+				t.Errorf("Probes should not need a client certificate, got %d", code)
+			}
+		})
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	pki := newTestPKI(t)
+	certs, err := newCertReloader(pki.certFile, pki.keyFile, zap.NewNop())
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+	first, _ := certs.GetCertificate(nil)
+
+	// Renew in place with the client pair, which is as good a certificate as any
+	renewedCert, _ := os.ReadFile(filepath.Join(pki.dir, "client.pem"))
+	renewedKey, _ := os.ReadFile(filepath.Join(pki.dir, "client-key.pem"))
+	os.WriteFile(pki.certFile, renewedCert, 0600)
+	os.WriteFile(pki.keyFile, renewedKey, 0600)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(pki.certFile, later, later)
+	os.Chtimes(pki.keyFile, later, later)
+
+	if cert, _ := certs.GetCertificate(nil); cert != first {
+		t.Error("Certificate files should not be checked again before the interval")
+	}
+	certs.checked = time.Time{}
+	if cert, _ := certs.GetCertificate(nil); cert == first {
+		t.Error("Expected the renewed certificate")
+	}
+
+	// A broken renewal keeps the current certificate
+	current, _ := certs.GetCertificate(nil)
+	os.WriteFile(pki.keyFile, []byte("half written"), 0600)
+	latest := later.Add(time.Minute)
+	os.Chtimes(pki.keyFile, latest, latest)
+	certs.checked = time.Time{}
+	if cert, _ := certs.GetCertificate(nil); cert != current {
+		t.Error("Expected the current certificate after a failed reload")
+	}
+}
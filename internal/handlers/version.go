@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APIVersion is the current version of the HTTP API. Routes are served under
+// /v1, with the original unversioned paths kept as aliases for deployed devices.
+const APIVersion = "v1"
+
+// apiPrefix is the path prefix of versioned routes
+const apiPrefix = "/" + APIVersion
+
+// withAPIVersion stamps every response with the API version that served it
+func withAPIVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-Version", APIVersion)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiPath returns a request path without its version prefix, so middleware can
+// treat /v1/apps and the legacy /apps alike
+func apiPath(path string) string {
+	if rest := strings.TrimPrefix(path, apiPrefix); rest != path && (rest == "" || rest[0] == '/') {
+		if rest == "" {
+			return "/"
+		}
+		return rest
+	}
+	return path
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, path := range []string{"/v1/apps", "/apps", "/v1/apps/test-app", "/apps/test-app", "/v1/health", "/health"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, w.Code)
+		}
+		if got := w.Header().Get("X-API-Version"); got != APIVersion {
+			t.Errorf("GET %s: expected X-API-Version %s, got %q", path, APIVersion, got)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var health map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if health["api_version"] != APIVersion {
+		t.Errorf("Expected api_version in health, got %v", health["api_version"])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v2/apps", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown version, got %d", w.Code)
+	}
+}
+
+func TestAPIPath(t *testing.T) {
+	tests := map[string]string{
+		"/v1/apps":         "/apps",
+		"/v1/apps/refresh": "/apps/refresh",
+		"/v1":              "/",
+		"/apps":            "/apps",
+		"/v10/apps":        "/v10/apps",
+		"/v1apps":          "/v1apps",
+	}
+	for path, want := range tests {
+		if got := apiPath(path); got != want {
+			t.Errorf("apiPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/koios/matrx-renderer/internal/pixlet"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+const (
+	wsMaxDevices     = 64               // Devices one connection may follow
+	wsResultBuffer   = 16               // Results queued per connection before the oldest are dropped
+	wsMaxMessageSize = 4096             // Largest client message accepted
+	wsWriteWait      = 10 * time.Second // Time allowed to write a message
+	wsPongWait       = 60 * time.Second // Time allowed between pongs before the client is considered gone
+	wsPingInterval   = 30 * time.Second // Must be below wsPongWait
+)
+
+// wsMessage is a JSON text message on /ws. Clients send "subscribe" and
+// "unsubscribe" with device_ids; the server answers with "subscribed", listing
+// every device followed, or "error".
+type wsMessage struct {
+	Type      string   `json:"type"`
+	DeviceIDs []string `json:"device_ids,omitempty"`
+	Message   string   `json:"message,omitempty"`
+}
+
+// wsResult announces a render result. When size is non-zero the next message is
+// a binary one holding the WebP.
+type wsResult struct {
+	Type        string    `json:"type"`
+	UUID        string    `json:"uuid"`
+	DeviceID    string    `json:"device_id"`
+	AppID       string    `json:"app_id"`
+	Error       bool      `json:"error"`
+	Skipped     bool      `json:"skipped"`
+	ProcessedAt time.Time `json:"processed_at"`
+	Size        int       `json:"size"`
+}
+
+// SetCORS lets browser apps on the CORS allowed origins open /ws; otherwise only
+// same-origin pages may
+func (h *AppHandler) SetCORS(cors *CORS) {
+	h.cors = cors
+}
+
+// handleWebSocket handles GET /ws - streams render results for the devices named
+// in device_id, and any the client subscribes to later, as they are produced
+func (h *AppHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	deviceIDs := splitList(strings.Join(r.URL.Query()["device_id"], ","))
+	if len(deviceIDs) > wsMaxDevices {
+		writeError(w, r, http.StatusBadRequest, "Too many devices")
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin: h.allowsWebSocketOrigin,
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			writeError(w, r, status, reason.Error())
+		},
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded
+		h.logger.Debug("WebSocket upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	sub := h.processor.Results().Subscribe(deviceIDs, wsResultBuffer)
+	defer sub.Close()
+
+	h.logger.Info("WebSocket client connected",
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.Strings("device_ids", deviceIDs))
+
+	// Replies to client messages go through the writer below, the only goroutine
+	// allowed to write to conn
+	replies := make(chan wsMessage, 4)
+	stop := make(chan struct{})
+	defer close(stop)
+	reply := func(msg wsMessage) {
+		select {
+		case replies <- msg:
+		case <-stop:
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.readWebSocket(conn, sub, reply)
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	err = h.writeWebSocketJSON(conn, wsMessage{Type: "subscribed", DeviceIDs: sub.DeviceIDs()})
+	for err == nil {
+		select {
+		case result := <-sub.Results():
+			err = h.writeWebSocketResult(conn, result)
+		case reply := <-replies:
+			err = h.writeWebSocketJSON(conn, reply)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		case <-done:
+			h.logger.Info("WebSocket client disconnected", zap.String("remote_addr", r.RemoteAddr))
+			return
+		case <-h.closing:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(wsWriteWait))
+			return
+		}
+	}
+	h.logger.Debug("WebSocket write failed", zap.String("remote_addr", r.RemoteAddr), zap.Error(err))
+}
+
+// readWebSocket applies subscription changes from the client until the
+// connection closes or stops answering pings
+func (h *AppHandler) readWebSocket(conn *websocket.Conn, sub *pixlet.ResultSubscription, reply func(wsMessage)) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			reply(wsMessage{Type: "error", Message: "Invalid JSON message"})
+			continue
+		}
+
+		switch msg.Type {
+		case "subscribe":
+			if len(sub.DeviceIDs())+len(msg.DeviceIDs) > wsMaxDevices {
+				reply(wsMessage{Type: "error", Message: "Too many devices"})
+				continue
+			}
+			sub.Add(msg.DeviceIDs...)
+		case "unsubscribe":
+			sub.Remove(msg.DeviceIDs...)
+		default:
+			reply(wsMessage{Type: "error", Message: "Unknown message type"})
+			continue
+		}
+		reply(wsMessage{Type: "subscribed", DeviceIDs: sub.DeviceIDs()})
+	}
+}
+
+// writeWebSocketResult sends a result's announcement followed by its WebP
+func (h *AppHandler) writeWebSocketResult(conn *websocket.Conn, result *models.RenderResult) error {
+	webp, err := base64.StdEncoding.DecodeString(result.RenderOutput)
+	if err != nil {
+		h.logger.Error("Failed to decode render output for WebSocket",
+			zap.String("app_id", result.AppID),
+			zap.String("device_id", result.DeviceID),
+			zap.Error(err))
+		return nil
+	}
+
+	err = h.writeWebSocketJSON(conn, wsResult{
+		Type:        "render_result",
+		UUID:        result.UUID,
+		DeviceID:    result.DeviceID,
+		AppID:       result.AppID,
+		Error:       result.Error,
+		Skipped:     result.Skipped,
+		ProcessedAt: result.ProcessedAt,
+		Size:        len(webp),
+	})
+	if err != nil || len(webp) == 0 {
+		return err
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteMessage(websocket.BinaryMessage, webp)
+}
+
+// writeWebSocketJSON sends v as a JSON text message
+func (h *AppHandler) writeWebSocketJSON(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(v)
+}
+
+// allowsWebSocketOrigin accepts handshakes without an Origin (non-browser
+// clients), from the API's own origin, or from a CORS allowed origin
+func (h *AppHandler) allowsWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return h.cors.allowsOrigin(origin)
+}
+
+// publishRender shares a render made outside RenderApp with the device's /ws
+// subscribers
+func publishRender(feed *pixlet.ResultFeed, uuid, appID, deviceID string, webp []byte) {
+	if !feed.Watched(deviceID) {
+		return
+	}
+	feed.Publish(&models.RenderResult{
+		Type:         "render_result",
+		UUID:         uuid,
+		DeviceID:     deviceID,
+		AppID:        appID,
+		RenderOutput: base64.StdEncoding.EncodeToString(webp),
+		Skipped:      len(webp) == 0,
+		ProcessedAt:  time.Now(),
+	})
+}
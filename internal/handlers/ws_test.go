@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/pkg/models"
+)
+
+// dialTestWebSocket serves h and opens /ws with the given query and headers
+func dialTestWebSocket(t *testing.T, h *AppHandler, query string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/ws?" + query
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	}
+	return conn, resp, err
+}
+
+func readWSMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	return msg
+}
+
+func TestWebSocket_StreamsDeviceResults(t *testing.T) {
+	h := setupTestHandler(t)
+	conn, _, err := dialTestWebSocket(t, h, "device_id=panel-1,panel-2", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	if msg := readWSMessage(t, conn); msg.Type != "subscribed" || !reflect.DeepEqual(msg.DeviceIDs, []string{"panel-1", "panel-2"}) {
+		t.Fatalf("Expected subscription to both devices, got %+v", msg)
+	}
+
+	webp := []byte("RIFF....WEBP")
+	h.processor.Results().Publish(&models.RenderResult{DeviceID: "other", AppID: "test-app"})
+	h.processor.Results().Publish(&models.RenderResult{
+		UUID:         "job-1",
+		DeviceID:     "panel-2",
+		AppID:        "test-app",
+		RenderOutput: base64.StdEncoding.EncodeToString(webp),
+	})
+
+	var announced wsResult
+	if err := conn.ReadJSON(&announced); err != nil {
+		t.Fatal(err)
+	}
+	if announced.Type != "render_result" || announced.UUID != "job-1" || announced.DeviceID != "panel-2" || announced.Size != len(webp) {
+		t.Errorf("Unexpected announcement: %+v", announced)
+	}
+	kind, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != websocket.BinaryMessage || string(data) != string(webp) {
+		t.Errorf("Expected the WebP as a binary message, got type %d %q", kind, data)
+	}
+
+	// Skipped renders are announced without a binary message
+	h.processor.Results().Publish(&models.RenderResult{UUID: "job-2", DeviceID: "panel-1", Skipped: true})
+	if err := conn.ReadJSON(&announced); err != nil {
+		t.Fatal(err)
+	}
+	if !announced.Skipped || announced.Size != 0 {
+		t.Errorf("Expected a skipped announcement, got %+v", announced)
+	}
+}
+
+func TestWebSocket_Subscribe(t *testing.T) {
+	h := setupTestHandler(t)
+	conn, _, err := dialTestWebSocket(t, h, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if msg := readWSMessage(t, conn); msg.Type != "subscribed" || len(msg.DeviceIDs) != 0 {
+		t.Fatalf("Expected an empty subscription, got %+v", msg)
+	}
+
+	conn.WriteJSON(wsMessage{Type: "subscribe", DeviceIDs: []string{"b", "a"}})
+	if msg := readWSMessage(t, conn); !reflect.DeepEqual(msg.DeviceIDs, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], got %+v", msg)
+	}
+
+	conn.WriteJSON(wsMessage{Type: "unsubscribe", DeviceIDs: []string{"a"}})
+	if msg := readWSMessage(t, conn); !reflect.DeepEqual(msg.DeviceIDs, []string{"b"}) {
+		t.Errorf("Expected [b], got %+v", msg)
+	}
+
+	conn.WriteJSON(wsMessage{Type: "bogus"})
+	if msg := readWSMessage(t, conn); msg.Type != "error" {
+		t.Errorf("Expected an error for an unknown type, got %+v", msg)
+	}
+
+	conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+	if msg := readWSMessage(t, conn); msg.Type != "error" {
+		t.Errorf("Expected an error for invalid JSON, got %+v", msg)
+	}
+}
+
+func TestWebSocket_Origin(t *testing.T) {
+	h := setupTestHandler(t)
+
+	_, resp, err := dialTestWebSocket(t, h, "", http.Header{"Origin": {"https://evil.example.com"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected a foreign origin to be rejected, got %v", err)
+	}
+
+	h.SetCORS(NewCORS(config.ServerConfig{CORSAllowedOrigins: "https://dash.example.com"}))
+	if _, _, err := dialTestWebSocket(t, h, "", http.Header{"Origin": {"https://dash.example.com"}}); err != nil {
+		t.Errorf("Expected a CORS allowed origin to connect, got %v", err)
+	}
+}
+
+func TestWebSocket_RequiresUpgrade(t *testing.T) {
+	h := setupTestHandler(t)
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without an upgrade, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"code":"bad_request"`) {
+		t.Errorf("Expected a JSON error envelope, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,81 @@
+package pixlet
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// affinityQueueSize is how many jobs may wait for their app's worker before
+// further jobs spill over to the shared queue
+const affinityQueueSize = 1
+
+// affinityQueue holds jobs routed to one base worker because of their app
+type affinityQueue struct {
+	interactive chan *RenderJob
+	background  chan *RenderJob
+}
+
+func newAffinityQueues(workers int) []affinityQueue {
+	queues := make([]affinityQueue, workers)
+	for i := range queues {
+		queues[i] = affinityQueue{
+			interactive: make(chan *RenderJob, affinityQueueSize),
+			background:  make(chan *RenderJob, affinityQueueSize),
+		}
+	}
+	return queues
+}
+
+// queue returns the channel for jobs of the given priority
+func (q *affinityQueue) queue(priority Priority) chan *RenderJob {
+	if priority == PriorityBackground {
+		return q.background
+	}
+	return q.interactive
+}
+
+// affinitySlot picks the base worker for an app by rendezvous hashing: every app
+// scores each worker and goes to the highest, so an app always lands on the same
+// worker and resizing the pool only moves the apps of the workers added or removed
+func affinitySlot(appID string, workers int) int {
+	var best int
+	var bestScore uint64
+	var slot [8]byte
+	for i := 0; i < workers; i++ {
+		h := fnv.New64a()
+		h.Write([]byte(appID))
+		binary.LittleEndian.PutUint64(slot[:], uint64(i))
+		h.Write(slot[:])
+		if score := h.Sum64(); i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// ownQueue returns the affinity queue served by a worker, or nil if it has none.
+// Only base workers own one; they are started first with IDs 0 to workers-1 and
+// never retire.
+func (wp *WorkerPool) ownQueue(workerID int) *affinityQueue {
+	if workerID < len(wp.affinity) {
+		return &wp.affinity[workerID]
+	}
+	return nil
+}
+
+// pushAffinity hands a job to its app's worker if that worker has room, and
+// reports whether it did. Jobs that don't fit go to the shared queue instead.
+func (wp *WorkerPool) pushAffinity(job *RenderJob) bool {
+	if len(wp.affinity) == 0 {
+		return false
+	}
+	slot := affinitySlot(job.AppID, len(wp.affinity))
+	select {
+	case wp.affinity[slot].queue(job.Priority) <- job:
+		metricAffinityJobs.WithLabelValues("sticky").Inc()
+		return true
+	default:
+		metricAffinityJobs.WithLabelValues("spill").Inc()
+		return false
+	}
+}
@@ -0,0 +1,83 @@
+package pixlet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func TestAffinitySlot(t *testing.T) {
+	used := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		appID := fmt.Sprintf("app-%d", i)
+		slot := affinitySlot(appID, 4)
+		if slot < 0 || slot >= 4 {
+			t.Fatalf("affinitySlot(%q) = %d, out of range", appID, slot)
+		}
+		if again := affinitySlot(appID, 4); again != slot {
+			t.Fatalf("affinitySlot(%q) = %d then %d", appID, slot, again)
+		}
+		used[slot] = true
+
+		// Adding a worker only moves apps onto the new one
+		if grown := affinitySlot(appID, 5); grown != slot && grown != 4 {
+			t.Errorf("affinitySlot(%q) moved from %d to %d when a worker was added", appID, slot, grown)
+		}
+	}
+	if len(used) != 4 {
+		t.Errorf("Expected apps spread over 4 workers, got %d", len(used))
+	}
+}
+
+func TestWorkerPoolAffinity(t *testing.T) {
+	appsDir := t.TempDir()
+	writeTestApp(t, appsDir, "blank-app", "def main(config):\n    return []\n")
+	registry := models.NewAppRegistry()
+	if err := registry.LoadApps(appsDir); err != nil {
+		t.Fatalf("LoadApps: %v", err)
+	}
+
+	wp := NewWorkerPool(2, zap.NewNop(), registry, runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0,
+		ScalingConfig{}, false, QueueConfig{Affinity: true}, RenderLimits{})
+
+	// Queue jobs before starting the pool so we can see where they land
+	errs := make(chan error, 2)
+	submit := func(n int) {
+		go func() {
+			_, err := wp.SubmitRoots(context.Background(), "blank-app", nil, models.Device{Width: 64, Height: 32}, RenderOptions{})
+			errs <- err
+		}()
+		deadline := time.Now().Add(2 * time.Second)
+		for wp.queued() < n && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	own := wp.affinity[affinitySlot("blank-app", 2)]
+	submit(1)
+	if len(own.interactive) != 1 || len(wp.interactive) != 0 {
+		t.Fatalf("Expected first job on its app's worker, got %d sticky and %d shared", len(own.interactive), len(wp.interactive))
+	}
+	submit(2)
+	if len(own.interactive) != 1 || len(wp.interactive) != 1 {
+		t.Fatalf("Expected second job to spill over, got %d sticky and %d shared", len(own.interactive), len(wp.interactive))
+	}
+
+	wp.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wp.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("job failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package pixlet
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+// appletCache is an LRU cache of loaded applets, so renders skip re-reading and
+// re-parsing the .star source. Entries are keyed by app ID and remember the source
+// modification time; a changed file is treated as a miss and reloaded.
+type appletCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	entries  map[string]*list.Element
+	lookups  hitCounter
+}
+
+type appletCacheEntry struct {
+	appID   string
+	modTime time.Time
+	applet  *runtime.Applet
+}
+
+// newAppletCache creates a cache holding up to capacity applets.
+// A capacity <= 0 disables caching.
+func newAppletCache(capacity int) *appletCache {
+	return &appletCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached applet for appID if it was loaded from a source with the given mtime
+func (c *appletCache) Get(appID string, modTime time.Time) (*runtime.Applet, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[appID]
+	if !ok {
+		c.lookups.record(false)
+		return nil, false
+	}
+	entry := elem.Value.(*appletCacheEntry)
+	if !entry.modTime.Equal(modTime) {
+		c.order.Remove(elem)
+		delete(c.entries, appID)
+		c.lookups.record(false)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	c.lookups.record(true)
+	return entry.applet, true
+}
+
+// Put stores an applet, evicting the least recently used entry when full
+func (c *appletCache) Put(appID string, modTime time.Time, applet *runtime.Applet) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[appID]; ok {
+		elem.Value = &appletCacheEntry{appID: appID, modTime: modTime, applet: applet}
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[appID] = c.order.PushFront(&appletCacheEntry{appID: appID, modTime: modTime, applet: applet})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*appletCacheEntry).appID)
+	}
+}
+
+// Purge drops every cached applet
+func (c *appletCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// Len returns the number of cached applets
+func (c *appletCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,61 @@
+package pixlet
+
+import (
+	"testing"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func TestAppletCache(t *testing.T) {
+	modTime := time.Unix(1700000000, 0)
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		cache := newAppletCache(2)
+		a, b, c := &runtime.Applet{ID: "a"}, &runtime.Applet{ID: "b"}, &runtime.Applet{ID: "c"}
+		cache.Put("a", modTime, a)
+		cache.Put("b", modTime, b)
+		if _, ok := cache.Get("a", modTime); !ok {
+			t.Fatal("expected a to be cached")
+		}
+		cache.Put("c", modTime, c)
+
+		if _, ok := cache.Get("b", modTime); ok {
+			t.Error("expected b to be evicted")
+		}
+		if got, ok := cache.Get("a", modTime); !ok || got != a {
+			t.Error("expected a to survive eviction")
+		}
+		if got, ok := cache.Get("c", modTime); !ok || got != c {
+			t.Error("expected c to be cached")
+		}
+	})
+
+	t.Run("changed mtime is a miss", func(t *testing.T) {
+		cache := newAppletCache(2)
+		cache.Put("a", modTime, &runtime.Applet{ID: "a"})
+		if _, ok := cache.Get("a", modTime.Add(time.Second)); ok {
+			t.Error("expected miss for newer mtime")
+		}
+		if cache.Len() != 0 {
+			t.Errorf("expected stale entry to be dropped, got %d entries", cache.Len())
+		}
+	})
+
+	t.Run("purge empties the cache", func(t *testing.T) {
+		cache := newAppletCache(2)
+		cache.Put("a", modTime, &runtime.Applet{ID: "a"})
+		cache.Purge()
+		if _, ok := cache.Get("a", modTime); ok {
+			t.Error("expected miss after purge")
+		}
+	})
+
+	t.Run("zero capacity disables caching", func(t *testing.T) {
+		cache := newAppletCache(0)
+		cache.Put("a", modTime, &runtime.Applet{ID: "a"})
+		if cache.Len() != 0 {
+			t.Errorf("expected no entries, got %d", cache.Len())
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package pixlet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Benchmark iteration limits
+const (
+	DefaultBenchmarkIterations = 10
+	MaxBenchmarkIterations     = 100
+)
+
+// BenchmarkResult summarizes repeated renders of one app and config
+type BenchmarkResult struct {
+	AppID       string         `json:"app_id"`
+	Iterations  int            `json:"iterations"`
+	Errors      int            `json:"errors"`
+	Skipped     int            `json:"skipped"`               // renders where the app returned no screens
+	FirstError  string         `json:"first_error,omitempty"` // message of the first failed render
+	LatencyMs   LatencySummary `json:"latency_ms"`            // render plus WebP encode, all iterations
+	OutputBytes SizeSummary    `json:"output_bytes"`          // encoded WebP size, successful renders only
+}
+
+// LatencySummary describes a set of durations in milliseconds
+type LatencySummary struct {
+	First float64 `json:"first"` // includes loading the app if it wasn't cached
+	Min   float64 `json:"min"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// SizeSummary describes a set of output sizes in bytes
+type SizeSummary struct {
+	Min  int `json:"min"`
+	Mean int `json:"mean"`
+	Max  int `json:"max"`
+}
+
+// Benchmark renders an app iterations times, one after another, and reports
+// latency percentiles, WebP output sizes and error counts. Every iteration goes
+// through the worker pool and encoder; the render cache is neither read nor
+// written, so results reflect the app itself. Renders run at background priority
+// so benchmarks never hold up interactive requests.
+func (p *Processor) Benchmark(ctx context.Context, request *models.RenderRequest, iterations int) (*BenchmarkResult, error) {
+	if iterations <= 0 || iterations > MaxBenchmarkIterations {
+		return nil, fmt.Errorf("iterations must be between 1 and %d", MaxBenchmarkIterations)
+	}
+
+	opts := renderOptionsFor(request)
+	webpOpts, err := p.webpOptions(opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	device, err := p.resolveDevice(request.Device)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithPriority(ctx, PriorityBackground)
+	result := &BenchmarkResult{AppID: request.AppID, Iterations: iterations}
+	latencies := make([]time.Duration, 0, iterations)
+	var sizes []int
+
+	for i := 0; i < iterations; i++ {
+		start := time.Now()
+		roots, err := p.workerPool.SubmitRoots(ctx, request.AppID, request.Params, device, opts)
+		var size int
+		if err == nil && len(roots) > 0 {
+			var data []byte
+			data, err = encodeWebP(ctx, roots, webpOpts, p.frameLimit(request.AppID, roots), p.profiles.Lookup(device.ID))
+			if err != nil {
+				err = fmt.Errorf("error encoding WebP: %w", err)
+			}
+			size = len(data)
+		}
+		latencies = append(latencies, time.Since(start))
+
+		// Stop early if the caller went away; the remaining iterations would only fail
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		switch {
+		case err != nil:
+			result.Errors++
+			if result.FirstError == "" {
+				result.FirstError = err.Error()
+			}
+		case len(roots) == 0:
+			result.Skipped++
+		default:
+			sizes = append(sizes, size)
+		}
+	}
+
+	result.LatencyMs = summarizeLatencies(latencies)
+	result.OutputBytes = summarizeSizes(sizes)
+
+	p.logger.Info("Benchmarked app",
+		zap.String("app_id", request.AppID),
+		zap.Int("iterations", iterations),
+		zap.Int("errors", result.Errors),
+		zap.Float64("p95_ms", result.LatencyMs.P95))
+	return result, nil
+}
+
+// summarizeLatencies reduces durations, in the order they were measured, to a LatencySummary
+func summarizeLatencies(latencies []time.Duration) LatencySummary {
+	if len(latencies) == 0 {
+		return LatencySummary{}
+	}
+
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	summary := LatencySummary{
+		First: ms(latencies[0]),
+		Min:   ms(percentile(latencies, 0)),
+		P50:   ms(percentile(latencies, 0.50)),
+		P90:   ms(percentile(latencies, 0.90)),
+		P95:   ms(percentile(latencies, 0.95)),
+		P99:   ms(percentile(latencies, 0.99)),
+		Max:   ms(percentile(latencies, 1)),
+	}
+	var total time.Duration
+	for _, d := range latencies {
+		total += d
+	}
+	summary.Mean = ms(total / time.Duration(len(latencies)))
+	return summary
+}
+
+// summarizeSizes reduces output sizes to a SizeSummary
+func summarizeSizes(sizes []int) SizeSummary {
+	if len(sizes) == 0 {
+		return SizeSummary{}
+	}
+
+	summary := SizeSummary{Min: sizes[0], Max: sizes[0]}
+	total := 0
+	for _, size := range sizes {
+		summary.Min = min(summary.Min, size)
+		summary.Max = max(summary.Max, size)
+		total += size
+	}
+	summary.Mean = total / len(sizes)
+	return summary
+}
@@ -0,0 +1,70 @@
+package pixlet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestProcessorBenchmark(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestApp(t, tempDir, "empty-app", "def main(config):\n    return []\n")
+	writeTestApp(t, tempDir, "failing-app", "def main(config):\n    fail(\"boom\")\n")
+
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, AppletCacheSize: 4, RenderCacheTTL: 60}, zap.NewNop())
+	defer processor.Stop()
+
+	request := func(appID string) *models.RenderRequest {
+		return &models.RenderRequest{
+			Type:   "render_request",
+			AppID:  appID,
+			Device: models.Device{ID: "bench", Width: 64, Height: 32},
+			Params: map[string]interface{}{},
+		}
+	}
+
+	result, err := processor.Benchmark(context.Background(), request("empty-app"), 5)
+	if err != nil {
+		t.Fatalf("Benchmark failed: %v", err)
+	}
+	if result.Iterations != 5 || result.Skipped != 5 || result.Errors != 0 {
+		t.Errorf("Expected 5 skipped renders, got %+v", result)
+	}
+	if result.LatencyMs.Max < result.LatencyMs.P50 || result.LatencyMs.P50 < result.LatencyMs.Min {
+		t.Errorf("Latency percentiles out of order: %+v", result.LatencyMs)
+	}
+	if processor.Stats(context.Background()).Renders.Total != 5 {
+		t.Errorf("Expected every iteration to bypass the render cache, got %d renders", processor.Stats(context.Background()).Renders.Total)
+	}
+
+	result, err = processor.Benchmark(context.Background(), request("failing-app"), 3)
+	if err != nil {
+		t.Fatalf("Benchmark failed: %v", err)
+	}
+	if result.Errors != 3 || result.FirstError == "" {
+		t.Errorf("Expected 3 errors with a message, got %+v", result)
+	}
+
+	for _, n := range []int{0, MaxBenchmarkIterations + 1} {
+		if _, err := processor.Benchmark(context.Background(), request("empty-app"), n); err == nil {
+			t.Errorf("Expected %d iterations to be rejected", n)
+		}
+	}
+}
+
+func TestSummarizeLatencies(t *testing.T) {
+	latencies := []time.Duration{40 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+	got := summarizeLatencies(latencies)
+	want := LatencySummary{First: 40, Min: 10, Mean: 25, P50: 20, P90: 40, P95: 40, P99: 40, Max: 40}
+	if got != want {
+		t.Errorf("summarizeLatencies = %+v, want %+v", got, want)
+	}
+
+	if got := summarizeSizes([]int{100, 300, 200}); got != (SizeSummary{Min: 100, Mean: 200, Max: 300}) {
+		t.Errorf("summarizeSizes = %+v", got)
+	}
+}
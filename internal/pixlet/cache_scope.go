@@ -0,0 +1,95 @@
+package pixlet
+
+import (
+	"context"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+// Scopes of the keys apps store through their cache module
+const (
+	CacheScopeGlobal = "global" // all apps share one key space
+	CacheScopeApp    = "app"    // each app has its own keys
+	CacheScopeDevice = "device" // each app has its own keys per device
+)
+
+type cacheDeviceKey struct{}
+
+// withCacheDevice returns a context whose renders cache under deviceID when
+// keys are scoped per device
+func withCacheDevice(ctx context.Context, deviceID string) context.Context {
+	if deviceID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, cacheDeviceKey{}, deviceID)
+}
+
+// cacheDeviceFromContext returns the device set by withCacheDevice, or ""
+func cacheDeviceFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(cacheDeviceKey{}).(string)
+	return id
+}
+
+// threadContext returns the render context attached to an applet's thread, or
+// a background context without a thread
+func threadContext(thread *starlark.Thread) context.Context {
+	if thread == nil {
+		return context.Background()
+	}
+	return starlarkutil.ThreadContext(thread)
+}
+
+// scopedCache rescopes the keys of an applet's cache module, which pixlet
+// always scopes as "pixlet:{app ID}:{key}", before they reach the cache. The
+// device comes from the render context of each job, so one cache serves every
+// worker.
+type scopedCache struct {
+	cache runtime.Cache
+	scope string
+}
+
+// newScopedCache wraps cache to scope keys by scope. App scoping is pixlet's
+// own, so cache is returned as is for it.
+func newScopedCache(cache runtime.Cache, scope string) runtime.Cache {
+	switch scope {
+	case CacheScopeGlobal, CacheScopeDevice:
+		return &scopedCache{cache: cache, scope: scope}
+	}
+	return cache
+}
+
+// Get retrieves a value under the rescoped key
+func (c *scopedCache) Get(thread *starlark.Thread, key string) ([]byte, bool, error) {
+	return c.cache.Get(thread, c.key(thread, key))
+}
+
+// Set stores a value under the rescoped key
+func (c *scopedCache) Set(thread *starlark.Thread, key string, value []byte, ttl int64) error {
+	return c.cache.Set(thread, c.key(thread, key), value, ttl)
+}
+
+// key rescopes an app-scoped key. Device keys stay under the app's prefix, so
+// FlushApp still removes them; global keys belong to no app. Renders without a
+// device, such as install test renders, keep the app's scope.
+func (c *scopedCache) key(thread *starlark.Thread, key string) string {
+	if thread == nil {
+		return key
+	}
+	prefix := "pixlet:" + thread.Name + ":"
+	appKey, ok := strings.CutPrefix(key, prefix)
+	if !ok {
+		return key
+	}
+	switch c.scope {
+	case CacheScopeGlobal:
+		return "matrx:applet-cache:" + appKey
+	case CacheScopeDevice:
+		if device := cacheDeviceFromContext(threadContext(thread)); device != "" {
+			return prefix + "device:" + device + ":" + appKey
+		}
+	}
+	return key
+}
@@ -0,0 +1,76 @@
+package pixlet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.starlark.net/starlark"
+	"go.uber.org/zap"
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/starlarkutil"
+)
+
+// appletThread returns a thread like the one pixlet runs app appID on
+func appletThread(ctx context.Context, appID string) *starlark.Thread {
+	thread := &starlark.Thread{Name: appID}
+	starlarkutil.AttachThreadContext(ctx, thread)
+	return thread
+}
+
+func TestScopedCache_Key(t *testing.T) {
+	device := appletThread(withCacheDevice(context.Background(), "living-room"), "weather")
+	noDevice := appletThread(context.Background(), "weather")
+
+	tests := []struct {
+		scope  string
+		thread *starlark.Thread
+		want   string
+	}{
+		{CacheScopeDevice, device, "pixlet:weather:device:living-room:forecast"},
+		{CacheScopeDevice, noDevice, "pixlet:weather:forecast"},
+		{CacheScopeGlobal, device, "matrx:applet-cache:forecast"},
+	}
+	for _, tt := range tests {
+		c := newScopedCache(runtime.NewInMemoryCache(), tt.scope).(*scopedCache)
+		if got := c.key(tt.thread, "pixlet:weather:forecast"); got != tt.want {
+			t.Errorf("%s scope: key = %q, want %q", tt.scope, got, tt.want)
+		}
+	}
+
+	// Device keys keep the app's prefix, so FlushApp finds them on Redis
+	if got := appletCacheKey("pixlet:weather:device:living-room:forecast"); got != "pixlet:{weather}:device:living-room:forecast" {
+		t.Errorf("appletCacheKey = %q", got)
+	}
+}
+
+func TestScopedCache_AppScopeUnwrapped(t *testing.T) {
+	cache := runtime.NewInMemoryCache()
+	if got := newScopedCache(cache, CacheScopeApp); got != runtime.Cache(cache) {
+		t.Errorf("Expected app scoping to use the cache as is, got %T", got)
+	}
+}
+
+func TestScopedCache_DevicesDontCollide(t *testing.T) {
+	c := newScopedCache(runtime.NewInMemoryCache(), CacheScopeDevice)
+	kitchen := appletThread(withCacheDevice(context.Background(), "kitchen"), "weather")
+	office := appletThread(withCacheDevice(context.Background(), "office"), "weather")
+
+	if err := c.Set(kitchen, "pixlet:weather:city", []byte("chicago"), 60); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, found, _ := c.Get(office, "pixlet:weather:city"); found {
+		t.Error("Expected another device's key to be invisible")
+	}
+	if value, found, _ := c.Get(kitchen, "pixlet:weather:city"); !found || string(value) != "chicago" {
+		t.Errorf("Expected the device's own key, got %q, %v", value, found)
+	}
+}
+
+func TestNewProcessor_PerDeviceCache(t *testing.T) {
+	p := NewProcessor(&config.PixletConfig{AppsPath: t.TempDir(), CacheScope: CacheScopeDevice}, zap.NewNop())
+	defer p.Stop()
+	if !p.workerPool.perDevice {
+		t.Error("Expected device scoping to keep each device's jobs apart")
+	}
+}
@@ -0,0 +1,164 @@
+package pixlet
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+// Backends of the app cache, as labeled in metrics and stats
+const (
+	cacheBackendMemory = "memory"
+	cacheBackendRedis  = "redis"
+)
+
+// AppCacheCounts counts lookups in the app cache, the keys apps store with
+// their cache module
+type AppCacheCounts struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Errors uint64 `json:"errors"`         // failed gets and sets
+	Keys   *int64 `json:"keys,omitempty"` // keys in Redis; unknown for the in-memory cache
+}
+
+// AppCacheStats reports the app cache on GET /admin/cache/stats. Counts are
+// this replica's since it started; key counts are shared by all replicas on
+// one Redis.
+type AppCacheStats struct {
+	Backend string                    `json:"backend"` // memory or redis
+	Scope   string                    `json:"scope"`   // PIXLET_CACHE_SCOPE
+	Total   AppCacheCounts            `json:"total"`
+	Apps    map[string]AppCacheCounts `json:"apps"`
+}
+
+// countingCache counts the lookups apps make through their cache module, per
+// app, on /metrics and in AppCacheStats
+type countingCache struct {
+	cache   runtime.Cache
+	backend string
+	mu      sync.Mutex
+	apps    map[string]*AppCacheCounts
+}
+
+func newCountingCache(cache runtime.Cache, backend string) *countingCache {
+	return &countingCache{
+		cache:   cache,
+		backend: backend,
+		apps:    make(map[string]*AppCacheCounts),
+	}
+}
+
+// Get retrieves a value, counting a hit, miss or error
+func (c *countingCache) Get(thread *starlark.Thread, key string) ([]byte, bool, error) {
+	value, found, err := c.cache.Get(thread, key)
+	result := "miss"
+	switch {
+	case err != nil:
+		result = "error"
+	case found:
+		result = "hit"
+	}
+	c.record(thread, result)
+	return value, found, err
+}
+
+// Set stores a value, counting an error
+func (c *countingCache) Set(thread *starlark.Thread, key string, value []byte, ttl int64) error {
+	err := c.cache.Set(thread, key, value, ttl)
+	if err != nil {
+		c.record(thread, "error")
+	}
+	return err
+}
+
+func (c *countingCache) record(thread *starlark.Thread, result string) {
+	metricAppCache.WithLabelValues(c.backend, result).Inc()
+
+	appID := ""
+	if thread != nil {
+		appID = thread.Name
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats, ok := c.apps[appID]
+	if !ok {
+		stats = &AppCacheCounts{}
+		c.apps[appID] = stats
+	}
+	switch result {
+	case "hit":
+		stats.Hits++
+	case "miss":
+		stats.Misses++
+	default:
+		stats.Errors++
+	}
+}
+
+// snapshot returns per-app counts and their sum
+func (c *countingCache) snapshot() (map[string]AppCacheCounts, AppCacheCounts) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	byApp := make(map[string]AppCacheCounts, len(c.apps))
+	var total AppCacheCounts
+	for appID, stats := range c.apps {
+		byApp[appID] = *stats
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Errors += stats.Errors
+	}
+	return byApp, total
+}
+
+// appCacheStatsTimeout bounds counting the app cache's keys, which scans the
+// whole keyspace
+const appCacheStatsTimeout = 10 * time.Second
+
+// AppCacheStats reports app cache lookups per app. With Redis it also counts
+// each app's keys there, failing if Redis does.
+func (p *Processor) AppCacheStats(ctx context.Context) (*AppCacheStats, error) {
+	byApp, total := p.cacheCounts.snapshot()
+	stats := &AppCacheStats{
+		Backend: p.cacheCounts.backend,
+		Scope:   p.config.CacheScope,
+		Total:   total,
+		Apps:    byApp,
+	}
+	if stats.Scope == "" {
+		stats.Scope = CacheScopeApp
+	}
+	if p.redisCache == nil {
+		return stats, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, appCacheStatsTimeout)
+	defer cancel()
+	counts, err := p.redisCache.CountAppKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var keys int64
+	for appID, n := range counts {
+		app := stats.Apps[appID]
+		app.Keys = &n
+		stats.Apps[appID] = app
+		keys += n
+	}
+	stats.Total.Keys = &keys
+	return stats, nil
+}
+
+// FlushAppCache deletes an app's rendered output and applet cache entries from
+// Redis and its output from this replica's memory, returning how many Redis
+// keys were removed. Other replicas' in-memory output expires on its TTL.
+func (p *Processor) FlushAppCache(ctx context.Context, appID string) (int64, error) {
+	if p.redisCache == nil {
+		return 0, ErrNoRedis
+	}
+	p.renderCache.forgetApp(appID)
+	return p.redisCache.FlushApp(ctx, appID)
+}
@@ -0,0 +1,56 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.starlark.net/starlark"
+	"tidbyt.dev/pixlet/runtime"
+)
+
+// failingCache fails every lookup
+type failingCache struct{}
+
+func (failingCache) Get(*starlark.Thread, string) ([]byte, bool, error) {
+	return nil, false, errors.New("cache down")
+}
+
+func (failingCache) Set(*starlark.Thread, string, []byte, int64) error {
+	return errors.New("cache down")
+}
+
+func TestCountingCache(t *testing.T) {
+	c := newCountingCache(runtime.NewInMemoryCache(), cacheBackendMemory)
+	weather := appletThread(context.Background(), "weather")
+	clock := appletThread(context.Background(), "clock")
+
+	c.Get(weather, "pixlet:weather:forecast")
+	c.Set(weather, "pixlet:weather:forecast", []byte("sunny"), 60)
+	c.Get(weather, "pixlet:weather:forecast")
+	c.Get(clock, "pixlet:clock:zone")
+
+	byApp, total := c.snapshot()
+	if got := byApp["weather"]; got.Hits != 1 || got.Misses != 1 || got.Errors != 0 {
+		t.Errorf("weather counts = %+v", got)
+	}
+	if got := byApp["clock"]; got.Hits != 0 || got.Misses != 1 {
+		t.Errorf("clock counts = %+v", got)
+	}
+	if total.Hits != 1 || total.Misses != 2 {
+		t.Errorf("total = %+v", total)
+	}
+}
+
+func TestCountingCache_Errors(t *testing.T) {
+	c := newCountingCache(failingCache{}, cacheBackendRedis)
+	weather := appletThread(context.Background(), "weather")
+
+	c.Get(weather, "pixlet:weather:forecast")
+	c.Set(weather, "pixlet:weather:forecast", []byte("sunny"), 60)
+
+	byApp, _ := c.snapshot()
+	if got := byApp["weather"]; got.Errors != 2 || got.Misses != 0 {
+		t.Errorf("Expected a failed get and set counted as errors, got %+v", got)
+	}
+}
@@ -0,0 +1,109 @@
+package pixlet
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// AppsUpdatedMessage on the control channel makes every replica reload its app
+// registry, e.g. after a deployment changed the shared apps path
+const AppsUpdatedMessage = "apps_updated"
+
+// controlPublishTimeout bounds announcing a change on the control channel
+const controlPublishTimeout = 2 * time.Second
+
+// controlListener follows the Redis control channel and acts on its messages.
+// go-redis resubscribes after a lost connection, so messages published while
+// it was down are missed, but later ones arrive.
+type controlListener struct {
+	processor *Processor
+	client    redis.UniversalClient
+	channel   string
+	pubsub    *redis.PubSub
+	logger    *zap.Logger
+	stopOnce  sync.Once
+	done      chan struct{}
+}
+
+// newControlListener returns a listener on channel, or nil without Redis or a
+// channel
+func newControlListener(p *Processor, channel string) *controlListener {
+	if p.redisCache == nil || channel == "" {
+		return nil
+	}
+	return &controlListener{
+		processor: p,
+		client:    p.redisCache.client,
+		channel:   channel,
+		logger:    p.logger,
+		done:      make(chan struct{}),
+	}
+}
+
+// Start subscribes to the control channel and handles its messages until Stop
+func (c *controlListener) Start() {
+	if c == nil {
+		return
+	}
+	// Subscribing without channels doesn't connect, so a Redis that is down
+	// doesn't hold up the caller
+	c.pubsub = c.client.Subscribe(context.Background())
+	go func() {
+		defer close(c.done)
+		if err := c.pubsub.Subscribe(context.Background(), c.channel); err != nil {
+			c.logger.Warn("Failed to subscribe to control channel; retrying in the background",
+				zap.String("channel", c.channel), zap.Error(err))
+		}
+		for msg := range c.pubsub.Channel() {
+			c.handle(msg.Payload)
+		}
+	}()
+}
+
+// Stop unsubscribes and waits for a message being handled. It is safe to call
+// more than once, and on a nil listener.
+func (c *controlListener) Stop() {
+	if c == nil {
+		return
+	}
+	c.stopOnce.Do(func() {
+		c.pubsub.Close()
+		<-c.done
+	})
+}
+
+func (c *controlListener) handle(message string) {
+	switch message {
+	case AppsUpdatedMessage:
+		c.logger.Info("Apps updated, refreshing app registry", zap.String("channel", c.channel))
+		if err := c.processor.RefreshAppRegistry(); err != nil {
+			c.logger.Error("Failed to refresh app registry", zap.Error(err))
+		}
+	default:
+		c.logger.Debug("Ignoring unknown control message", zap.String("message", message))
+	}
+}
+
+// AnnounceAppsUpdated publishes AppsUpdatedMessage on the control channel, so
+// every replica, this one included, reloads its app registry. It does nothing
+// without Redis or a control channel.
+func (p *Processor) AnnounceAppsUpdated(ctx context.Context) error {
+	if p.control == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, controlPublishTimeout)
+	defer cancel()
+	return p.control.client.Publish(ctx, p.control.channel, AppsUpdatedMessage).Err()
+}
+
+// announceAppsUpdated announces an install or delete, logging a failure: the
+// other replicas then catch up on their next refresh
+func (p *Processor) announceAppsUpdated() {
+	if err := p.AnnounceAppsUpdated(context.Background()); err != nil {
+		p.logger.Warn("Failed to announce app update to other replicas", zap.Error(err))
+	}
+}
@@ -0,0 +1,57 @@
+package pixlet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestControlListener_AppsUpdated(t *testing.T) {
+	appsPath := t.TempDir()
+	processor := NewProcessor(&config.PixletConfig{AppsPath: appsPath}, zap.NewNop())
+	defer processor.Stop()
+
+	// Another replica installed an app into the shared apps path
+	dir := filepath.Join(appsPath, "new-app")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(installTestManifest("new-app")), 0644)
+	os.WriteFile(filepath.Join(dir, "new-app.star"), []byte(installTestApp), 0644)
+
+	listener := &controlListener{processor: processor, channel: config.DefaultControlChannel, logger: zap.NewNop()}
+	listener.handle("something_else")
+	if _, ok := processor.GetAppRegistry().GetApp("new-app"); ok {
+		t.Fatal("An unknown message should not refresh the registry")
+	}
+	listener.handle(AppsUpdatedMessage)
+	if _, ok := processor.GetAppRegistry().GetApp("new-app"); !ok {
+		t.Error("Expected apps_updated to load the new app")
+	}
+}
+
+func TestControlListener_Disabled(t *testing.T) {
+	processor := NewProcessor(&config.PixletConfig{AppsPath: t.TempDir()}, zap.NewNop())
+	defer processor.Stop()
+	if newControlListener(processor, config.DefaultControlChannel) != nil {
+		t.Error("Expected no listener without Redis")
+	}
+	if err := processor.AnnounceAppsUpdated(context.Background()); err != nil {
+		t.Errorf("Announcing without Redis should do nothing, got %v", err)
+	}
+
+	redisProcessor := NewProcessorWithRedis(&config.PixletConfig{AppsPath: t.TempDir()}, &config.RedisConfig{Addr: "127.0.0.1:1"}, zap.NewNop())
+	defer redisProcessor.Close()
+	defer redisProcessor.Stop()
+	if newControlListener(redisProcessor, "") != nil {
+		t.Error("Expected no listener without a channel")
+	}
+
+	// A listener on an unreachable Redis starts and stops without blocking
+	listener := newControlListener(redisProcessor, config.DefaultControlChannel)
+	listener.Start()
+	listener.Stop()
+	listener.Stop()
+}
@@ -0,0 +1,131 @@
+package pixlet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// jobFlights lets concurrent identical render jobs share a single render
+type jobFlights struct {
+	mu      sync.Mutex
+	flights map[string]*jobFlight
+}
+
+type jobFlight struct {
+	done   chan struct{}
+	result *RenderResult
+	err    error
+}
+
+func newJobFlights() *jobFlights {
+	return &jobFlights{flights: make(map[string]*jobFlight)}
+}
+
+// do runs fn for the first caller with a given key; callers arriving while it is
+// in progress wait for and share its result. shared reports whether the result
+// came from another caller's render.
+func (f *jobFlights) do(ctx context.Context, key string, fn func() (*RenderResult, error)) (result *RenderResult, err error, shared bool) {
+	f.mu.Lock()
+	if flight, ok := f.flights[key]; ok {
+		f.mu.Unlock()
+		select {
+		case <-flight.done:
+			return flight.result, flight.err, true
+		case <-ctx.Done():
+			return nil, ctx.Err(), true
+		}
+	}
+
+	flight := &jobFlight{done: make(chan struct{})}
+	f.flights[key] = flight
+	f.mu.Unlock()
+
+	flight.result, flight.err = fn()
+
+	f.mu.Lock()
+	delete(f.flights, key)
+	f.mu.Unlock()
+	close(flight.done)
+
+	return flight.result, flight.err, false
+}
+
+// dedupe submits a job, joining an identical job already in flight instead of
+// rendering it again. If the job being joined is abandoned by its caller, the
+// remaining callers retry rather than inheriting the cancellation.
+func (wp *WorkerPool) dedupe(ctx context.Context, job *RenderJob) (*RenderResult, error) {
+	if wp.flights == nil {
+		return wp.enqueue(ctx, job)
+	}
+
+	key := jobKey(job, wp.perDevice)
+	for {
+		result, err, shared := wp.flights.do(ctx, key, func() (*RenderResult, error) {
+			return wp.enqueue(ctx, job)
+		})
+		// A render timing out isn't the joined caller giving up, so it is shared
+		abandoned := errors.Is(err, context.Canceled) || (errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, errRenderTimeout))
+		if shared && ctx.Err() == nil && abandoned {
+			continue
+		}
+		if shared {
+			metricJobsDeduplicated.Inc()
+			if result != nil {
+				wp.logger.Debug("Joined identical render in flight",
+					zap.String("job_id", JobIDFromContext(ctx)),
+					zap.String("rendered_by", result.JobID),
+					zap.String("app_id", job.AppID))
+			}
+		}
+		return result, err
+	}
+}
+
+// jobKey hashes everything that affects a job's rendered roots: the app, its
+// normalized config, the target size(s) and render options, and with perDevice,
+// when apps cache per device, the device. Encoding settings are applied after
+// rendering, so jobs that differ only in output format share a key.
+func jobKey(job *RenderJob, perDevice bool) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "app=%s\n", job.AppID)
+
+	config := appletConfig(job.Params)
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(h, "config %q=%q\n", key, config[key])
+	}
+
+	if len(job.Sizes) > 0 {
+		// Batch jobs return per-size results, so they never share with single jobs
+		for _, size := range job.Sizes {
+			fmt.Fprintf(h, "batch size=%s\n", size)
+		}
+	} else {
+		fmt.Fprintf(h, "size=%dx%d\n", job.Device.Width, job.Device.Height)
+	}
+
+	if !job.Options.RenderTime.IsZero() {
+		fmt.Fprintf(h, "time=%d\n", job.Options.RenderTime.UnixNano())
+	}
+	if job.Options.DebugOverlay {
+		// The overlay stamps the device ID, so it must match too
+		fmt.Fprintf(h, "overlay=%s\n", job.Device.ID)
+	}
+	if perDevice {
+		// Each device's cached data can change what the app draws
+		fmt.Fprintf(h, "device=%s\n", job.Device.ID)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
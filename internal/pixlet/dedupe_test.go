@@ -0,0 +1,154 @@
+package pixlet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+)
+
+func TestJobKey(t *testing.T) {
+	base := func() *RenderJob {
+		return &RenderJob{
+			AppID:  "clock",
+			Params: map[string]interface{}{"tz": "UTC", "format": "24h", "seconds": true},
+			Device: models.Device{ID: "device-a", Width: 64, Height: 32},
+		}
+	}
+	key := jobKey(base(), false)
+
+	same := []struct {
+		name   string
+		modify func(job *RenderJob)
+	}{
+		{"different device ID", func(job *RenderJob) { job.Device.ID = "device-b" }},
+		{"stringified param", func(job *RenderJob) { job.Params["seconds"] = "true" }},
+		{"encoding override", func(job *RenderJob) {
+			lossless := false
+			job.Options.Encoding = &models.EncodingOptions{Lossless: &lossless}
+		}},
+		{"priority", func(job *RenderJob) { job.Priority = PriorityBackground }},
+	}
+	for _, tt := range same {
+		job := base()
+		tt.modify(job)
+		if got := jobKey(job, false); got != key {
+			t.Errorf("%s: expected same key", tt.name)
+		}
+	}
+
+	different := []struct {
+		name   string
+		modify func(job *RenderJob)
+	}{
+		{"app", func(job *RenderJob) { job.AppID = "weather" }},
+		{"param value", func(job *RenderJob) { job.Params["tz"] = "Europe/Paris" }},
+		{"extra param", func(job *RenderJob) { job.Params["color"] = "red" }},
+		{"size", func(job *RenderJob) { job.Device.Width = 128 }},
+		{"batch sizes", func(job *RenderJob) { job.Sizes = []models.Size{{Width: 64, Height: 32}} }},
+		{"render time", func(job *RenderJob) { job.Options.RenderTime = time.Unix(1700000000, 0) }},
+		{"debug overlay", func(job *RenderJob) { job.Options.DebugOverlay = true }},
+	}
+	for _, tt := range different {
+		job := base()
+		tt.modify(job)
+		if got := jobKey(job, false); got == key {
+			t.Errorf("%s: expected different key", tt.name)
+		}
+	}
+}
+
+func TestJobKey_PerDevice(t *testing.T) {
+	// Same app and config on two devices: with per-device app caches their
+	// renders may differ, so they must not share a flight
+	kitchen := &RenderJob{AppID: "weather", Params: map[string]interface{}{"city": "chicago"}, Device: models.Device{ID: "kitchen", Width: 64, Height: 32}}
+	office := &RenderJob{AppID: "weather", Params: map[string]interface{}{"city": "chicago"}, Device: models.Device{ID: "office", Width: 64, Height: 32}}
+
+	if jobKey(kitchen, false) != jobKey(office, false) {
+		t.Error("Expected devices to share a key when apps cache per app")
+	}
+	if jobKey(kitchen, true) == jobKey(office, true) {
+		t.Error("Expected devices to get their own keys when apps cache per device")
+	}
+}
+
+func TestJobFlightsShareResult(t *testing.T) {
+	flights := newJobFlights()
+	release := make(chan struct{})
+	want := &RenderResult{}
+	calls := 0
+
+	type outcome struct {
+		result *RenderResult
+		shared bool
+	}
+	outcomes := make(chan outcome, 2)
+	run := func() {
+		result, _, shared := flights.do(context.Background(), "key", func() (*RenderResult, error) {
+			calls++
+			<-release
+			return want, nil
+		})
+		outcomes <- outcome{result, shared}
+	}
+
+	go run()
+	waitForFlight(t, flights, "key")
+	go run()
+
+	// Give the second caller time to join before the render completes
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	sharedCount := 0
+	for i := 0; i < 2; i++ {
+		got := <-outcomes
+		if got.result != want {
+			t.Error("expected both callers to receive the same result")
+		}
+		if got.shared {
+			sharedCount++
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 render, got %d", calls)
+	}
+	if sharedCount != 1 {
+		t.Errorf("expected 1 shared result, got %d", sharedCount)
+	}
+}
+
+func TestJobFlightsWaiterCancelled(t *testing.T) {
+	flights := newJobFlights()
+	release := make(chan struct{})
+	defer close(release)
+
+	go flights.do(context.Background(), "key", func() (*RenderResult, error) {
+		<-release
+		return &RenderResult{}, nil
+	})
+	waitForFlight(t, flights, "key")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err, _ := flights.do(ctx, "key", nil); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// waitForFlight blocks until a render for key is in flight
+func waitForFlight(t *testing.T, flights *jobFlights, key string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		flights.mu.Lock()
+		_, ok := flights.flights[key]
+		flights.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("render never started")
+}
@@ -0,0 +1,105 @@
+package pixlet
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	_ "unsafe" // for go:linkname
+
+	"github.com/zachomedia/go-bdf"
+	"go.uber.org/zap"
+
+	"tidbyt.dev/pixlet/render"
+)
+
+// fontDataRaw is pixlet's table of embedded BDF fonts (name -> base64 data).
+// Pixlet has no public API for adding fonts yet, so custom fonts are inserted
+// here directly; render.GetFont resolves and caches them like the built-in
+// ones. Only registerFont and unregisterFont may touch it, so the link is
+// replaced in one place once the fork exports a synchronized registration API.
+//
+//go:linkname fontDataRaw tidbyt.dev/pixlet/render.fontDataRaw
+var fontDataRaw map[string]string
+
+// fontsMu serializes this package's changes to fontDataRaw. Pixlet reads the
+// table without it, so fonts must be registered before any render starts.
+var fontsMu sync.Mutex
+
+// LoadFonts registers every .bdf file in dir with the Pixlet runtime, named after the
+// file without its extension. Built-in fonts are never replaced. Fonts registered
+// before the first render also appear in the Starlark render.fonts dict. The font
+// table is not synchronized with rendering, so call this during startup.
+func LoadFonts(dir string, logger *zap.Logger) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fonts directory: %w", err)
+	}
+
+	builtin := make(map[string]bool)
+	for _, name := range render.GetFontList() {
+		builtin[name] = true
+	}
+
+	var loaded []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".bdf") {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if builtin[name] {
+			logger.Warn("Skipping custom font that shadows a built-in font",
+				zap.String("font", name))
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			logger.Warn("Failed to read custom font",
+				zap.String("font", name),
+				zap.Error(err))
+			continue
+		}
+		if err := validateFont(data); err != nil {
+			logger.Warn("Failed to parse custom font",
+				zap.String("font", name),
+				zap.Error(err))
+			continue
+		}
+
+		registerFont(name, data)
+		loaded = append(loaded, name)
+	}
+
+	return loaded, nil
+}
+
+// validateFont checks that data is a BDF font with at least one glyph
+func validateFont(data []byte) error {
+	font, err := bdf.Parse(data)
+	if err != nil {
+		return err
+	}
+	if len(font.Characters) == 0 {
+		return fmt.Errorf("font has no glyphs")
+	}
+	return nil
+}
+
+// registerFont adds BDF font data to the runtime's font table
+func registerFont(name string, data []byte) {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+	fontDataRaw[name] = base64.StdEncoding.EncodeToString(data)
+}
+
+// unregisterFont removes a custom font from the runtime's font table. A face
+// pixlet already resolved for it stays in pixlet's cache.
+func unregisterFont(name string) {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+	delete(fontDataRaw, name)
+}
@@ -0,0 +1,81 @@
+package pixlet
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestLoadFonts(t *testing.T) {
+	fontsDir := t.TempDir()
+
+	// Reuse an embedded font's BDF data as the "custom" font
+	data, err := base64.StdEncoding.DecodeString(fontDataRaw["tom-thumb"])
+	if err != nil {
+		t.Fatalf("Failed to decode embedded font: %v", err)
+	}
+	files := map[string][]byte{
+		"brand-test.bdf": data,
+		"tom-thumb.bdf":  data,                     // shadows a built-in font
+		"broken.bdf":     []byte("not a bdf font"), // fails to parse
+		"readme.txt":     []byte("ignored"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(fontsDir, name), content, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	loaded, err := LoadFonts(fontsDir, zap.NewNop())
+	if err != nil {
+		t.Fatalf("LoadFonts failed: %v", err)
+	}
+	// The font table is process-wide, so don't leave the font to other tests
+	t.Cleanup(func() {
+		for _, name := range loaded {
+			unregisterFont(name)
+		}
+	})
+	sort.Strings(loaded)
+	if len(loaded) != 1 || loaded[0] != "brand-test" {
+		t.Fatalf("Expected only brand-test to load, got %v", loaded)
+	}
+
+	if _, err := LoadFonts(filepath.Join(fontsDir, "missing"), zap.NewNop()); err == nil {
+		t.Error("Expected error for missing fonts directory")
+	}
+
+	// An app can now render text with the custom font
+	appsDir := t.TempDir()
+	appDir := filepath.Join(appsDir, "font-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+	appContent := `
+load("render.star", "render")
+
+def main(config):
+    return render.Root(child = render.Text("Hi", font = "brand-test"))
+`
+	if err := os.WriteFile(filepath.Join(appDir, "font-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "font-app", "font-app.star")
+
+	processor := NewProcessor(&config.PixletConfig{AppsPath: appsDir}, zap.NewNop())
+	defer processor.Stop()
+
+	err = processor.StreamFrames(context.Background(), "font-app", map[string]interface{}{},
+		models.Device{ID: "test-device", Width: 64, Height: 32}, RenderOptions{},
+		func(frame Frame) error { return nil })
+	if err != nil {
+		t.Fatalf("Rendering with custom font failed: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package pixlet
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"github.com/tidbyt/gg"
+	"go.uber.org/zap"
+
+	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/render"
+)
+
+// defaultMaxAnimationMillis caps animations that don't request ShowFullAnimation,
+// matching the limit used when encoding WebP output.
+const defaultMaxAnimationMillis = 15000
+
+// Frame is a single painted frame of a rendered app
+type Frame struct {
+	Index int
+	Image image.Image
+	Delay time.Duration
+}
+
+// FrameFunc receives frames in display order. Returning an error stops painting.
+type FrameFunc func(frame Frame) error
+
+// StreamFrames renders an app and paints its frames one at a time, handing each
+// to emit as soon as it is ready instead of buffering the whole animation.
+func (p *Processor) StreamFrames(ctx context.Context, appID string, params map[string]interface{}, device models.Device, opts RenderOptions, emit FrameFunc) error {
+	roots, err := p.renderRoots(ctx, appID, params, device, opts)
+	if err != nil {
+		return err
+	}
+
+	count, err := paintFrames(ctx, roots, p.frameLimit(appID, roots), p.profiles.Lookup(device.ID).filter(emit))
+	if err != nil {
+		return err
+	}
+
+	p.logger.Debug("Pixlet frames streamed",
+		zap.String("app_id", appID),
+		zap.Int("frame_count", count))
+	return nil
+}
+
+// paintFrames paints each frame of the roots in order, honoring the same
+// maximum animation duration as WebP encoding and stopping after maxFrames frames
+// (render.DefaultMaxFrameCount when <= 0). Returns the number of frames emitted.
+func paintFrames(ctx context.Context, roots []render.Root, maxFrames int, emit FrameFunc) (int, error) {
+	if len(roots) == 0 {
+		return 0, nil
+	}
+	if maxFrames <= 0 {
+		maxFrames = render.DefaultMaxFrameCount
+	}
+
+	delay := time.Duration(encode.DefaultScreenDelayMillis) * time.Millisecond
+	if roots[0].Delay > 0 {
+		delay = time.Duration(roots[0].Delay) * time.Millisecond
+	}
+
+	remaining := time.Duration(defaultMaxAnimationMillis) * time.Millisecond
+	if roots[0].ShowFullAnimation {
+		remaining = 0
+	}
+	limited := remaining > 0
+
+	index := 0
+	for _, root := range roots {
+		numFrames := root.Child.FrameCount()
+		if numFrames > maxFrames {
+			numFrames = maxFrames
+		}
+
+		for i := 0; i < numFrames; i++ {
+			if index >= maxFrames {
+				return index, nil
+			}
+			if err := ctx.Err(); err != nil {
+				return index, err
+			}
+
+			frameDelay := delay
+			if limited {
+				if frameDelay > remaining {
+					frameDelay = remaining
+				}
+				remaining -= frameDelay
+			}
+
+			if err := emit(Frame{Index: index, Image: paintFrame(root, i), Delay: frameDelay}); err != nil {
+				return index, err
+			}
+			index++
+
+			if limited && remaining <= 0 {
+				return index, nil
+			}
+		}
+	}
+
+	return index, nil
+}
+
+// countFrames returns the number of frames the roots would produce without limits
+func countFrames(roots []render.Root) int {
+	total := 0
+	for _, root := range roots {
+		total += root.Child.FrameCount()
+	}
+	return total
+}
+
+// paintFrame paints a single frame of a root onto a solid black canvas
+func paintFrame(root render.Root, frameIndex int) image.Image {
+	width := root.Width
+	if width <= 0 {
+		width = render.DefaultFrameWidth
+	}
+	height := root.Height
+	if height <= 0 {
+		height = render.DefaultFrameHeight
+	}
+
+	dc := gg.NewContext(width, height)
+	dc.SetColor(color.Black)
+	dc.Clear()
+
+	dc.Push()
+	root.Child.Paint(dc, image.Rect(0, 0, width, height), frameIndex)
+	dc.Pop()
+
+	return dc.Image()
+}
@@ -0,0 +1,37 @@
+package pixlet
+
+import (
+	"context"
+	"testing"
+
+	"tidbyt.dev/pixlet/render"
+)
+
+func TestPaintFramesAboveDefaultMaxFrameCount(t *testing.T) {
+	children := make([]render.Widget, render.DefaultMaxFrameCount+500)
+	for i := range children {
+		children[i] = render.Box{Width: 1, Height: 1}
+	}
+	roots := []render.Root{{
+		Child:             render.Animation{Children: children},
+		ShowFullAnimation: true,
+	}}
+
+	tests := []struct {
+		maxFrames int
+		want      int
+	}{
+		{0, render.DefaultMaxFrameCount},
+		{render.DefaultMaxFrameCount + 200, render.DefaultMaxFrameCount + 200},
+		{render.DefaultMaxFrameCount + 1000, len(children)},
+	}
+	for _, tt := range tests {
+		got, err := paintFrames(context.Background(), roots, tt.maxFrames, func(Frame) error { return nil })
+		if err != nil {
+			t.Fatalf("paintFrames(%d) failed: %v", tt.maxFrames, err)
+		}
+		if got != tt.want {
+			t.Errorf("paintFrames(%d) emitted %d frames, want %d", tt.maxFrames, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package pixlet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/runtime/modules/starlarkhttp"
+)
+
+// HTTP cache limits, matching the runtime's own client
+const (
+	httpTimeout         = 5 * time.Second  // per request, including time spent rate limited
+	httpMinTTL          = 5 * time.Second  // floor for cacheable responses without freshness info
+	httpMaxResponseTTL  = time.Hour        // cap on TTLs taken from response headers
+	httpMaxResponseSize = 20 * 1024 * 1024 // 20MB
+	httpCachePrefix     = "httpcache"
+	ttlHeader           = "X-Tidbyt-Cache-Seconds" // set by http.star from ttl_seconds
+	cacheStatusHeader   = "Tidbyt-Cache-Status"
+)
+
+// cacheableStatus lists response codes that may be cached
+var cacheableStatus = map[int]bool{
+	200: true, 201: true, 202: true, 203: true, 204: true, 206: true,
+	300: true, 301: true, 404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// httpCache is the transport behind http.star. It serves responses from a
+// runtime.Cache (Redis when configured, so every worker and replica shares it)
+// for as long as their Cache-Control headers allow, and collapses concurrent
+// identical misses into a single upstream request.
+type httpCache struct {
+	cache   runtime.Cache
+	next    http.RoundTripper
+	mu      sync.Mutex
+	flights map[string]*httpFlight
+	lookups hitCounter
+}
+
+type httpFlight struct {
+	done chan struct{}
+	data []byte // serialized response; nil if the leader's request failed
+}
+
+func newHTTPCache(cache runtime.Cache, next http.RoundTripper) *httpCache {
+	return &httpCache{cache: cache, next: next, flights: make(map[string]*httpFlight)}
+}
+
+// initHTTP points the runtime's http.star client at an HTTP cache over transport
+// and returns the cache. It must run before any app loads http.star, which
+// captures the client.
+func initHTTP(cache runtime.Cache, transport http.RoundTripper) *httpCache {
+	hc := newHTTPCache(cache, transport)
+	starlarkhttp.StarlarkHTTPClient = &http.Client{
+		Transport: hc,
+		Timeout:   2 * httpTimeout,
+	}
+	return hc
+}
+
+// RoundTrip serves req from the cache when possible, otherwise sends it upstream
+// and stores the response for its cacheable lifetime
+func (c *httpCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), httpTimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	if !cacheableRequest(req) {
+		data, err := c.fetch(req)
+		if err != nil {
+			return nil, err
+		}
+		return readCachedResponse(data, req, "")
+	}
+
+	key, err := httpCacheKey(req)
+	if err != nil {
+		return nil, err
+	}
+	if data, ok, err := c.cache.Get(nil, key); ok && err == nil {
+		if resp, err := readCachedResponse(data, req, "HIT"); err == nil {
+			c.lookups.record(true)
+			return resp, nil
+		}
+	}
+
+	c.mu.Lock()
+	if flight, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-flight.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		c.lookups.record(flight.data != nil)
+		if flight.data != nil {
+			return readCachedResponse(flight.data, req, "HIT")
+		}
+		// The leader failed; try on our own rather than sharing its error
+		data, err := c.fetch(req)
+		if err != nil {
+			return nil, err
+		}
+		return readCachedResponse(data, req, "MISS")
+	}
+	flight := &httpFlight{done: make(chan struct{})}
+	c.flights[key] = flight
+	c.mu.Unlock()
+	c.lookups.record(false)
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+		close(flight.done)
+	}()
+
+	data, err := c.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	flight.data = data
+
+	resp, err := readCachedResponse(data, req, "MISS")
+	if err != nil {
+		return nil, err
+	}
+	if ttl := responseTTL(req, resp, time.Now()); ttl > 0 {
+		c.cache.Set(nil, key, data, int64(ttl.Seconds()))
+	}
+	return resp, nil
+}
+
+// fetch sends req upstream and returns the serialized response. The body is read
+// in full, up to the size cap, before the request's timeout is released.
+func (c *httpCache) fetch(req *http.Request) ([]byte, error) {
+	resp, err := c.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	resp.Body = http.MaxBytesReader(nil, resp.Body, httpMaxResponseSize)
+	data, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", req.URL.Host, err)
+	}
+	return data, nil
+}
+
+// cacheableRequest reports whether req's response may come from or go to the cache.
+// POSTs are only cached when the app asked for a TTL.
+func cacheableRequest(req *http.Request) bool {
+	if _, noStore := parseCacheControl(req.Header.Get("Cache-Control"))["no-store"]; noStore {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return developerTTL(req) > 0
+	}
+	return false
+}
+
+// responseTTL returns how long resp may be cached, or 0 if it must not be.
+//
+// A ttl_seconds set by the app wins, since the app knows how fresh it needs the
+// data to be. Otherwise the response's Cache-Control decides: no-store, no-cache
+// and private responses are never cached, and s-maxage, max-age or Expires (less
+// the response's Age) set the lifetime, capped at an hour. Responses that carry no
+// freshness information are cached briefly so bursts of identical requests still
+// collapse. A 429 is cached for its Retry-After to back off the upstream.
+func responseTTL(req *http.Request, resp *http.Response, now time.Time) time.Duration {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && time.Duration(seconds)*time.Second > httpMinTTL {
+			return time.Duration(seconds) * time.Second
+		}
+		return httpMinTTL
+	}
+	if !cacheableStatus[resp.StatusCode] {
+		return 0
+	}
+
+	if ttl := developerTTL(req); ttl > 0 {
+		return ttl
+	}
+
+	directives := parseCacheControl(resp.Header.Get("Cache-Control"))
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[d]; ok {
+			return 0
+		}
+	}
+
+	var ttl time.Duration
+	var explicit bool
+	if seconds, ok := directives["s-maxage"]; ok {
+		ttl, explicit = seconds, true
+	} else if seconds, ok := directives["max-age"]; ok {
+		ttl, explicit = seconds, true
+	} else if expires := resp.Header.Get("Expires"); expires != "" {
+		explicit = true
+		if at, err := http.ParseTime(expires); err == nil {
+			date := now
+			if d, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+				date = d
+			}
+			ttl = at.Sub(date)
+		}
+	}
+	if !explicit {
+		return httpMinTTL
+	}
+
+	if age, err := strconv.Atoi(resp.Header.Get("Age")); err == nil {
+		ttl -= time.Duration(age) * time.Second
+	}
+	if ttl > httpMaxResponseTTL {
+		ttl = httpMaxResponseTTL
+	}
+	if ttl < time.Second {
+		return 0
+	}
+	return ttl.Truncate(time.Second)
+}
+
+// developerTTL returns the ttl_seconds the app passed to http.star, if any
+func developerTTL(req *http.Request) time.Duration {
+	seconds, err := strconv.Atoi(req.Header.Get(ttlHeader))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// parseCacheControl returns the directives in a Cache-Control header, with the
+// seconds for those that take one; malformed values count as zero
+func parseCacheControl(header string) map[string]time.Duration {
+	directives := make(map[string]time.Duration)
+	for _, directive := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name == "" {
+			continue
+		}
+		seconds, _ := strconv.Atoi(strings.Trim(value, `"`))
+		directives[strings.ToLower(name)] = time.Duration(seconds) * time.Second
+	}
+	return directives
+}
+
+// httpCacheKey identifies a request by app and a hash of the full request minus
+// its TTL, so apps asking for different freshness share an entry
+func httpCacheKey(req *http.Request) (string, error) {
+	ttl := req.Header.Get(ttlHeader)
+	req.Header.Del(ttlHeader)
+	dump, err := httputil.DumpRequest(req, true)
+	if ttl != "" {
+		req.Header.Set(ttlHeader, ttl)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize request: %w", err)
+	}
+
+	sum := sha256.Sum256(dump)
+	return fmt.Sprintf("%s:%s:%s", httpCachePrefix, req.Header.Get(appHeader), hex.EncodeToString(sum[:])), nil
+}
+
+// readCachedResponse rebuilds a response from its serialized form, marking it
+// with the cache status when one is given
+func readCachedResponse(data []byte, req *http.Request, status string) (*http.Response, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached response: %w", err)
+	}
+	if status != "" {
+		resp.Header.Set(cacheStatusHeader, status)
+	}
+	return resp, nil
+}
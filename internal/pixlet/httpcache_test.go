@@ -0,0 +1,115 @@
+package pixlet
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func TestResponseTTL(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		status     int
+		reqHeader  http.Header
+		respHeader http.Header
+		want       time.Duration
+	}{
+		{"max-age", 200, nil, http.Header{"Cache-Control": {"public, max-age=60"}}, 60 * time.Second},
+		{"s-maxage wins over max-age", 200, nil, http.Header{"Cache-Control": {"max-age=60, s-maxage=120"}}, 120 * time.Second},
+		{"age is subtracted", 200, nil, http.Header{"Cache-Control": {"max-age=60"}, "Age": {"45"}}, 15 * time.Second},
+		{"expired by age", 200, nil, http.Header{"Cache-Control": {"max-age=60"}, "Age": {"90"}}, 0},
+		{"capped at an hour", 200, nil, http.Header{"Cache-Control": {"max-age=604800"}}, time.Hour},
+		{"expires relative to date", 200, nil, http.Header{
+			"Expires": {now.Add(10 * time.Minute).Format(http.TimeFormat)},
+			"Date":    {now.Format(http.TimeFormat)},
+		}, 10 * time.Minute},
+		{"no-store", 200, nil, http.Header{"Cache-Control": {"no-store"}}, 0},
+		{"no-cache", 200, nil, http.Header{"Cache-Control": {"no-cache, max-age=60"}}, 0},
+		{"private", 200, nil, http.Header{"Cache-Control": {"private, max-age=60"}}, 0},
+		{"no freshness info", 200, nil, http.Header{}, httpMinTTL},
+		{"app ttl wins", 200, http.Header{ttlHeader: {"300"}}, http.Header{"Cache-Control": {"no-store"}}, 300 * time.Second},
+		{"server error", 500, nil, http.Header{"Cache-Control": {"max-age=60"}}, 0},
+		{"rate limited", 429, nil, http.Header{"Retry-After": {"30"}}, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &http.Request{Method: http.MethodGet, Header: http.Header{}}
+			for k, v := range tt.reqHeader {
+				req.Header[k] = v
+			}
+			resp := &http.Response{StatusCode: tt.status, Header: tt.respHeader}
+			if got := responseTTL(req, resp, now); got != tt.want {
+				t.Errorf("responseTTL = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPCacheRoundTrip(t *testing.T) {
+	var hits atomic.Int32
+	cacheControl := "max-age=60"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Cache-Control", cacheControl)
+		io.WriteString(w, "forecast")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newHTTPCache(runtime.NewInMemoryCache(), http.DefaultTransport)}
+	get := func(path string) (string, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set(appHeader, "weather")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), resp.Header.Get(cacheStatusHeader)
+	}
+
+	t.Run("concurrent misses reach the upstream once", func(t *testing.T) {
+		hits.Store(0)
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if body, _ := get("/burst"); body != "forecast" {
+					t.Errorf("body = %q", body)
+				}
+			}()
+		}
+		wg.Wait()
+		if got := hits.Load(); got != 1 {
+			t.Errorf("upstream hits = %d, want 1", got)
+		}
+
+		if _, status := get("/burst"); status != "HIT" {
+			t.Errorf("cache status = %q, want HIT", status)
+		}
+	})
+
+	t.Run("no-store responses are not cached", func(t *testing.T) {
+		hits.Store(0)
+		cacheControl = "no-store"
+		defer func() { cacheControl = "max-age=60" }()
+
+		get("/live")
+		if _, status := get("/live"); status != "MISS" {
+			t.Errorf("cache status = %q, want MISS", status)
+		}
+		if got := hits.Load(); got != 2 {
+			t.Errorf("upstream hits = %d, want 2", got)
+		}
+	})
+}
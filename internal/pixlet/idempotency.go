@@ -0,0 +1,271 @@
+package pixlet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/auth"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ErrDuplicateRequest is returned, with the earlier result, for a request whose
+// UUID or idempotency key already rendered within the idempotency TTL
+var ErrDuplicateRequest = errors.New("duplicate render request")
+
+// ErrRequestInProgress is returned for a request whose UUID or idempotency key
+// is still rendering on this or another replica
+var ErrRequestInProgress = errors.New("render request is already in progress")
+
+// ErrIdempotencyKeyMismatch is returned for a request reusing an idempotency key
+// or UUID with a different body than the request that first used it
+var ErrIdempotencyKeyMismatch = errors.New("idempotency key was used with a different request")
+
+// processedMaxEntries bounds the in-memory tier; Redis holds the overflow
+const processedMaxEntries = 16384
+
+// processedRequests remembers recently rendered requests, by UUID or idempotency
+// key, so a queue redelivery or a client retry reuses their result instead of
+// rendering and reaching the device twice. Records are small: the output itself
+// lives in the render cache, see Processor.RenderAppOnce. They live in memory
+// and, when configured, in Redis so a retry reaching another replica is caught
+// too.
+type processedRequests struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]processedEntry
+	redis   *RedisCache // optional shared tier
+}
+
+type processedEntry struct {
+	record  processedRecord
+	expires time.Time
+}
+
+// processedRecord is what a request's key holds: a claim while it renders, then
+// its result without the output, which is kept under OutputKey in the render cache
+type processedRecord struct {
+	BodyHash  string              `json:"body_hash"`
+	Pending   bool                `json:"pending,omitempty"`
+	Result    models.RenderResult `json:"result"`
+	OutputKey string              `json:"output_key,omitempty"`
+}
+
+// newProcessedRequests returns a store keeping records for ttl, or nil if ttl
+// disables it
+func newProcessedRequests(ttl time.Duration, redisCache *RedisCache) *processedRequests {
+	if ttl <= 0 {
+		return nil
+	}
+	return &processedRequests{
+		ttl:     ttl,
+		entries: make(map[string]processedEntry),
+		redis:   redisCache,
+	}
+}
+
+// claim marks key as rendering for a request with bodyHash, in memory and with
+// SET NX in Redis. It returns a nil record when the caller now holds the claim
+// and should render, or the finished record of an earlier request with the same
+// body. A claim still held by another request fails with ErrRequestInProgress,
+// and a key recorded for a different body with ErrIdempotencyKeyMismatch. Redis
+// errors are ignored: at worst a retry reaching another replica renders again.
+func (s *processedRequests) claim(ctx context.Context, key, bodyHash string) (*processedRecord, error) {
+	pending := processedRecord{BodyHash: bodyHash, Pending: true}
+
+	s.mu.Lock()
+	if entry, ok := s.entries[key]; ok && time.Now().Before(entry.expires) {
+		s.mu.Unlock()
+		return entry.record.check(bodyHash)
+	}
+	s.setLocal(key, pending)
+	s.mu.Unlock()
+
+	if s.redis == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return nil, nil
+	}
+	claimed, err := s.redis.client.SetNX(ctx, key, data, s.ttl).Result()
+	if err != nil || claimed {
+		return nil, nil
+	}
+
+	// Another replica holds the key; its record decides
+	s.forgetLocal(key)
+	data, err = s.redis.client.Get(ctx, key).Bytes()
+	if err != nil {
+		// It expired or was released in between, so the client can retry
+		return nil, ErrRequestInProgress
+	}
+	var record processedRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, ErrRequestInProgress
+	}
+	return record.check(bodyHash)
+}
+
+// check returns the record if a request with bodyHash may reuse it
+func (r processedRecord) check(bodyHash string) (*processedRecord, error) {
+	switch {
+	case r.BodyHash != bodyHash:
+		return nil, ErrIdempotencyKeyMismatch
+	case r.Pending:
+		return nil, ErrRequestInProgress
+	}
+	return &r, nil
+}
+
+// complete replaces the claim on key with the finished record in every tier
+func (s *processedRequests) complete(ctx context.Context, key string, record processedRecord) {
+	s.mu.Lock()
+	s.setLocal(key, record)
+	s.mu.Unlock()
+
+	if s.redis == nil {
+		return
+	}
+	if data, err := json.Marshal(record); err == nil {
+		s.redis.client.Set(ctx, key, data, s.ttl)
+	}
+}
+
+// release drops the claim on key in every tier, so a retry renders again
+func (s *processedRequests) release(ctx context.Context, key string) {
+	s.forgetLocal(key)
+	if s.redis != nil {
+		s.redis.client.Del(ctx, key)
+	}
+}
+
+func (s *processedRequests) forgetLocal(key string) {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+}
+
+// setLocal stores a record in memory, sweeping expired entries when full and
+// skipping the write if the store is still full afterwards. Callers hold s.mu.
+func (s *processedRequests) setLocal(key string, record processedRecord) {
+	if _, exists := s.entries[key]; !exists && len(s.entries) >= processedMaxEntries {
+		now := time.Now()
+		for k, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, k)
+			}
+		}
+		if len(s.entries) >= processedMaxEntries {
+			return
+		}
+	}
+	s.entries[key] = processedEntry{record: record, expires: time.Now().Add(s.ttl)}
+}
+
+// processedKey returns the key a request is remembered under: its idempotency
+// key, scoped to the app, the device and the authenticated caller so clients
+// can't collide or read each other's results, or else its UUID. It is empty
+// for a request with neither.
+func processedKey(ctx context.Context, request *models.RenderRequest) string {
+	switch {
+	case request.IdempotencyKey != "":
+		principal, _ := auth.PrincipalFromContext(ctx)
+		h := sha256.New()
+		fmt.Fprintf(h, "device=%q\nsubject=%q\nkey=%q\n", request.Device.ID, principal.Subject, request.IdempotencyKey)
+		return "matrx:idempotency:" + request.AppID + ":" + hex.EncodeToString(h.Sum(nil))
+	case request.UUID != "":
+		return "matrx:processed:" + request.UUID
+	}
+	return ""
+}
+
+// requestBodyHash hashes what a request asks to render: its config, size and
+// render options, including encoder overrides
+func requestBodyHash(request *models.RenderRequest) string {
+	job := &RenderJob{AppID: request.AppID, Params: request.Params, Device: request.Device, Options: renderOptionsFor(request)}
+	encoding, _ := json.Marshal(request.Encoding)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "job=%s\nencoding=%s\n", jobKey(job, false), encoding)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RenderAppOnce renders a request like RenderApp, unless a request with the same
+// idempotency key, or without one the same UUID, already rendered within
+// PIXLET_REQUEST_IDEMPOTENCY_TTL. Then it returns that result with
+// ErrDuplicateRequest, so the caller can acknowledge a redelivery or answer a
+// retry without pushing it to the device again. While the first request renders,
+// repeats fail with ErrRequestInProgress; a repeat with a different body fails
+// with ErrIdempotencyKeyMismatch. Failed renders aren't remembered, so retrying
+// them renders again.
+func (p *Processor) RenderAppOnce(ctx context.Context, request *models.RenderRequest) (*models.RenderResult, error) {
+	key := processedKey(ctx, request)
+	if p.processed == nil || key == "" {
+		return p.RenderApp(ctx, request)
+	}
+	bodyHash := requestBodyHash(request)
+
+	record, err := p.processed.claim(ctx, key, bodyHash)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		if result, ok := p.replayProcessed(ctx, record); ok {
+			return result, ErrDuplicateRequest
+		}
+		// The output was evicted from the render cache, so render it again
+	}
+
+	result, err := p.RenderApp(ctx, request)
+	if err != nil || result == nil || result.Error {
+		p.processed.release(ctx, key)
+		return result, err
+	}
+	p.recordProcessed(ctx, key, bodyHash, result)
+	return result, err
+}
+
+// recordProcessed stores a finished render under key: its output in the render
+// cache, which bounds memory and shares it through Redis, and the rest of the
+// result in the processed record
+func (p *Processor) recordProcessed(ctx context.Context, key, bodyHash string, result *models.RenderResult) {
+	record := processedRecord{BodyHash: bodyHash, Result: *result}
+	record.Result.RenderOutput = ""
+	if result.RenderOutput != "" {
+		data, err := base64.StdEncoding.DecodeString(result.RenderOutput)
+		if err != nil {
+			p.processed.release(ctx, key)
+			return
+		}
+		record.OutputKey = key + ":output"
+		if err := p.renderCache.Set(ctx, record.OutputKey, data, p.processed.ttl); err != nil {
+			p.logger.Warn("Failed to store idempotent render output",
+				zap.String("app_id", result.AppID),
+				zap.Error(err))
+		}
+	}
+	p.processed.complete(ctx, key, record)
+}
+
+// replayProcessed rebuilds a recorded result, fetching its output from the
+// render cache. It reports false when the output is gone.
+func (p *Processor) replayProcessed(ctx context.Context, record *processedRecord) (*models.RenderResult, bool) {
+	result := record.Result
+	if record.OutputKey == "" {
+		return &result, true
+	}
+	data, ok := p.renderCache.Get(ctx, record.OutputKey)
+	if !ok {
+		return nil, false
+	}
+	result.RenderOutput = base64.StdEncoding.EncodeToString(data)
+	return &result, true
+}
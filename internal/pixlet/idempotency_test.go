@@ -0,0 +1,164 @@
+package pixlet
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/auth"
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestProcessedRequests(t *testing.T) {
+	ctx := context.Background()
+	if newProcessedRequests(0, nil) != nil {
+		t.Error("A zero TTL should disable the store")
+	}
+
+	store := newProcessedRequests(time.Minute, nil)
+	if record, err := store.claim(ctx, "u1", "body"); record != nil || err != nil {
+		t.Fatalf("claim = %+v, %v; want the claim for an unknown key", record, err)
+	}
+	// Repeats wait for the first request, unless their body differs
+	if _, err := store.claim(ctx, "u1", "body"); !errors.Is(err, ErrRequestInProgress) {
+		t.Errorf("Expected ErrRequestInProgress while claimed, got %v", err)
+	}
+	if _, err := store.claim(ctx, "u1", "other"); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+		t.Errorf("Expected ErrIdempotencyKeyMismatch for another body, got %v", err)
+	}
+
+	store.complete(ctx, "u1", processedRecord{BodyHash: "body", Result: models.RenderResult{UUID: "u1"}, OutputKey: "u1:output"})
+	record, err := store.claim(ctx, "u1", "body")
+	if err != nil || record == nil || record.Result.UUID != "u1" || record.OutputKey != "u1:output" {
+		t.Fatalf("claim = %+v, %v; want the completed record", record, err)
+	}
+	if _, err := store.claim(ctx, "u1", "other"); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+		t.Errorf("Expected ErrIdempotencyKeyMismatch for another body, got %v", err)
+	}
+
+	// Released keys can be claimed again
+	store.release(ctx, "u1")
+	if record, err := store.claim(ctx, "u1", "other"); record != nil || err != nil {
+		t.Errorf("claim = %+v, %v; want a new claim after release", record, err)
+	}
+
+	store.ttl = -time.Second
+	store.complete(ctx, "expired", processedRecord{BodyHash: "body"})
+	store.ttl = time.Minute
+	if record, err := store.claim(ctx, "expired", "body"); record != nil || err != nil {
+		t.Errorf("claim = %+v, %v; want a new claim for an expired key", record, err)
+	}
+}
+
+func TestRenderAppOnce_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	processor := NewProcessor(&config.PixletConfig{AppsPath: t.TempDir(), RequestIdempotencyTTL: 60}, zap.NewNop())
+	defer processor.Stop()
+
+	// A request that already rendered is answered from the store without
+	// reaching the registry or the pool
+	request := &models.RenderRequest{UUID: "u1", AppID: "gone"}
+	key, bodyHash := processedKey(ctx, request), requestBodyHash(request)
+	output := base64.StdEncoding.EncodeToString([]byte("earlier"))
+	processor.processed.claim(ctx, key, bodyHash)
+	processor.recordProcessed(ctx, key, bodyHash, &models.RenderResult{UUID: "u1", AppID: "gone", RenderOutput: output})
+	if entry := processor.processed.entries[key]; entry.record.Result.RenderOutput != "" || entry.record.OutputKey == "" {
+		t.Errorf("Expected the output to be kept in the render cache, got %+v", entry.record)
+	}
+	result, err := processor.RenderAppOnce(ctx, request)
+	if !errors.Is(err, ErrDuplicateRequest) {
+		t.Fatalf("Expected ErrDuplicateRequest, got %v", err)
+	}
+	if result.RenderOutput != output {
+		t.Errorf("Expected the earlier result, got %+v", result)
+	}
+
+	// Reusing the UUID for another body is refused
+	if _, err := processor.RenderAppOnce(ctx, &models.RenderRequest{UUID: "u1", AppID: "gone", Params: map[string]interface{}{"a": "b"}}); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+		t.Errorf("Expected ErrIdempotencyKeyMismatch, got %v", err)
+	}
+
+	// A repeat of a request still rendering is refused
+	inFlight := &models.RenderRequest{UUID: "u3", AppID: "gone"}
+	processor.processed.claim(ctx, processedKey(ctx, inFlight), requestBodyHash(inFlight))
+	if _, err := processor.RenderAppOnce(ctx, inFlight); !errors.Is(err, ErrRequestInProgress) {
+		t.Errorf("Expected ErrRequestInProgress, got %v", err)
+	}
+
+	// Failures aren't remembered, so redeliveries retry them
+	request = &models.RenderRequest{UUID: "u2", AppID: "missing", Device: models.Device{ID: "d1"}}
+	if _, err := processor.RenderAppOnce(ctx, request); err == nil || errors.Is(err, ErrDuplicateRequest) {
+		t.Fatalf("Expected a render error, got %v", err)
+	}
+	if _, err := processor.RenderAppOnce(ctx, request); err == nil || errors.Is(err, ErrDuplicateRequest) || errors.Is(err, ErrRequestInProgress) {
+		t.Errorf("A failed render should release its claim, got %v", err)
+	}
+
+	// A record whose output left the render cache renders again
+	evicted := &models.RenderRequest{UUID: "u4", AppID: "missing"}
+	key = processedKey(ctx, evicted)
+	processor.processed.complete(ctx, key, processedRecord{BodyHash: requestBodyHash(evicted), OutputKey: key + ":output"})
+	if _, err := processor.RenderAppOnce(ctx, evicted); err == nil || errors.Is(err, ErrDuplicateRequest) {
+		t.Errorf("Expected a render error once the output is gone, got %v", err)
+	}
+}
+
+func TestProcessedKey(t *testing.T) {
+	ctx := context.Background()
+	alice := auth.WithPrincipal(ctx, auth.Principal{Subject: "alice"})
+	bob := auth.WithPrincipal(ctx, auth.Principal{Subject: "bob"})
+	keyed := func(appID, deviceID string) *models.RenderRequest {
+		return &models.RenderRequest{UUID: "u1", AppID: appID, Device: models.Device{ID: deviceID}, IdempotencyKey: "k1"}
+	}
+
+	if got := processedKey(ctx, &models.RenderRequest{UUID: "u1", AppID: "clock"}); got != "matrx:processed:u1" {
+		t.Errorf("Expected the UUID key, got %q", got)
+	}
+	if got := processedKey(ctx, &models.RenderRequest{AppID: "clock"}); got != "" {
+		t.Errorf("Expected no key without a UUID or idempotency key, got %q", got)
+	}
+
+	// The idempotency key wins, scoped to the app, device and caller
+	key := processedKey(alice, keyed("clock", "d1"))
+	if key == processedKey(alice, &models.RenderRequest{UUID: "u1", AppID: "clock"}) || key != processedKey(alice, keyed("clock", "d1")) {
+		t.Errorf("Expected a stable idempotency key, got %q", key)
+	}
+	for name, other := range map[string]string{
+		"app":       processedKey(alice, keyed("weather", "d1")),
+		"device":    processedKey(alice, keyed("clock", "d2")),
+		"principal": processedKey(bob, keyed("clock", "d1")),
+		"anonymous": processedKey(ctx, keyed("clock", "d1")),
+	} {
+		if other == key {
+			t.Errorf("Expected another %s to get another key", name)
+		}
+	}
+}
+
+func TestRequestBodyHash(t *testing.T) {
+	base := models.RenderRequest{AppID: "clock", Device: models.Device{ID: "d1", Width: 64, Height: 32}, Params: map[string]interface{}{"tz": "UTC"}}
+	hash := requestBodyHash(&base)
+
+	// The request's own ID and key don't change its body
+	same := base
+	same.UUID, same.IdempotencyKey = "u2", "k2"
+	if requestBodyHash(&same) != hash {
+		t.Error("Expected the UUID and idempotency key to be left out of the body hash")
+	}
+
+	params := base
+	params.Params = map[string]interface{}{"tz": "Europe/Paris"}
+	size := base
+	size.Device.Width = 128
+	encoding := base
+	encoding.Encoding = &models.EncodingOptions{}
+	for name, request := range map[string]*models.RenderRequest{"params": &params, "size": &size, "encoding": &encoding} {
+		if requestBodyHash(request) == hash {
+			t.Errorf("Expected different %s to change the body hash", name)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package pixlet
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+
+	"tidbyt.dev/pixlet/runtime"
+	"tidbyt.dev/pixlet/tools"
+)
+
+const (
+	maxBundleFiles = 1000     // Files an app bundle may contain
+	maxBundleBytes = 64 << 20 // Total uncompressed size of an app bundle
+)
+
+var (
+	// ErrInvalidBundle is returned for uploads that aren't a readable zip or
+	// tar.gz app bundle with a manifest.yaml
+	ErrInvalidBundle = errors.New("invalid app bundle")
+
+	// ErrInvalidApp is returned when a bundle's manifest is invalid, or its app
+	// fails to load or render
+	ErrInvalidApp = errors.New("invalid app")
+
+	// ErrAppExists is returned when installing an app that is already
+	// installed without asking to replace it
+	ErrAppExists = errors.New("app already installed")
+
+	// ErrAppNotFound is returned for an app that isn't installed
+	ErrAppNotFound = errors.New("app not found")
+
+	// ErrDestructiveDisabled is returned for deletes and replacing installs
+	// when PIXLET_ALLOW_DESTRUCTIVE is off
+	ErrDestructiveDisabled = errors.New("deleting and replacing apps is disabled")
+)
+
+// InstallOptions control how an app bundle is installed
+type InstallOptions struct {
+	Replace    bool // Replace an installed app with the same ID
+	TestRender bool // Run the app once with an empty config before installing
+}
+
+// InstallApp validates a zip or tar.gz app bundle and installs it into the apps
+// path, returning the installed manifest. The bundle holds manifest.yaml and the
+// app's .star source, at its root or inside a single top-level directory. The
+// app is unpacked next to the installed apps and moved into place with a rename,
+// so renders never see a partly written app.
+func (p *Processor) InstallApp(ctx context.Context, bundle []byte, opts InstallOptions) (*models.AppManifest, error) {
+	if opts.Replace && !p.config.AllowDestructive {
+		return nil, ErrDestructiveDisabled
+	}
+
+	p.installMu.Lock()
+	defer p.installMu.Unlock()
+
+	// Staging under the apps path keeps the final rename on one filesystem. The
+	// manifest sits at least two levels down, so registry scans never pick up a
+	// half-extracted app.
+	staging, err := os.MkdirTemp(p.config.AppsPath, ".install-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	extracted := filepath.Join(staging, "bundle")
+	if err := extractBundle(bundle, extracted); err != nil {
+		return nil, err
+	}
+
+	appDir, err := bundleRoot(extracted)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := models.LoadManifest(appDir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidApp, err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidApp, err)
+	}
+
+	applet, err := p.loadBundledApplet(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to load applet: %v", ErrInvalidApp, err)
+	}
+	if opts.TestRender {
+		size := p.DefaultDeviceSize()
+		if _, err := p.workerPool.runApplet(applet, "", map[string]interface{}{}, size.Width, size.Height); err != nil {
+			return nil, fmt.Errorf("%w: test render failed: %v", ErrInvalidApp, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	target := filepath.Join(p.config.AppsPath, manifest.ID)
+	if existing, ok := p.appRegistry.GetApp(manifest.ID); ok {
+		if !opts.Replace {
+			return nil, fmt.Errorf("%w: %s", ErrAppExists, manifest.ID)
+		}
+		target = existing.DirectoryPath
+	}
+
+	if err := replaceDir(appDir, target, filepath.Join(staging, "previous"), opts.Replace); err != nil {
+		return nil, err
+	}
+
+	p.logger.Info("Installed app",
+		zap.String("app_id", manifest.ID),
+		zap.String("path", target),
+		zap.Bool("replace", opts.Replace))
+	p.flushAppCaches(manifest.ID)
+
+	if err := p.RefreshAppRegistry(); err != nil {
+		return nil, err
+	}
+	p.announceAppsUpdated()
+	installed, ok := p.appRegistry.GetApp(manifest.ID)
+	if !ok {
+		return nil, fmt.Errorf("installed app %s did not load", manifest.ID)
+	}
+	return installed, nil
+}
+
+// DeleteApp removes an installed app's directory and refreshes the registry.
+// The directory is first renamed out of the registry's view, so a concurrent
+// refresh never loads a half-deleted app.
+func (p *Processor) DeleteApp(appID string) error {
+	if !p.config.AllowDestructive {
+		return ErrDestructiveDisabled
+	}
+
+	p.installMu.Lock()
+	defer p.installMu.Unlock()
+
+	app, ok := p.appRegistry.GetApp(appID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAppNotFound, appID)
+	}
+
+	// Never remove the apps path itself, or anything outside it
+	rel, err := filepath.Rel(p.config.AppsPath, app.DirectoryPath)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return fmt.Errorf("app %s is not in its own directory under the apps path", appID)
+	}
+
+	staging, err := os.MkdirTemp(p.config.AppsPath, ".delete-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := os.Rename(app.DirectoryPath, filepath.Join(staging, "app")); err != nil {
+		return fmt.Errorf("failed to remove app: %w", err)
+	}
+
+	p.logger.Info("Deleted app",
+		zap.String("app_id", appID),
+		zap.String("path", app.DirectoryPath))
+	p.flushAppCaches(appID)
+
+	if err := p.RefreshAppRegistry(); err != nil {
+		return err
+	}
+	p.announceAppsUpdated()
+	return nil
+}
+
+// appFlushTimeout bounds flushing an app's keys from Redis
+const appFlushTimeout = 5 * time.Second
+
+// flushAppCaches drops an app's cached output and applet cache entries once it
+// is replaced or deleted. Other replicas' in-memory tiers expire on their TTL.
+func (p *Processor) flushAppCaches(appID string) {
+	if p.redisCache == nil {
+		p.renderCache.forgetApp(appID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), appFlushTimeout)
+	defer cancel()
+	deleted, err := p.FlushAppCache(ctx, appID)
+	if err != nil {
+		p.logger.Warn("Failed to flush app from Redis cache", zap.String("app_id", appID), zap.Error(err))
+		return
+	}
+	p.logger.Debug("Flushed app from Redis cache", zap.String("app_id", appID), zap.Int64("keys", deleted))
+}
+
+// loadBundledApplet loads a staged app the way workers load installed ones
+func (p *Processor) loadBundledApplet(manifest *models.AppManifest) (*runtime.Applet, error) {
+	var appFS fs.FS
+	info, err := os.Stat(manifest.StarFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		appFS = os.DirFS(manifest.StarFilePath)
+	} else {
+		if !strings.HasSuffix(manifest.StarFilePath, ".star") {
+			return nil, fmt.Errorf("app file must have suffix .star: %s", manifest.FileName)
+		}
+		appFS = tools.NewSingleFileFS(manifest.StarFilePath)
+	}
+	return runtime.NewAppletFromFS(manifest.ID, appFS, p.appletOptions()...)
+}
+
+// replaceDir moves src to target. An existing target is only replaced when
+// replace is set; it is first moved to backup and restored if the move fails.
+func replaceDir(src, target, backup string, replace bool) error {
+	if _, err := os.Lstat(target); err == nil {
+		if !replace {
+			return fmt.Errorf("%w: %s already exists", ErrAppExists, filepath.Base(target))
+		}
+		if err := os.Rename(target, backup); err != nil {
+			return fmt.Errorf("failed to move installed app aside: %w", err)
+		}
+		if err := os.Rename(src, target); err != nil {
+			os.Rename(backup, target)
+			return fmt.Errorf("failed to install app: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.Rename(src, target); err != nil {
+		return fmt.Errorf("failed to install app: %w", err)
+	}
+	return nil
+}
+
+// bundleRoot returns the directory holding the bundle's manifest.yaml: the
+// bundle root or its only top-level directory
+func bundleRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "manifest.yaml")); err == nil {
+		return dir, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		nested := filepath.Join(dir, entries[0].Name())
+		if _, err := os.Stat(filepath.Join(nested, "manifest.yaml")); err == nil {
+			return nested, nil
+		}
+	}
+	return "", fmt.Errorf("%w: manifest.yaml not found", ErrInvalidBundle)
+}
+
+// extractBundle unpacks a zip or tar.gz bundle, told apart by their magic
+// bytes, into dest
+func extractBundle(bundle []byte, dest string) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(bundle, []byte("PK\x03\x04")), bytes.HasPrefix(bundle, []byte("PK\x05\x06")):
+		return extractZip(bundle, dest)
+	case bytes.HasPrefix(bundle, []byte{0x1f, 0x8b}):
+		return extractTarGz(bundle, dest)
+	}
+	return fmt.Errorf("%w: expected a zip or tar.gz archive", ErrInvalidBundle)
+}
+
+func extractZip(bundle []byte, dest string) error {
+	reader, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	limits := &bundleLimits{}
+	for _, file := range reader.File {
+		mode := file.Mode()
+		if !mode.IsRegular() && !mode.IsDir() {
+			return fmt.Errorf("%w: %s is not a regular file", ErrInvalidBundle, file.Name)
+		}
+
+		target, skip, err := bundlePath(dest, file.Name)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+		if mode.IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		err = limits.write(target, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTarGz(bundle []byte, dest string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	defer gz.Close()
+
+	limits := &bundleLimits{}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeDir:
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			return fmt.Errorf("%w: %s is not a regular file", ErrInvalidBundle, header.Name)
+		}
+
+		target, skip, err := bundlePath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+		if header.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := limits.write(target, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// bundlePath maps an archive entry name to its path under dest, rejecting
+// names that would escape it. Archive metadata such as macOS __MACOSX folders
+// is skipped.
+func bundlePath(dest, name string) (string, bool, error) {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	if name == "" {
+		return "", true, nil
+	}
+	if name == "__MACOSX" || strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "._") {
+		return "", true, nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", false, fmt.Errorf("%w: invalid path %s", ErrInvalidBundle, name)
+	}
+	return filepath.Join(dest, filepath.FromSlash(name)), false, nil
+}
+
+// bundleLimits caps the files and bytes written while extracting a bundle, so
+// a small upload can't expand to fill the disk
+type bundleLimits struct {
+	files int
+	bytes int64
+}
+
+// write copies r to a new file at target within the limits
+func (l *bundleLimits) write(target string, r io.Reader) error {
+	l.files++
+	if l.files > maxBundleFiles {
+		return fmt.Errorf("%w: more than %d files", ErrInvalidBundle, maxBundleFiles)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	defer file.Close()
+
+	n, err := io.Copy(file, io.LimitReader(r, maxBundleBytes-l.bytes+1))
+	l.bytes += n
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if l.bytes > maxBundleBytes {
+		return fmt.Errorf("%w: larger than %d MB uncompressed", ErrInvalidBundle, maxBundleBytes>>20)
+	}
+	return file.Close()
+}
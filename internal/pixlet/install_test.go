@@ -0,0 +1,256 @@
+package pixlet
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+)
+
+const installTestApp = `
+load("render.star", "render")
+
+def main(config):
+    return render.Root(child=render.Text("Installed"))
+`
+
+func installTestManifest(id string) string {
+	return "id: " + id + "\nname: " + id + "\nsummary: test\ndesc: test\nauthor: test\nfileName: " + id + ".star\npackageName: apps." + id + "\n"
+}
+
+func zipBundle(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzBundle(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to write tar: %v", err)
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestInstallApp(t *testing.T) {
+	tempDir := t.TempDir()
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, AllowDestructive: true}, zap.NewNop())
+
+	bundle := zipBundle(t, map[string]string{
+		"new-app/manifest.yaml":   installTestManifest("new-app"),
+		"new-app/new-app.star":    installTestApp,
+		"__MACOSX/new-app/._junk": "metadata",
+	})
+	manifest, err := processor.InstallApp(context.Background(), bundle, InstallOptions{})
+	if err != nil {
+		t.Fatalf("InstallApp failed: %v", err)
+	}
+	if manifest.ID != "new-app" || manifest.DirectoryPath != filepath.Join(tempDir, "new-app") {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+	if _, ok := processor.GetAppRegistry().GetApp("new-app"); !ok {
+		t.Error("Installed app not in the registry")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "__MACOSX")); !os.IsNotExist(err) {
+		t.Error("Archive metadata should not be installed")
+	}
+
+	// Staging directories are cleaned up
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the installed app in the apps path, got %d entries", len(entries))
+	}
+
+	if _, err := processor.InstallApp(context.Background(), bundle, InstallOptions{}); !errors.Is(err, ErrAppExists) {
+		t.Errorf("Expected ErrAppExists, got %v", err)
+	}
+
+	replacement := tarGzBundle(t, map[string]string{
+		"manifest.yaml": strings.Replace(installTestManifest("new-app"), "name: new-app", "name: Replaced", 1),
+		"new-app.star":  installTestApp,
+	})
+	manifest, err = processor.InstallApp(context.Background(), replacement, InstallOptions{Replace: true})
+	if err != nil {
+		t.Fatalf("Replacing app failed: %v", err)
+	}
+	if manifest.Name != "Replaced" {
+		t.Errorf("Expected the replaced manifest, got name %q", manifest.Name)
+	}
+}
+
+func TestInstallApp_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		bundle func(t *testing.T) []byte
+		want   error
+	}{
+		{"not an archive", func(t *testing.T) []byte { return []byte("hello") }, ErrInvalidBundle},
+		{"no manifest", func(t *testing.T) []byte {
+			return zipBundle(t, map[string]string{"app.star": installTestApp})
+		}, ErrInvalidBundle},
+		{"path traversal", func(t *testing.T) []byte {
+			return tarGzBundle(t, map[string]string{"../escape/manifest.yaml": installTestManifest("escape")})
+		}, nil},
+		{"invalid id", func(t *testing.T) []byte {
+			return zipBundle(t, map[string]string{
+				"manifest.yaml": installTestManifest("bad id"),
+				"bad id.star":   installTestApp,
+			})
+		}, ErrInvalidApp},
+		{"broken applet", func(t *testing.T) []byte {
+			return zipBundle(t, map[string]string{
+				"manifest.yaml": installTestManifest("broken"),
+				"broken.star":   "def main(:\n",
+			})
+		}, ErrInvalidApp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir}, zap.NewNop())
+
+			_, err := processor.InstallApp(context.Background(), tt.bundle(t), InstallOptions{})
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+			if len(processor.GetAppRegistry().GetAllApps()) != 0 {
+				t.Error("No app should be installed")
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(tempDir), "escape")); !os.IsNotExist(err) {
+				t.Error("Bundle escaped the apps path")
+			}
+		})
+	}
+}
+
+func TestDeleteApp(t *testing.T) {
+	tempDir := t.TempDir()
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, AllowDestructive: true}, zap.NewNop())
+
+	bundle := zipBundle(t, map[string]string{
+		"manifest.yaml": installTestManifest("doomed"),
+		"doomed.star":   installTestApp,
+	})
+	if _, err := processor.InstallApp(context.Background(), bundle, InstallOptions{}); err != nil {
+		t.Fatalf("InstallApp failed: %v", err)
+	}
+
+	if err := processor.DeleteApp("doomed"); err != nil {
+		t.Fatalf("DeleteApp failed: %v", err)
+	}
+	if _, ok := processor.GetAppRegistry().GetApp("doomed"); ok {
+		t.Error("Deleted app still in the registry")
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("Expected an empty apps path, got %d entries", len(entries))
+	}
+
+	if err := processor.DeleteApp("doomed"); !errors.Is(err, ErrAppNotFound) {
+		t.Errorf("Expected ErrAppNotFound, got %v", err)
+	}
+}
+
+func TestDestructiveDisabled(t *testing.T) {
+	tempDir := t.TempDir()
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir}, zap.NewNop())
+
+	bundle := zipBundle(t, map[string]string{
+		"manifest.yaml": installTestManifest("kept"),
+		"kept.star":     installTestApp,
+	})
+	if _, err := processor.InstallApp(context.Background(), bundle, InstallOptions{}); err != nil {
+		t.Fatalf("Installing a new app should be allowed: %v", err)
+	}
+	if _, err := processor.InstallApp(context.Background(), bundle, InstallOptions{Replace: true}); !errors.Is(err, ErrDestructiveDisabled) {
+		t.Errorf("Expected ErrDestructiveDisabled replacing, got %v", err)
+	}
+	if err := processor.DeleteApp("kept"); !errors.Is(err, ErrDestructiveDisabled) {
+		t.Errorf("Expected ErrDestructiveDisabled deleting, got %v", err)
+	}
+	if _, ok := processor.GetAppRegistry().GetApp("kept"); !ok {
+		t.Error("App should still be installed")
+	}
+}
+
+func TestBundlePath(t *testing.T) {
+	dest := "/apps/staging"
+	tests := []struct {
+		name string
+		want string
+		skip bool
+	}{
+		{"app/manifest.yaml", filepath.Join(dest, "app", "manifest.yaml"), false},
+		{"app\\main.star", filepath.Join(dest, "app", "main.star"), false},
+		{"../../etc/passwd", filepath.Join(dest, "etc", "passwd"), false},
+		{"/abs/file", filepath.Join(dest, "abs", "file"), false},
+		{"__MACOSX/app/file", "", true},
+		{"app/._main.star", "", true},
+		{"./", "", true},
+	}
+	for _, tt := range tests {
+		got, skip, err := bundlePath(dest, tt.name)
+		if err != nil {
+			t.Errorf("bundlePath(%q) error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want || skip != tt.skip {
+			t.Errorf("bundlePath(%q) = %q, %v; want %q, %v", tt.name, got, skip, tt.want, tt.skip)
+		}
+	}
+}
+
+func TestBundleLimits(t *testing.T) {
+	dir := t.TempDir()
+	limits := &bundleLimits{bytes: maxBundleBytes - 4}
+	if err := limits.write(filepath.Join(dir, "ok"), strings.NewReader("1234")); err != nil {
+		t.Fatalf("Write within limit failed: %v", err)
+	}
+	if err := limits.write(filepath.Join(dir, "big"), strings.NewReader("5")); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("Expected ErrInvalidBundle past the byte limit, got %v", err)
+	}
+
+	limits = &bundleLimits{files: maxBundleFiles}
+	if err := limits.write(filepath.Join(dir, "extra"), strings.NewReader("")); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("Expected ErrInvalidBundle past the file limit, got %v", err)
+	}
+
+	// Duplicate entries can't overwrite a file already extracted
+	limits = &bundleLimits{}
+	if err := limits.write(filepath.Join(dir, "ok"), strings.NewReader("x")); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("Expected ErrInvalidBundle for a duplicate entry, got %v", err)
+	}
+}
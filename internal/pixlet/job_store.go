@@ -0,0 +1,67 @@
+package pixlet
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// jobStoreTimeout bounds one job store command, so a slow Redis falls back to
+// the replica's own jobs instead of stalling the request
+const jobStoreTimeout = time.Second
+
+// JobStore keeps asynchronous render jobs in Redis, so any replica behind a load
+// balancer can answer for a job another one accepted. Jobs are opaque to it;
+// each expires after the TTL it was last written with.
+type JobStore struct {
+	client redis.UniversalClient
+}
+
+// newJobStore returns a store over redisCache, or nil without Redis
+func newJobStore(redisCache *RedisCache) *JobStore {
+	if redisCache == nil {
+		return nil
+	}
+	return &JobStore{client: redisCache.client}
+}
+
+func jobStoreKey(id string) string {
+	return "matrx:render-job:" + id
+}
+
+// Put stores job id, replacing it if it exists
+func (s *JobStore) Put(ctx context.Context, id string, job []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, jobStoreTimeout)
+	defer cancel()
+	return s.client.Set(ctx, jobStoreKey(id), job, ttl).Err()
+}
+
+// Update replaces job id only if it still exists, so a job deleted meanwhile
+// stays deleted. It reports whether the job was there.
+func (s *JobStore) Update(ctx context.Context, id string, job []byte, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, jobStoreTimeout)
+	defer cancel()
+	return s.client.SetXX(ctx, jobStoreKey(id), job, ttl).Result()
+}
+
+// Get returns job id, or false if it doesn't exist or has expired
+func (s *JobStore) Get(ctx context.Context, id string) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, jobStoreTimeout)
+	defer cancel()
+	job, err := s.client.Get(ctx, jobStoreKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return job, true, nil
+}
+
+// Delete removes job id
+func (s *JobStore) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, jobStoreTimeout)
+	defer cancel()
+	return s.client.Del(ctx, jobStoreKey(id)).Err()
+}
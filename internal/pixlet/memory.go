@@ -0,0 +1,61 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"runtime/metrics"
+	"time"
+)
+
+// ErrHeapGrowthExceeded is returned when the process heap grows past
+// PIXLET_MAX_HEAP_GROWTH_MB while a render runs
+var ErrHeapGrowthExceeded = errors.New("process heap grew past limit during render")
+
+// heapCheckInterval is how often the heap is sampled while a render runs
+const heapCheckInterval = 20 * time.Millisecond
+
+// heapObjectsMetric is live plus not-yet-swept heap memory. Unlike
+// runtime.ReadMemStats, reading it does not stop the world.
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// heapInUse returns the bytes currently occupied by heap objects
+func heapInUse() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// watchHeapGrowth samples the process heap until stop is called and cancels the
+// render with ErrHeapGrowthExceeded once it has grown more than limit bytes past
+// where it was when the render started. This is a process-level guard, not a
+// per-render budget: Go cannot attribute allocations to a goroutine, so growth
+// from renders running alongside counts too, and every render running while the
+// heap balloons is aborted, not only the one that caused it.
+func watchHeapGrowth(ctx context.Context, cancel context.CancelCauseFunc, limit uint64) (stop func()) {
+	done := make(chan struct{})
+	baseline := heapInUse()
+
+	go func() {
+		ticker := time.NewTicker(heapCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if used := heapInUse(); used > baseline && used-baseline > limit {
+					metricHeapGrowthAborts.Inc()
+					cancel(ErrHeapGrowthExceeded)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
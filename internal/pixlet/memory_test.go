@@ -0,0 +1,48 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchHeapGrowth(t *testing.T) {
+	t.Run("cancels when the heap grows past the limit", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+
+		stop := watchHeapGrowth(ctx, cancel, 1<<20)
+		defer stop()
+
+		var hold [][]byte
+		deadline := time.After(5 * time.Second)
+		for ctx.Err() == nil {
+			select {
+			case <-deadline:
+				t.Fatal("watchHeapGrowth did not cancel the context")
+			default:
+			}
+			hold = append(hold, make([]byte, 1<<20))
+			time.Sleep(time.Millisecond)
+		}
+		_ = hold
+
+		if !errors.Is(context.Cause(ctx), ErrHeapGrowthExceeded) {
+			t.Errorf("cause = %v, want ErrHeapGrowthExceeded", context.Cause(ctx))
+		}
+	})
+
+	t.Run("stop leaves the context alone", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+
+		stop := watchHeapGrowth(ctx, cancel, 1<<40)
+		stop()
+		time.Sleep(2 * heapCheckInterval)
+
+		if ctx.Err() != nil {
+			t.Errorf("context cancelled after stop: %v", context.Cause(ctx))
+		}
+	})
+}
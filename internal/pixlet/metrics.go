@@ -0,0 +1,112 @@
+package pixlet
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Worker pool instrumentation, exported on /metrics. Worker utilization is
+// rate(matrx_render_worker_busy_seconds_total[1m]) per worker.
+var (
+	metricJobsQueued = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "matrx_render_jobs_queued",
+		Help: "Render jobs waiting for a worker.",
+	}, []string{"priority"})
+
+	metricJobsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "matrx_render_jobs_rejected_total",
+		Help: "Render jobs rejected because their queue was full.",
+	}, []string{"priority"})
+
+	metricJobsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "matrx_render_jobs_in_flight",
+		Help: "Render jobs currently being processed by a worker.",
+	})
+
+	metricWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "matrx_render_workers",
+		Help: "Live render workers.",
+	})
+
+	metricQueueWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "matrx_render_queue_wait_seconds",
+		Help:    "Time render jobs spent queued before a worker picked them up.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 9), // 1ms to ~65s
+	}, []string{"priority"})
+
+	metricProcessDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "matrx_render_process_seconds",
+		Help:    "Time workers spent processing render jobs.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 14), // 5ms to ~41s
+	}, []string{"outcome"})
+
+	metricJobsDeduplicated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "matrx_render_jobs_deduplicated_total",
+		Help: "Render jobs served by joining an identical job already in flight.",
+	})
+
+	metricHeapGrowthAborts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "matrx_heap_growth_aborts_total",
+		Help: "Renders aborted because the process heap grew past the heap growth limit while they ran.",
+	})
+
+	metricAdaptiveTimeouts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "matrx_render_adaptive_timeouts_total",
+		Help: "Renders killed by an adaptive timeout shorter than the global render timeout.",
+	})
+
+	metricQuarantines = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "matrx_render_quarantines_total",
+		Help: "Times an app was moved to the quarantine pool for rendering slowly.",
+	})
+
+	metricRenderCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "matrx_render_cache_requests_total",
+		Help: "Render cache lookups by result (hit or miss).",
+	}, []string{"result"})
+
+	metricAppCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "matrx_app_cache_requests_total",
+		Help: "Lookups apps made through their cache module, by backend (memory or redis) and result (hit, miss or error, which also counts failed sets).",
+	}, []string{"backend", "result"})
+
+	metricOutboundRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "matrx_render_outbound_requests_total",
+		Help: "HTTP requests from apps that missed the HTTP cache, by outcome.",
+	}, []string{"outcome"})
+
+	metricAffinityJobs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "matrx_render_affinity_jobs_total",
+		Help: "Render jobs routed to their app's worker (sticky) or to the shared queue because it was busy (spill).",
+	}, []string{"result"})
+
+	metricWorkerBusy = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "matrx_render_worker_busy_seconds_total",
+		Help: "Time each render worker spent processing jobs.",
+	}, []string{"worker"})
+
+	metricStreamLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "matrx_render_stream_length",
+		Help: "Entries in the Redis render requests stream.",
+	})
+
+	metricStreamLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "matrx_render_stream_group_lag",
+		Help: "Render requests not yet delivered to each consumer group; -1 when Redis can't tell.",
+	}, []string{"group"})
+
+	metricStreamPending = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "matrx_render_stream_group_pending",
+		Help: "Render requests delivered to each consumer group but not yet acknowledged.",
+	}, []string{"group"})
+
+	metricStreamOldestPending = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "matrx_render_stream_group_oldest_pending_seconds",
+		Help: "Time since each consumer group's oldest pending render request was delivered.",
+	}, []string{"group"})
+
+	metricStreamConsumers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "matrx_render_stream_group_consumers",
+		Help: "Consumers in each consumer group.",
+	}, []string{"group"})
+)
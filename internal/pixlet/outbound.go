@@ -0,0 +1,275 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for outbound requests to a host whose circuit breaker
+// has tripped after repeated failures
+var ErrCircuitOpen = errors.New("upstream circuit breaker is open")
+
+// appHeader is set by pixlet's http module to the ID of the calling app
+const appHeader = "X-Tidbyt-App"
+
+// OutboundConfig controls the HTTP client apps use through http.star
+type OutboundConfig struct {
+	HostRate         int           // Requests per second to any one host; 0 is unlimited
+	HostBurst        int           // Requests a host may receive at once (default: HostRate)
+	AppRate          int           // Requests per second from any one app; 0 is unlimited
+	AppBurst         int           // Requests an app may send at once (default: AppRate)
+	BreakerFailures  int           // Consecutive failures that open a host's circuit; 0 disables
+	BreakerCooldown  time.Duration // How long an open circuit rejects requests (default: 30s)
+	MaxConnsPerHost  int           // Connections per host; 0 is unlimited
+	IdleConnsPerHost int           // Idle connections kept per host (default: 16)
+
+	// Shared, when set, keeps the host and app buckets in Redis so the rates
+	// hold across replicas; nil limits each process on its own
+	Shared *SharedRateLimiter
+}
+
+// Default outbound settings
+const (
+	defaultBreakerCooldown  = 30 * time.Second
+	defaultIdleConnsPerHost = 16
+)
+
+// outboundTransport is the transport under the HTTP cache, so only
+// cache misses reach it. It rate limits per host and per app and stops calling
+// hosts that keep failing, over one shared connection pool.
+type outboundTransport struct {
+	next     http.RoundTripper
+	hosts    *limiterSet
+	apps     *limiterSet
+	breakers *breakerSet
+}
+
+// newOutboundTransport builds the shared pooled transport for app HTTP requests
+func newOutboundTransport(cfg OutboundConfig) *outboundTransport {
+	if cfg.IdleConnsPerHost <= 0 {
+		cfg.IdleConnsPerHost = defaultIdleConnsPerHost
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = defaultBreakerCooldown
+	}
+
+	pooled := http.DefaultTransport.(*http.Transport).Clone()
+	pooled.MaxIdleConnsPerHost = cfg.IdleConnsPerHost
+	pooled.MaxConnsPerHost = cfg.MaxConnsPerHost
+
+	hosts := newLimiterSet(cfg.HostRate, cfg.HostBurst)
+	hosts.shared, hosts.prefix = cfg.Shared, "outbound:host:"
+	apps := newLimiterSet(cfg.AppRate, cfg.AppBurst)
+	apps.shared, apps.prefix = cfg.Shared, "outbound:app:"
+
+	return &outboundTransport{
+		next:     pooled,
+		hosts:    hosts,
+		apps:     apps,
+		breakers: newBreakerSet(cfg.BreakerFailures, cfg.BreakerCooldown),
+	}
+}
+
+// RoundTrip waits for the host and app rate limits, then sends the request unless
+// the host's circuit is open
+func (t *outboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+
+	if !t.breakers.allow(host) {
+		metricOutboundRequests.WithLabelValues("circuit_open").Inc()
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+	}
+	if err := t.hosts.wait(req.Context(), host); err != nil {
+		metricOutboundRequests.WithLabelValues("rate_limited").Inc()
+		return nil, fmt.Errorf("rate limit for host %s: %w", host, err)
+	}
+	if app := req.Header.Get(appHeader); app != "" {
+		if err := t.apps.wait(req.Context(), app); err != nil {
+			metricOutboundRequests.WithLabelValues("rate_limited").Inc()
+			return nil, fmt.Errorf("rate limit for app %s: %w", app, err)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+
+	// Server errors and transport failures count against the host; a cancelled
+	// render is not the host's fault
+	failed := err != nil || resp.StatusCode >= 500
+	if errors.Is(err, context.Canceled) {
+		t.breakers.abandon(host)
+	} else {
+		t.breakers.record(host, !failed)
+	}
+
+	if failed {
+		metricOutboundRequests.WithLabelValues("error").Inc()
+	} else {
+		metricOutboundRequests.WithLabelValues("success").Inc()
+	}
+	return resp, err
+}
+
+// limiterSet holds a token bucket per key, all with the same rate
+type limiterSet struct {
+	mu      sync.Mutex
+	rate    int
+	burst   int
+	buckets map[string]*tokenBucket
+
+	shared *SharedRateLimiter // Buckets in Redis, under prefix; nil keeps them in memory
+	prefix string
+}
+
+func newLimiterSet(rate, burst int) *limiterSet {
+	if burst <= 0 {
+		burst = rate
+	}
+	return &limiterSet{rate: rate, burst: burst, buckets: make(map[string]*tokenBucket)}
+}
+
+// wait blocks until key may make a request or ctx ends. A zero rate never blocks.
+func (s *limiterSet) wait(ctx context.Context, key string) error {
+	if s.rate <= 0 {
+		return nil
+	}
+
+	delay := s.reserve(ctx, key)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// The reserved token is not returned; the bucket refills on its own
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from key's bucket, in Redis when shared and reachable,
+// otherwise in memory, and returns how long to wait before using it
+func (s *limiterSet) reserve(ctx context.Context, key string) time.Duration {
+	if s.shared != nil {
+		if delay, err := s.shared.Reserve(ctx, s.prefix+key, float64(s.rate), float64(s.burst)); err == nil {
+			return delay
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(s.burst), last: time.Now()}
+		s.buckets[key] = bucket
+	}
+	return bucket.reserve(float64(s.rate), float64(s.burst), time.Now())
+}
+
+// tokenBucket refills at a fixed rate up to a burst size. Callers must hold the
+// owning limiterSet's lock.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token, going into debt if none is left, and returns how long
+// the caller must wait before using it
+func (b *tokenBucket) reserve(rate, burst float64, now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
+
+// breakerSet keeps a circuit breaker per host. After threshold consecutive
+// failures a host's circuit opens and requests fail fast for the cooldown; then
+// a single trial request decides whether it closes again.
+type breakerSet struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	hosts     map[string]*breaker
+}
+
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	trial     bool // a half-open trial request is in flight
+}
+
+func newBreakerSet(threshold int, cooldown time.Duration) *breakerSet {
+	return &breakerSet{threshold: threshold, cooldown: cooldown, hosts: make(map[string]*breaker)}
+}
+
+// allow reports whether a request to host may be sent
+func (s *breakerSet) allow(host string) bool {
+	if s.threshold <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.hosts[host]
+	if !ok || b.failures < s.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// abandon releases a trial request that ended without an outcome
+func (s *breakerSet) abandon(host string) {
+	if s.threshold <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.hosts[host]; ok {
+		b.trial = false
+	}
+}
+
+// record updates host's breaker with the outcome of a request
+func (s *breakerSet) record(host string, success bool) {
+	if s.threshold <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.hosts[host]
+	if success {
+		if ok {
+			delete(s.hosts, host)
+		}
+		return
+	}
+	if !ok {
+		b = &breaker{}
+		s.hosts[host] = b
+	}
+	b.failures++
+	b.trial = false
+	if b.failures >= s.threshold {
+		b.openUntil = time.Now().Add(s.cooldown)
+	}
+}
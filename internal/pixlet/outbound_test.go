@@ -0,0 +1,122 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	now := time.Now()
+	b := &tokenBucket{tokens: 2, last: now}
+
+	for i := 0; i < 2; i++ {
+		if d := b.reserve(10, 2, now); d != 0 {
+			t.Fatalf("reserve %d within burst waited %v", i, d)
+		}
+	}
+	if d := b.reserve(10, 2, now); d != 100*time.Millisecond {
+		t.Errorf("reserve past burst = %v, want 100ms", d)
+	}
+	// After a second the bucket is full again, not overfull
+	if d := b.reserve(10, 2, now.Add(time.Second)); d != 0 {
+		t.Errorf("reserve after refill waited %v", d)
+	}
+	if b.tokens != 1 {
+		t.Errorf("tokens = %v, want 1 (burst cap minus one)", b.tokens)
+	}
+}
+
+func TestLimiterSetWait(t *testing.T) {
+	s := newLimiterSet(1, 1)
+	if err := s.wait(context.Background(), "host-a"); err != nil {
+		t.Fatalf("first wait: %v", err)
+	}
+
+	// Keys have separate buckets
+	if err := s.wait(context.Background(), "host-b"); err != nil {
+		t.Fatalf("other key: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.wait(ctx, "host-a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait past rate = %v, want deadline exceeded", err)
+	}
+
+	if err := newLimiterSet(0, 0).wait(context.Background(), "host-a"); err != nil {
+		t.Errorf("unlimited wait: %v", err)
+	}
+}
+
+func TestLimiterSetWait_SharedUnavailable(t *testing.T) {
+	redisCache := NewRedisCache(&config.RedisConfig{Addr: "127.0.0.1:1"})
+	defer redisCache.Close()
+
+	// An unreachable Redis falls back to the in-memory bucket
+	s := newLimiterSet(1, 1)
+	s.shared, s.prefix = newSharedRateLimiter(redisCache), "outbound:host:"
+	if err := s.wait(context.Background(), "host-a"); err != nil {
+		t.Fatalf("first wait: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.wait(ctx, "host-a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait past rate = %v, want deadline exceeded", err)
+	}
+}
+
+func TestBreakerSet(t *testing.T) {
+	s := newBreakerSet(2, 20*time.Millisecond)
+
+	s.record("host", false)
+	if !s.allow("host") {
+		t.Fatal("circuit opened below the failure threshold")
+	}
+	s.record("host", false)
+	if s.allow("host") {
+		t.Fatal("circuit still closed at the failure threshold")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !s.allow("host") {
+		t.Fatal("no trial request allowed after cooldown")
+	}
+	if s.allow("host") {
+		t.Error("second request allowed while the trial is in flight")
+	}
+
+	s.record("host", true)
+	if !s.allow("host") || !s.allow("host") {
+		t.Error("circuit not closed after a successful trial")
+	}
+}
+
+func TestOutboundTransport(t *testing.T) {
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	transport := newOutboundTransport(OutboundConfig{BreakerFailures: 2, BreakerCooldown: time.Minute})
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+
+	status = http.StatusOK
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("request after repeated 500s = %v, want ErrCircuitOpen", err)
+	}
+}
@@ -0,0 +1,57 @@
+package pixlet
+
+import (
+	"image/color"
+	"time"
+
+	"tidbyt.dev/pixlet/render"
+)
+
+// overlayFont is the smallest built-in font (4x6), keeping the overlay readable on 64x32
+const overlayFont = "tom-thumb"
+
+// overlayInfo identifies which instance produced a render, and when
+type overlayInfo struct {
+	AppID    string
+	DeviceID string
+	Size     string // WIDTHxHEIGHT, set for batch renders that produce several sizes
+	Worker   string
+	Time     time.Time
+}
+
+// lines returns the non-empty overlay lines in display order
+func (o overlayInfo) lines() []string {
+	var lines []string
+	for _, line := range []string{o.AppID, o.DeviceID, o.Size, o.Time.UTC().Format("15:04:05"), o.Worker} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// withDebugOverlay stamps the overlay in the top-left corner of every root, on a
+// translucent background so it stays legible over any app content
+func withDebugOverlay(roots []render.Root, info overlayInfo) ([]render.Root, error) {
+	var children []render.Widget
+	for _, line := range info.lines() {
+		text := &render.Text{Content: line, Font: overlayFont, Color: color.White}
+		if err := text.Init(); err != nil {
+			return nil, err
+		}
+		children = append(children, text)
+	}
+
+	panel := render.Padding{
+		Child: render.Column{Children: children},
+		Pad:   render.Insets{Left: 1, Top: 1, Right: 1},
+		Color: color.RGBA{A: 192},
+	}
+
+	stamped := make([]render.Root, len(roots))
+	for i, root := range roots {
+		root.Child = render.Stack{Children: []render.Widget{root.Child, panel}}
+		stamped[i] = root
+	}
+	return stamped, nil
+}
@@ -0,0 +1,110 @@
+package pixlet
+
+import (
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestOverlayInfoLines(t *testing.T) {
+	info := overlayInfo{
+		AppID:  "clock",
+		Worker: "host#2",
+		Time:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	want := []string{"clock", "03:04:05", "host#2"}
+	got := info.lines()
+	if len(got) != len(want) {
+		t.Fatalf("lines() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("lines()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWorkerPoolOverlayInfo(t *testing.T) {
+	wp := &WorkerPool{instance: "host"}
+	job := &RenderJob{
+		AppID:   "clock",
+		Device:  models.Device{ID: "device-1"},
+		Options: RenderOptions{RenderTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+
+	single := wp.overlayInfo(2, job, "")
+	if single.DeviceID != "device-1" || single.Size != "" || single.Worker != "host#2" {
+		t.Errorf("overlayInfo = %+v, want device-1 without a size on host#2", single)
+	}
+
+	// Batch sizes keep the device and add the size
+	batch := wp.overlayInfo(2, job, "128x64")
+	want := []string{"clock", "device-1", "128x64", "03:04:05", "host#2"}
+	got := batch.lines()
+	if len(got) != len(want) {
+		t.Fatalf("lines() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("lines()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStreamFramesDebugOverlay(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "black-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+	appContent := `
+load("render.star", "render")
+
+def main(config):
+    return render.Root(child = render.Box(color = "#000"))
+`
+	if err := os.WriteFile(filepath.Join(appDir, "black-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "black-app", "black-app.star")
+
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir}, zap.NewNop())
+	defer processor.Stop()
+
+	litPixels := func(opts RenderOptions) int {
+		var img image.Image
+		err := processor.StreamFrames(context.Background(), "black-app", map[string]interface{}{},
+			models.Device{ID: "dev-1", Width: 64, Height: 32}, opts,
+			func(frame Frame) error {
+				img = frame.Image
+				return nil
+			})
+		if err != nil {
+			t.Fatalf("StreamFrames failed: %v", err)
+		}
+		lit := 0
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if r, g, b, _ := img.At(x, y).RGBA(); r|g|b != 0 {
+					lit++
+				}
+			}
+		}
+		return lit
+	}
+
+	if n := litPixels(RenderOptions{}); n != 0 {
+		t.Errorf("Expected an all-black frame without overlay, got %d lit pixels", n)
+	}
+	if n := litPixels(RenderOptions{DebugOverlay: true}); n == 0 {
+		t.Error("Expected overlay text to light up pixels")
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"image"
 	"io/fs"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/koios/matrx-renderer/internal/config"
@@ -16,6 +17,7 @@ import (
 	"go.uber.org/zap"
 
 	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/render"
 	"tidbyt.dev/pixlet/runtime"
 	"tidbyt.dev/pixlet/schema"
 	"tidbyt.dev/pixlet/tools"
@@ -36,8 +38,29 @@ type Processor struct {
 	secretDecryptionKey runtime.SecretDecryptionKey // Key for decrypting secrets in Pixlet apps
 	hasSecretKey        bool                        // Whether a real secret key is configured
 	workerPool          *WorkerPool                 // Worker pool for concurrent rendering
+	webp                WebPOptions                 // Default WebP encoder settings
+	defaultSize         models.Size                 // Device size used when a request doesn't set one
+	allowedSizes        map[models.Size]bool        // Device size whitelist; empty allows any size
+	profiles            *DeviceProfiles             // Per-device color profiles; nil when not configured
+	renderCache         *renderCache                // Encoded output for apps with a render cache TTL
+	processed           *processedRequests          // Results of recent request UUIDs; nil when idempotency is off
+	streamRetention     *streamRetention            // Trims the render requests stream; nil when not configured
+	streamMetrics       *streamMetrics              // Samples the render requests stream for /metrics; nil without Redis
+	sharedLimiter       *SharedRateLimiter          // Rate limit buckets in Redis; nil without Redis
+	jobStore            *JobStore                   // Async render jobs in Redis; nil without Redis
+	control             *controlListener            // Follows the Redis control channel; nil when not configured
+	schemaPool          *schemaPool                 // Workers for schema loads and handler calls
+	httpCache           *httpCache                  // Cache behind http.star, for /stats
+	cacheCounts         *countingCache              // Lookups through the cache module, for /admin/cache/stats
+	results             *ResultFeed                 // Device render results for live subscribers
+	installMu           sync.Mutex                  // Serializes app installs
+	appsErr             atomic.Pointer[error]       // Why the apps path last failed to load; nil once it loads
+	started             time.Time
 }
 
+// ErrSizeNotAllowed is returned when a device size is not in the configured whitelist
+var ErrSizeNotAllowed = errors.New("device size not allowed")
+
 // appletOptions returns the common runtime options for creating an applet.
 func (p *Processor) appletOptions() []runtime.AppletOption {
 	opts := []runtime.AppletOption{
@@ -46,6 +69,9 @@ func (p *Processor) appletOptions() []runtime.AppletOption {
 	if p.hasSecretKey {
 		opts = append(opts, runtime.WithSecretDecryptionKey(&p.secretDecryptionKey))
 	}
+	if limits := renderLimitsFromConfig(p.config); limits.MaxExecutionSteps > 0 {
+		opts = append(opts, withStepLimit(limits.MaxExecutionSteps))
+	}
 	return opts
 }
 
@@ -88,13 +114,17 @@ func GetSecretDecryptionKey(cfg *config.PixletConfig, logger *zap.Logger) (*runt
 // NewProcessor creates a new Pixlet processor with persistent runtime using InMemory cache
 func NewProcessor(cfg *config.PixletConfig, logger *zap.Logger) *Processor {
 	cache := runtime.NewInMemoryCache()
-	runtime.InitHTTP(cache)
-	runtime.InitCache(cache)
+	httpCache := initHTTP(cache, newOutboundTransport(outboundFromConfig(cfg)))
+	cacheCounts := newCountingCache(cache, cacheBackendMemory)
+	runtime.InitCache(newScopedCache(cacheCounts, cfg.CacheScope))
+
+	loadCustomFonts(cfg, logger)
 
 	// Create app registry and load apps
 	appRegistry := models.NewAppRegistry()
-	if err := appRegistry.LoadApps(cfg.AppsPath); err != nil {
-		logger.Error("Failed to load apps", zap.Error(err))
+	appsErr := appRegistry.LoadApps(cfg.AppsPath)
+	if appsErr != nil {
+		logger.Error("Failed to load apps", zap.Error(appsErr))
 	}
 
 	secretDecryptionKey, err := GetSecretDecryptionKey(cfg, logger)
@@ -116,12 +146,18 @@ func NewProcessor(cfg *config.PixletConfig, logger *zap.Logger) *Processor {
 		nil, // no Redis cache
 		*secretDecryptionKey,
 		timeout,
+		cfg.AppletCacheSize,
+		scalingFromConfig(cfg),
+		cfg.RenderDedupe,
+		queueFromConfig(cfg),
+		renderLimitsFromConfig(cfg),
 	)
+	workerPool.perDevice = cfg.CacheScope == CacheScopeDevice
 	workerPool.Start()
 
 	hasKey := secretDecryptionKey.EncryptedKeysetJSON != nil
 
-	return &Processor{
+	p := &Processor{
 		config:              cfg,
 		logger:              logger,
 		cache:               cache,
@@ -130,7 +166,20 @@ func NewProcessor(cfg *config.PixletConfig, logger *zap.Logger) *Processor {
 		secretDecryptionKey: *secretDecryptionKey,
 		hasSecretKey:        hasKey,
 		workerPool:          workerPool,
+		webp:                webpOptionsFromConfig(cfg, logger),
+		defaultSize:         defaultSizeFromConfig(cfg),
+		allowedSizes:        allowedSizesFromConfig(cfg, logger),
+		profiles:            deviceProfilesFromConfig(cfg, logger),
+		renderCache:         newRenderCache(nil),
+		processed:           newProcessedRequests(time.Duration(cfg.RequestIdempotencyTTL)*time.Second, nil),
+		schemaPool:          schemaPoolFromConfig(cfg),
+		httpCache:           httpCache,
+		cacheCounts:         cacheCounts,
+		results:             NewResultFeed(),
+		started:             time.Now(),
 	}
+	p.setAppsErr(appsErr)
+	return p
 }
 
 // NewProcessorWithRedis creates a new Pixlet processor with Redis cache support
@@ -138,15 +187,26 @@ func NewProcessorWithRedis(cfg *config.PixletConfig, redisConfig *config.RedisCo
 	// Create shared Redis cache instance
 	redisCache := NewRedisCache(redisConfig)
 
-	// For initialization, we use an in-memory cache as fallback
+	// The in-memory cache is kept as a fallback; the runtime's HTTP and cache
+	// modules are pointed at Redis once here rather than on every render, since
+	// they are process-wide globals shared by all workers
 	cache := runtime.NewInMemoryCache()
-	runtime.InitHTTP(cache)
-	runtime.InitCache(cache)
+	sharedLimiter := newSharedRateLimiter(redisCache)
+	outbound := outboundFromConfig(cfg)
+	if cfg.HTTPRateLimitShared {
+		outbound.Shared = sharedLimiter
+	}
+	httpCache := initHTTP(redisCache, newOutboundTransport(outbound))
+	cacheCounts := newCountingCache(redisCache, cacheBackendRedis)
+	runtime.InitCache(newScopedCache(cacheCounts, cfg.CacheScope))
+
+	loadCustomFonts(cfg, logger)
 
 	// Create app registry and load apps
 	appRegistry := models.NewAppRegistry()
-	if err := appRegistry.LoadApps(cfg.AppsPath); err != nil {
-		logger.Error("Failed to load apps", zap.Error(err))
+	appsErr := appRegistry.LoadApps(cfg.AppsPath)
+	if appsErr != nil {
+		logger.Error("Failed to load apps", zap.Error(appsErr))
 	}
 
 	secretDecryptionKey, err := GetSecretDecryptionKey(cfg, logger)
@@ -168,12 +228,18 @@ func NewProcessorWithRedis(cfg *config.PixletConfig, redisConfig *config.RedisCo
 		redisCache,
 		*secretDecryptionKey,
 		timeout,
+		cfg.AppletCacheSize,
+		scalingFromConfig(cfg),
+		cfg.RenderDedupe,
+		queueFromConfig(cfg),
+		renderLimitsFromConfig(cfg),
 	)
+	workerPool.perDevice = cfg.CacheScope == CacheScopeDevice
 	workerPool.Start()
 
 	hasKey := secretDecryptionKey.EncryptedKeysetJSON != nil
 
-	return &Processor{
+	p := &Processor{
 		config:              cfg,
 		redisConfig:         redisConfig,
 		logger:              logger,
@@ -184,18 +250,221 @@ func NewProcessorWithRedis(cfg *config.PixletConfig, redisConfig *config.RedisCo
 		secretDecryptionKey: *secretDecryptionKey,
 		hasSecretKey:        hasKey,
 		workerPool:          workerPool,
+		webp:                webpOptionsFromConfig(cfg, logger),
+		defaultSize:         defaultSizeFromConfig(cfg),
+		allowedSizes:        allowedSizesFromConfig(cfg, logger),
+		profiles:            deviceProfilesFromConfig(cfg, logger),
+		renderCache:         newRenderCache(redisCache),
+		processed:           newProcessedRequests(time.Duration(cfg.RequestIdempotencyTTL)*time.Second, redisCache),
+		streamRetention:     newStreamRetention(redisCache, redisConfig, logger),
+		sharedLimiter:       sharedLimiter,
+		jobStore:            newJobStore(redisCache),
+		schemaPool:          schemaPoolFromConfig(cfg),
+		httpCache:           httpCache,
+		cacheCounts:         cacheCounts,
+		results:             NewResultFeed(),
+		started:             time.Now(),
 	}
+	p.setAppsErr(appsErr)
+	p.streamRetention.Start()
+	p.streamMetrics = newStreamMetrics(p, time.Duration(redisConfig.StreamMetricsInterval)*time.Second)
+	p.streamMetrics.Start()
+	p.control = newControlListener(p, redisConfig.ControlChannel)
+	p.control.Start()
+	return p
 }
 
 // RenderApp renders a Pixlet app with the given configuration using the runtime
 func (p *Processor) RenderApp(ctx context.Context, request *models.RenderRequest) (*models.RenderResult, error) {
-	screens, err := p.renderScreens(ctx, request.AppID, request.Params, request.Device)
+	opts := renderOptionsFor(request)
+	device, err := p.resolveDevice(request.Device)
+	if err != nil {
+		return p.buildRenderResult(ctx, request, request.Device, nil, opts, err)
+	}
+
+	if data, ok := p.cachedRender(ctx, request.AppID, request.Params, device, "webp", opts); ok {
+		result := cachedRenderResult(request, device, data)
+		p.results.Publish(result)
+		return result, nil
+	}
+
+	roots, err := p.workerPool.SubmitRoots(ctx, request.AppID, request.Params, device, opts)
+	result, err := p.buildRenderResult(ctx, request, device, roots, opts, err)
+	p.results.Publish(result)
+	return result, err
+}
+
+// Results returns the feed RenderApp publishes each device's results to
+func (p *Processor) Results() *ResultFeed {
+	return p.results
+}
+
+// RenderAppBatch renders the same app and config at several device sizes in a single
+// worker job, loading the applet once. Results are keyed by size ("WIDTHxHEIGHT");
+// a failure at one size is flagged on that result without affecting the others.
+func (p *Processor) RenderAppBatch(ctx context.Context, request *models.RenderRequest, sizes []models.Size) (map[string]*models.RenderResult, error) {
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("at least one size is required")
+	}
+
+	for _, size := range sizes {
+		if err := p.CheckDeviceSize(size); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := renderOptionsFor(request)
+	results := make(map[string]*models.RenderResult, len(sizes))
+
+	// Only render the sizes that aren't cached
+	var missing []models.Size
+	for _, size := range sizes {
+		device := models.Device{ID: request.Device.ID, Width: size.Width, Height: size.Height}
+		if data, ok := p.cachedRender(ctx, request.AppID, request.Params, device, "webp", opts); ok {
+			results[size.String()] = cachedRenderResult(request, device, data)
+			continue
+		}
+		missing = append(missing, size)
+	}
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	batch, err := p.workerPool.SubmitBatch(ctx, request.AppID, request.Params, missing, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, size := range missing {
+		device := models.Device{ID: request.Device.ID, Width: size.Width, Height: size.Height}
+		result, err := p.buildRenderResult(ctx, request, device, batch[i].Roots, opts, batch[i].Error)
+		if err != nil {
+			p.logger.Warn("Batch render failed for size",
+				zap.String("app_id", request.AppID),
+				zap.String("size", size.String()),
+				zap.Error(err))
+		}
+		results[size.String()] = result
+	}
+
+	return results, nil
+}
+
+// renderOptionsFor extracts per-request render overrides from a render request
+func renderOptionsFor(request *models.RenderRequest) RenderOptions {
+	opts := RenderOptions{Encoding: request.Encoding, DebugOverlay: request.DebugOverlay}
+	if request.RenderTime != nil {
+		opts.RenderTime = *request.RenderTime
+	}
+	return opts
+}
+
+// loadCustomFonts registers fonts from the configured fonts directory, if any
+func loadCustomFonts(cfg *config.PixletConfig, logger *zap.Logger) {
+	if cfg.FontsPath == "" {
+		return
+	}
+	fonts, err := LoadFonts(cfg.FontsPath, logger)
+	if err != nil {
+		logger.Error("Failed to load custom fonts", zap.Error(err))
+		return
+	}
+	logger.Info("Loaded custom fonts",
+		zap.String("path", cfg.FontsPath),
+		zap.Strings("fonts", fonts))
+}
+
+// deviceProfilesFromConfig loads the configured device color profiles, if any.
+// A file that fails to load is logged and ignored so rendering still works.
+func deviceProfilesFromConfig(cfg *config.PixletConfig, logger *zap.Logger) *DeviceProfiles {
+	if cfg.DeviceProfilesPath == "" {
+		return nil
+	}
+	profiles, err := LoadDeviceProfiles(cfg.DeviceProfilesPath)
+	if err != nil {
+		logger.Error("Failed to load device profiles", zap.Error(err))
+		return nil
+	}
+	logger.Info("Loaded device profiles",
+		zap.String("path", cfg.DeviceProfilesPath),
+		zap.Int("profiles", len(profiles.Profiles)),
+		zap.Int("devices", len(profiles.Devices)))
+	return profiles
+}
+
+// frameLimit returns the maximum number of frames painted for an app: the manifest's
+// maxFrameCount when set, otherwise the configured default. It logs a warning when
+// the rendered roots exceed the limit and will be truncated.
+func (p *Processor) frameLimit(appID string, roots []render.Root) int {
+	limit := p.config.MaxFrameCount
+	if app, exists := p.appRegistry.GetApp(appID); exists && app.MaxFrameCount > 0 {
+		limit = app.MaxFrameCount
+	}
+	if limit <= 0 {
+		limit = render.DefaultMaxFrameCount
+	}
+
+	if total := countFrames(roots); total > limit {
+		p.logger.Warn("App exceeded maximum frame count, truncating",
+			zap.String("app_id", appID),
+			zap.Int("frame_count", total),
+			zap.Int("max_frame_count", limit))
+	}
+	return limit
+}
+
+// webpOptionsFromConfig builds the default WebP encoder settings from config,
+// falling back to libwebp's defaults for unset or out-of-range values
+func webpOptionsFromConfig(cfg *config.PixletConfig, logger *zap.Logger) WebPOptions {
+	opts := WebPOptions{
+		Lossless: !cfg.WebPLossy,
+		Quality:  cfg.WebPQuality,
+		Method:   cfg.WebPMethod,
+	}
+	// config.Load rejects 0, so a zero here is a field left unset
+	if opts.Quality <= 0 {
+		opts.Quality = defaultWebPQuality
+	}
+	if opts.Method <= 0 {
+		opts.Method = defaultWebPMethod
+	}
+	if err := opts.Validate(); err != nil {
+		logger.Warn("Invalid WebP configuration, using defaults", zap.Error(err))
+		opts.Quality = defaultWebPQuality
+		opts.Method = defaultWebPMethod
+	}
+	return opts
+}
+
+// webpOptions applies per-request encoding overrides on top of the configured defaults
+func (p *Processor) webpOptions(overrides *models.EncodingOptions) (WebPOptions, error) {
+	opts := p.webp
+	if overrides == nil {
+		return opts, nil
+	}
+	if overrides.Lossless != nil {
+		opts.Lossless = *overrides.Lossless
+	}
+	if overrides.Quality != nil {
+		opts.Quality = *overrides.Quality
+	}
+	if overrides.Method != nil {
+		opts.Method = *overrides.Method
+	}
+	if err := opts.Validate(); err != nil {
+		return WebPOptions{}, err
+	}
+	return opts, nil
+}
+
+// buildRenderResult encodes rendered roots into a render result for the given device
+func (p *Processor) buildRenderResult(ctx context.Context, request *models.RenderRequest, device models.Device, roots []render.Root, opts RenderOptions, err error) (*models.RenderResult, error) {
 	if err != nil {
 		// Render failed (e.g., fail() called in starlark) - return empty result with error flag
 		return &models.RenderResult{
 			Type:         "render_result",
 			UUID:         request.UUID,
-			DeviceID:     request.Device.ID,
+			DeviceID:     device.ID,
 			AppID:        request.AppID,
 			RenderOutput: "",
 			Error:        true,
@@ -204,38 +473,45 @@ func (p *Processor) RenderApp(ctx context.Context, request *models.RenderRequest
 	}
 
 	// Check if app returned empty screens (e.g., return [] in starlark)
-	if screens.Empty() {
+	if len(roots) == 0 {
 		p.logger.Debug("Pixlet render returned empty screens (skipped)",
 			zap.String("app_id", request.AppID),
-			zap.String("device_id", request.Device.ID))
+			zap.String("device_id", device.ID))
+		p.storeRender(ctx, request.AppID, request.Params, device, "webp", opts, []byte{})
 
 		return &models.RenderResult{
 			Type:         "render_result",
 			UUID:         request.UUID,
-			DeviceID:     request.Device.ID,
+			DeviceID:     device.ID,
 			AppID:        request.AppID,
 			RenderOutput: "",
 			Error:        false,
+			Skipped:      true,
 			ProcessedAt:  time.Now(),
 		}, nil
 	}
 
-	filter := func(input image.Image) (image.Image, error) {
-		return input, nil
-	}
-
-	maxDuration := 15000
-	if screens.ShowFullAnimation {
-		maxDuration = 0
+	webpOpts, err := p.webpOptions(opts.Encoding)
+	if err != nil {
+		// Invalid encoding overrides - return empty result with error flag
+		return &models.RenderResult{
+			Type:         "render_result",
+			UUID:         request.UUID,
+			DeviceID:     device.ID,
+			AppID:        request.AppID,
+			RenderOutput: "",
+			Error:        true,
+			ProcessedAt:  time.Now(),
+		}, err
 	}
 
-	webpData, err := screens.EncodeWebP(maxDuration, filter)
+	webpData, err := encodeWebP(ctx, roots, webpOpts, p.frameLimit(request.AppID, roots), p.profiles.Lookup(device.ID))
 	if err != nil {
 		// Encoding failed - return empty result with error flag
 		return &models.RenderResult{
 			Type:         "render_result",
 			UUID:         request.UUID,
-			DeviceID:     request.Device.ID,
+			DeviceID:     device.ID,
 			AppID:        request.AppID,
 			RenderOutput: "",
 			Error:        true,
@@ -243,17 +519,18 @@ func (p *Processor) RenderApp(ctx context.Context, request *models.RenderRequest
 		}, fmt.Errorf("error encoding WebP: %w", err)
 	}
 
+	p.storeRender(ctx, request.AppID, request.Params, device, "webp", opts, webpData)
 	base64Output := base64.StdEncoding.EncodeToString(webpData)
 
 	p.logger.Debug("Pixlet render completed",
 		zap.String("app_id", request.AppID),
-		zap.String("device_id", request.Device.ID),
+		zap.String("device_id", device.ID),
 		zap.Int("output_size", len(webpData)))
 
 	return &models.RenderResult{
 		Type:         "render_result",
 		UUID:         request.UUID,
-		DeviceID:     request.Device.ID,
+		DeviceID:     device.ID,
 		AppID:        request.AppID,
 		RenderOutput: base64Output,
 		Error:        false,
@@ -261,39 +538,248 @@ func (p *Processor) RenderApp(ctx context.Context, request *models.RenderRequest
 	}, nil
 }
 
-// RenderPreview renders an app configuration and returns raw image bytes in the requested format.
-func (p *Processor) RenderPreview(ctx context.Context, appID string, params map[string]interface{}, device models.Device, format string) ([]byte, error) {
-	screens, err := p.renderScreens(ctx, appID, params, device)
+// cachedRenderResult builds a render result from cached output
+func cachedRenderResult(request *models.RenderRequest, device models.Device, data []byte) *models.RenderResult {
+	return &models.RenderResult{
+		Type:         "render_result",
+		UUID:         request.UUID,
+		DeviceID:     device.ID,
+		AppID:        request.AppID,
+		RenderOutput: base64.StdEncoding.EncodeToString(data),
+		Error:        false,
+		Skipped:      len(data) == 0,
+		ProcessedAt:  time.Now(),
+	}
+}
+
+// renderCacheTTL returns how long an app's output may be served from cache: the
+// manifest's renderCacheTTL when set, otherwise the configured default. Zero or
+// less disables caching.
+func (p *Processor) renderCacheTTL(appID string) time.Duration {
+	ttl := p.config.RenderCacheTTL
+	if app, exists := p.appRegistry.GetApp(appID); exists && app.RenderCacheTTL != 0 {
+		ttl = app.RenderCacheTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// renderCacheKeyFor returns the cache key and TTL for a render, or an empty key
+// when the render must not be cached. Debug overlays stamp the wall clock, so
+// they are never cached.
+func (p *Processor) renderCacheKeyFor(appID string, params map[string]interface{}, device models.Device, format string, opts RenderOptions) (string, time.Duration) {
+	ttl := p.renderCacheTTL(appID)
+	if ttl <= 0 || opts.DebugOverlay {
+		return "", 0
+	}
+	webpOpts, err := p.webpOptions(opts.Encoding)
 	if err != nil {
-		return nil, err
+		return "", 0
 	}
+	return renderCacheKey(appID, params, device, format, opts, webpOpts, p.profiles.Lookup(device.ID), p.config.CacheScope == CacheScopeDevice), ttl
+}
 
-	filter := func(input image.Image) (image.Image, error) {
-		return input, nil
+// cachedRender returns cached output for a render, if any
+func (p *Processor) cachedRender(ctx context.Context, appID string, params map[string]interface{}, device models.Device, format string, opts RenderOptions) ([]byte, bool) {
+	key, _ := p.renderCacheKeyFor(appID, params, device, format, opts)
+	if key == "" {
+		return nil, false
 	}
 
-	maxDuration := 15000
-	if screens.ShowFullAnimation {
-		maxDuration = 0
+	data, ok := p.renderCache.Get(ctx, key)
+	if !ok {
+		metricRenderCache.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	metricRenderCache.WithLabelValues("hit").Inc()
+	p.logger.Debug("Render served from cache",
+		zap.String("app_id", appID),
+		zap.String("device_id", device.ID))
+	return data, true
+}
+
+// storeRender caches encoded output for apps with a render cache TTL
+func (p *Processor) storeRender(ctx context.Context, appID string, params map[string]interface{}, device models.Device, format string, opts RenderOptions, data []byte) {
+	key, ttl := p.renderCacheKeyFor(appID, params, device, format, opts)
+	if key == "" {
+		return
+	}
+	if err := p.renderCache.Set(ctx, key, data, ttl); err != nil {
+		p.logger.Warn("Failed to cache render output",
+			zap.String("app_id", appID),
+			zap.Error(err))
 	}
+}
 
+// RenderPreview renders an app configuration and returns raw image bytes in the requested format.
+func (p *Processor) RenderPreview(ctx context.Context, appID string, params map[string]interface{}, device models.Device, format string, opts RenderOptions) ([]byte, error) {
 	if strings.ToLower(format) != "webp" {
 		return nil, fmt.Errorf("unsupported format: %s (only webp is supported)", format)
 	}
 
-	webpData, err := screens.EncodeWebP(maxDuration, filter)
+	webpOpts, err := p.webpOptions(opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	device, err = p.resolveDevice(device)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, ok := p.cachedRender(ctx, appID, params, device, "webp", opts); ok {
+		return data, nil
+	}
+
+	roots, err := p.workerPool.SubmitRoots(ctx, appID, params, device, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	webpData, err := encodeWebP(ctx, roots, webpOpts, p.frameLimit(appID, roots), p.profiles.Lookup(device.ID))
 	if err != nil {
 		return nil, fmt.Errorf("error encoding WebP: %w", err)
 	}
+	p.storeRender(ctx, appID, params, device, "webp", opts, webpData)
 	p.logger.Debug("Pixlet preview rendered",
 		zap.String("app_id", appID),
 		zap.Int("output_size", len(webpData)))
 	return webpData, nil
 }
 
-func (p *Processor) renderScreens(ctx context.Context, appID string, params map[string]interface{}, device models.Device) (*encode.Screens, error) {
+func (p *Processor) renderRoots(ctx context.Context, appID string, params map[string]interface{}, device models.Device, opts RenderOptions) ([]render.Root, error) {
+	device, err := p.resolveDevice(device)
+	if err != nil {
+		return nil, err
+	}
+
 	// Delegate rendering to the worker pool for concurrent processing
-	return p.workerPool.Submit(ctx, appID, params, device)
+	return p.workerPool.SubmitRoots(ctx, appID, params, device, opts)
+}
+
+// renderLimitsFromConfig builds per-render resource limits from config
+func renderLimitsFromConfig(cfg *config.PixletConfig) RenderLimits {
+	var limits RenderLimits
+	if cfg.MaxExecutionSteps > 0 {
+		limits.MaxExecutionSteps = uint64(cfg.MaxExecutionSteps)
+	}
+	if cfg.MaxHeapGrowthMB > 0 {
+		limits.MaxHeapGrowth = uint64(cfg.MaxHeapGrowthMB) << 20
+	}
+	limits.AdaptiveTimeout = cfg.AdaptiveTimeout
+	limits.MinTimeout = secondsToDuration(cfg.AdaptiveTimeoutMin)
+	return limits
+}
+
+// queueFromConfig builds the worker pool's queue limits from config
+func queueFromConfig(cfg *config.PixletConfig) QueueConfig {
+	return QueueConfig{
+		Size:           cfg.QueueSize,
+		RejectWhenFull: cfg.QueueFullPolicy != "block",
+		Affinity:       cfg.WorkerAffinity,
+		Quarantine: QuarantineConfig{
+			Workers:   cfg.QuarantineWorkers,
+			Threshold: time.Duration(cfg.QuarantineThresholdMs) * time.Millisecond,
+			Strikes:   cfg.QuarantineStrikes,
+			Duration:  secondsToDuration(cfg.QuarantineDuration),
+		},
+	}
+}
+
+// outboundFromConfig builds the app HTTP client settings from config
+func outboundFromConfig(cfg *config.PixletConfig) OutboundConfig {
+	return OutboundConfig{
+		HostRate:         cfg.HTTPHostRate,
+		HostBurst:        cfg.HTTPHostBurst,
+		AppRate:          cfg.HTTPAppRate,
+		AppBurst:         cfg.HTTPAppBurst,
+		BreakerFailures:  cfg.HTTPBreakerFailures,
+		BreakerCooldown:  secondsToDuration(cfg.HTTPBreakerCooldown),
+		MaxConnsPerHost:  cfg.HTTPMaxConnsPerHost,
+		IdleConnsPerHost: cfg.HTTPIdleConnsPerHost,
+	}
+}
+
+// schemaPoolFromConfig starts the schema worker pool from config
+func schemaPoolFromConfig(cfg *config.PixletConfig) *schemaPool {
+	return newSchemaPool(cfg.SchemaWorkers, secondsToDuration(cfg.SchemaTimeout))
+}
+
+// scalingFromConfig builds the worker pool's autoscaling limits from config
+func scalingFromConfig(cfg *config.PixletConfig) ScalingConfig {
+	return ScalingConfig{
+		MaxWorkers:  cfg.RenderWorkersMax,
+		ScaleUpWait: time.Duration(cfg.WorkerScaleUpWaitMs) * time.Millisecond,
+		IdleTimeout: time.Duration(cfg.WorkerIdleTimeout) * time.Second,
+	}
+}
+
+// SharedRateLimiter returns the rate limiter whose buckets live in Redis, or nil
+// without Redis
+func (p *Processor) SharedRateLimiter() *SharedRateLimiter {
+	return p.sharedLimiter
+}
+
+// JobStore returns the store asynchronous render jobs are shared through, or
+// nil without Redis
+func (p *Processor) JobStore() *JobStore {
+	return p.jobStore
+}
+
+// DefaultDeviceSize returns the device size used when a request doesn't specify one
+func (p *Processor) DefaultDeviceSize() models.Size {
+	return p.defaultSize
+}
+
+// CheckDeviceSize returns ErrSizeNotAllowed if a whitelist is configured and size isn't on it
+func (p *Processor) CheckDeviceSize(size models.Size) error {
+	if len(p.allowedSizes) > 0 && !p.allowedSizes[size] {
+		return fmt.Errorf("%w: %s", ErrSizeNotAllowed, size)
+	}
+	return nil
+}
+
+// resolveDevice fills in default dimensions and enforces the size whitelist
+func (p *Processor) resolveDevice(device models.Device) (models.Device, error) {
+	if device.Width <= 0 {
+		device.Width = p.defaultSize.Width
+	}
+	if device.Height <= 0 {
+		device.Height = p.defaultSize.Height
+	}
+	if err := p.CheckDeviceSize(models.Size{Width: device.Width, Height: device.Height}); err != nil {
+		return device, err
+	}
+	return device, nil
+}
+
+// defaultSizeFromConfig returns the configured default device size, falling back to 64x32
+func defaultSizeFromConfig(cfg *config.PixletConfig) models.Size {
+	size := models.Size{Width: cfg.DefaultWidth, Height: cfg.DefaultHeight}
+	if size.Width <= 0 {
+		size.Width = 64
+	}
+	if size.Height <= 0 {
+		size.Height = 32
+	}
+	return size
+}
+
+// allowedSizesFromConfig parses the device size whitelist, skipping invalid entries
+func allowedSizesFromConfig(cfg *config.PixletConfig, logger *zap.Logger) map[models.Size]bool {
+	allowed := make(map[models.Size]bool)
+	for _, part := range strings.Split(cfg.AllowedSizes, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		size, err := models.ParseSize(part)
+		if err != nil {
+			logger.Warn("Ignoring invalid allowed size", zap.Error(err))
+			continue
+		}
+		allowed[size] = true
+	}
+	return allowed
 }
 
 // renderScreensDirect performs rendering directly without the worker pool (used for schema operations)
@@ -302,16 +788,6 @@ func (p *Processor) renderScreensDirect(ctx context.Context, appID string, param
 		return nil, fmt.Errorf("invalid app ID: %s", appID)
 	}
 
-	var requestCache runtime.Cache
-	if p.redisCache != nil {
-		requestCache = p.redisCache
-	} else {
-		requestCache = p.cache
-	}
-
-	runtime.InitHTTP(requestCache)
-	runtime.InitCache(requestCache)
-
 	app, exists := p.appRegistry.GetApp(appID)
 	if !exists {
 		return nil, fmt.Errorf("app not found: %s", appID)
@@ -363,7 +839,7 @@ func (p *Processor) renderScreensDirect(ctx context.Context, appID string, param
 	config["display_width"] = fmt.Sprintf("%d", width)
 	config["display_height"] = fmt.Sprintf("%d", height)
 
-	renderCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	renderCtx, cancel := context.WithTimeout(withCacheDevice(ctx, device.ID), p.timeout)
 	defer cancel()
 
 	// Use RunWithConfigAndDimensions to embed dimensions in roots for thread-safe rendering
@@ -408,8 +884,10 @@ func (p *Processor) RefreshAppRegistry() error {
 	// Create a new registry and load apps
 	newRegistry := models.NewAppRegistry()
 	if err := newRegistry.LoadApps(p.config.AppsPath); err != nil {
+		p.setAppsErr(err)
 		return fmt.Errorf("failed to load apps: %w", err)
 	}
+	p.setAppsErr(nil)
 
 	// Replace the current registry
 	p.appRegistry = newRegistry
@@ -426,15 +904,39 @@ func (p *Processor) RefreshAppRegistry() error {
 	return nil
 }
 
-// Stop gracefully shuts down the processor and its worker pool
+// Stop shuts down the processor and its worker pools immediately
 func (p *Processor) Stop() {
 	if p.workerPool != nil {
 		p.workerPool.Stop()
 	}
+	p.schemaPool.Stop()
+	p.streamRetention.Stop()
+	p.streamMetrics.Stop()
+	p.control.Stop()
+}
+
+// Drain shuts down the worker pool once accepted render jobs finish, failing
+// whatever is left when ctx ends. See WorkerPool.Drain.
+func (p *Processor) Drain(ctx context.Context) error {
+	defer p.schemaPool.Stop()
+	if p.workerPool == nil {
+		return nil
+	}
+	return p.workerPool.Drain(ctx)
 }
 
-// GetAppSchema returns the schema for a specific app
+// GetAppSchema returns the schema for a specific app, loading it on the schema pool
 func (p *Processor) GetAppSchema(ctx context.Context, appID string) (*schema.Schema, error) {
+	var appSchema *schema.Schema
+	err := p.schemaPool.Do(ctx, func(ctx context.Context) error {
+		var err error
+		appSchema, err = p.getAppSchema(ctx, appID)
+		return err
+	})
+	return appSchema, err
+}
+
+func (p *Processor) getAppSchema(ctx context.Context, appID string) (*schema.Schema, error) {
 	// Validate app ID (security: prevent path traversal)
 	if strings.Contains(appID, "..") || strings.Contains(appID, "/") {
 		return nil, fmt.Errorf("invalid app ID: %s", appID)
@@ -479,8 +981,18 @@ func (p *Processor) GetAppSchema(ctx context.Context, appID string) (*schema.Sch
 	return applet.Schema, nil
 }
 
-// CallSchemaHandler calls a schema handler for a specific app
+// CallSchemaHandler calls a schema handler for a specific app on the schema pool
 func (p *Processor) CallSchemaHandler(ctx context.Context, appID, handlerName, parameter string, config map[string]string) (string, error) {
+	var result string
+	err := p.schemaPool.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = p.callSchemaHandler(ctx, appID, handlerName, parameter, config)
+		return err
+	})
+	return result, err
+}
+
+func (p *Processor) callSchemaHandler(ctx context.Context, appID, handlerName, parameter string, config map[string]string) (string, error) {
 	// Validate app ID (security: prevent path traversal)
 	if strings.Contains(appID, "..") || strings.Contains(appID, "/") {
 		return "", fmt.Errorf("invalid app ID: %s", appID)
@@ -577,6 +1089,9 @@ func (p *Processor) resolveGeneratedHandlers(ctx context.Context, applet *runtim
 
 // Close closes the processor and any associated resources
 func (p *Processor) Close() error {
+	p.streamRetention.Stop()
+	p.streamMetrics.Stop()
+	p.control.Stop()
 	if p.redisCache != nil {
 		return p.redisCache.Close()
 	}
@@ -2,10 +2,14 @@ package pixlet
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"image"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/koios/matrx-renderer/internal/config"
 	"github.com/koios/matrx-renderer/pkg/models"
@@ -127,6 +131,41 @@ def main():
 	})
 }
 
+func TestRenderAppWithoutSecretKey(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "plain-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+
+	// Returns no screens, so the render completes without encoding WebP
+	appContent := `
+def main(config):
+    return []
+`
+	if err := os.WriteFile(filepath.Join(appDir, "plain-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "plain-app", "plain-app.star")
+
+	// Neither PIXLET_KEY_ENCRYPTION_KEY_B64 nor PIXLET_SECRET_KEYSET_B64 is set
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir}, zap.NewNop())
+	defer processor.Stop()
+
+	result, err := processor.RenderApp(context.Background(), &models.RenderRequest{
+		Type:   "render_request",
+		AppID:  "plain-app",
+		Device: models.Device{ID: "test-device", Width: 64, Height: 32},
+		Params: map[string]interface{}{},
+	})
+	if err != nil {
+		t.Fatalf("RenderApp failed without a secret key: %v", err)
+	}
+	if result.Error {
+		t.Error("Expected no error flag on result")
+	}
+}
+
 func TestAppStructureValidation(t *testing.T) {
 	// Create a temporary directory for testing
 	tempDir := t.TempDir()
@@ -194,6 +233,508 @@ func TestAppStructureValidation(t *testing.T) {
 	}
 }
 
+func TestRenderAppSkipped(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "empty-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+
+	appContent := `
+def main(config):
+    return []
+`
+	if err := os.WriteFile(filepath.Join(appDir, "empty-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "empty-app", "empty-app.star")
+
+	cfg := &config.PixletConfig{
+		AppsPath: tempDir,
+	}
+	processor := NewProcessor(cfg, zap.NewNop())
+	defer processor.Stop()
+
+	result, err := processor.RenderApp(context.Background(), &models.RenderRequest{
+		Type:   "render_request",
+		UUID:   "req-skip",
+		AppID:  "empty-app",
+		Device: models.Device{ID: "test-device", Width: 64, Height: 32},
+		Params: map[string]interface{}{},
+	})
+	if err != nil {
+		t.Fatalf("RenderApp failed: %v", err)
+	}
+	if !result.Skipped {
+		t.Error("Expected skipped=true for an app returning []")
+	}
+	if result.Error {
+		t.Error("Expected error=false for a skipped render")
+	}
+	if result.RenderOutput != "" {
+		t.Errorf("Expected empty render output, got %d bytes", len(result.RenderOutput))
+	}
+}
+
+func TestRenderAppPinnedClock(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "clock-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+
+	appContent := `
+load("time.star", "time")
+
+def main(config):
+    if time.now().unix != 1700000000:
+        fail("clock not pinned: %d" % time.now().unix)
+    return []
+`
+	if err := os.WriteFile(filepath.Join(appDir, "clock-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "clock-app", "clock-app.star")
+
+	cfg := &config.PixletConfig{
+		AppsPath: tempDir,
+	}
+	processor := NewProcessor(cfg, zap.NewNop())
+	defer processor.Stop()
+
+	renderTime := time.Unix(1700000000, 0).UTC()
+	result, err := processor.RenderApp(context.Background(), &models.RenderRequest{
+		Type:       "render_request",
+		UUID:       "req-clock",
+		AppID:      "clock-app",
+		Device:     models.Device{ID: "test-device", Width: 64, Height: 32},
+		Params:     map[string]interface{}{},
+		RenderTime: &renderTime,
+	})
+	if err != nil {
+		t.Fatalf("RenderApp failed: %v", err)
+	}
+	if result.Error {
+		t.Fatal("Expected render with pinned clock to succeed")
+	}
+	if !result.Skipped {
+		t.Error("Expected skipped=true from the pinned-clock app")
+	}
+}
+
+func TestRenderAppBatch(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "size-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+
+	// Skips at 64 wide and fails at any other width, so each size's result is distinguishable
+	appContent := `
+def main(config):
+    if config.get("display_width") == "64":
+        return []
+    fail("unsupported width " + config.get("display_width"))
+`
+	if err := os.WriteFile(filepath.Join(appDir, "size-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "size-app", "size-app.star")
+
+	cfg := &config.PixletConfig{
+		AppsPath: tempDir,
+	}
+	processor := NewProcessor(cfg, zap.NewNop())
+	defer processor.Stop()
+
+	request := &models.RenderRequest{
+		Type:   "render_request",
+		UUID:   "req-batch",
+		AppID:  "size-app",
+		Device: models.Device{ID: "test-device"},
+		Params: map[string]interface{}{},
+	}
+	sizes := []models.Size{{Width: 64, Height: 32}, {Width: 128, Height: 64}}
+
+	results, err := processor.RenderAppBatch(context.Background(), request, sizes)
+	if err != nil {
+		t.Fatalf("RenderAppBatch failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	small := results["64x32"]
+	if small == nil || !small.Skipped || small.Error {
+		t.Errorf("Expected 64x32 to be skipped without error, got %+v", small)
+	}
+	large := results["128x64"]
+	if large == nil || !large.Error {
+		t.Errorf("Expected 128x64 to carry an error, got %+v", large)
+	}
+
+	if _, err := processor.RenderAppBatch(context.Background(), request, nil); err == nil {
+		t.Error("Expected error when no sizes are given")
+	}
+}
+
+func TestStreamFramesMaxFrameCount(t *testing.T) {
+	tempDir := t.TempDir()
+
+	appContent := `
+load("render.star", "render")
+
+def main(config):
+    return render.Root(
+        child = render.Animation(children = [render.Box(width = i + 1, height = 1) for i in range(10)]),
+    )
+`
+	for _, id := range []string{"capped-app", "default-app"} {
+		appDir := filepath.Join(tempDir, id)
+		if err := os.MkdirAll(appDir, 0755); err != nil {
+			t.Fatalf("Failed to create app directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(appDir, id+".star"), []byte(appContent), 0644); err != nil {
+			t.Fatalf("Failed to create app file: %v", err)
+		}
+		writeManifest(t, appDir, id, id+".star")
+	}
+
+	// Per-app override in the manifest
+	f, err := os.OpenFile(filepath.Join(tempDir, "capped-app", "manifest.yaml"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open manifest: %v", err)
+	}
+	f.WriteString("maxFrameCount: 3\n")
+	f.Close()
+
+	cfg := &config.PixletConfig{
+		AppsPath:      tempDir,
+		MaxFrameCount: 6,
+	}
+	processor := NewProcessor(cfg, zap.NewNop())
+	defer processor.Stop()
+
+	tests := []struct {
+		appID string
+		want  int
+	}{
+		{"capped-app", 3},
+		{"default-app", 6},
+	}
+	for _, tt := range tests {
+		count := 0
+		err := processor.StreamFrames(context.Background(), tt.appID, map[string]interface{}{},
+			models.Device{ID: "test-device", Width: 64, Height: 32}, RenderOptions{},
+			func(frame Frame) error {
+				count++
+				return nil
+			})
+		if err != nil {
+			t.Fatalf("StreamFrames(%s) failed: %v", tt.appID, err)
+		}
+		if count != tt.want {
+			t.Errorf("StreamFrames(%s) emitted %d frames, want %d", tt.appID, count, tt.want)
+		}
+	}
+}
+
+func TestDeviceSizeLimits(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "box-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+	appContent := `
+load("render.star", "render")
+
+def main(config):
+    return render.Root(child = render.Box(color = "#f00"))
+`
+	if err := os.WriteFile(filepath.Join(appDir, "box-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "box-app", "box-app.star")
+
+	cfg := &config.PixletConfig{
+		AppsPath:      tempDir,
+		DefaultWidth:  128,
+		DefaultHeight: 64,
+		AllowedSizes:  "64x32, 128x64, bogus",
+	}
+	processor := NewProcessor(cfg, zap.NewNop())
+	defer processor.Stop()
+
+	if got := processor.DefaultDeviceSize(); got != (models.Size{Width: 128, Height: 64}) {
+		t.Errorf("DefaultDeviceSize() = %v, want 128x64", got)
+	}
+	if err := processor.CheckDeviceSize(models.Size{Width: 64, Height: 32}); err != nil {
+		t.Errorf("Expected 64x32 to be allowed, got %v", err)
+	}
+	if err := processor.CheckDeviceSize(models.Size{Width: 4096, Height: 4096}); !errors.Is(err, ErrSizeNotAllowed) {
+		t.Errorf("Expected ErrSizeNotAllowed for 4096x4096, got %v", err)
+	}
+
+	// Unset dimensions fall back to the configured default
+	var bounds image.Rectangle
+	err := processor.StreamFrames(context.Background(), "box-app", map[string]interface{}{},
+		models.Device{ID: "test-device"}, RenderOptions{},
+		func(frame Frame) error {
+			bounds = frame.Image.Bounds()
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("StreamFrames failed: %v", err)
+	}
+	if bounds.Dx() != 128 || bounds.Dy() != 64 {
+		t.Errorf("Expected default 128x64 frame, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	err = processor.StreamFrames(context.Background(), "box-app", map[string]interface{}{},
+		models.Device{ID: "test-device", Width: 4096, Height: 4096}, RenderOptions{},
+		func(frame Frame) error { return nil })
+	if !errors.Is(err, ErrSizeNotAllowed) {
+		t.Errorf("Expected ErrSizeNotAllowed from StreamFrames, got %v", err)
+	}
+}
+
+func TestRenderAppAppletCache(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "cached-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+	appFile := filepath.Join(appDir, "cached-app.star")
+	if err := os.WriteFile(appFile, []byte("def main(config):\n    return []\n"), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "cached-app", "cached-app.star")
+
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, AppletCacheSize: 4}, zap.NewNop())
+	defer processor.Stop()
+
+	render := func(renderTime *time.Time) (*models.RenderResult, error) {
+		return processor.RenderApp(context.Background(), &models.RenderRequest{
+			Type:       "render_request",
+			AppID:      "cached-app",
+			Device:     models.Device{ID: "test-device", Width: 64, Height: 32},
+			Params:     map[string]interface{}{},
+			RenderTime: renderTime,
+		})
+	}
+
+	if _, err := render(nil); err != nil {
+		t.Fatalf("RenderApp failed: %v", err)
+	}
+	if got := processor.workerPool.applets.Len(); got != 1 {
+		t.Fatalf("Expected 1 cached applet after render, got %d", got)
+	}
+
+	renderTime := time.Unix(1700000000, 0)
+	if _, err := render(&renderTime); err != nil {
+		t.Fatalf("RenderApp with pinned clock failed: %v", err)
+	}
+	if got := processor.workerPool.applets.Len(); got != 1 {
+		t.Errorf("Expected pinned-clock render to bypass the cache, got %d cached applets", got)
+	}
+
+	// A changed source file must be reloaded rather than served from the cache
+	if err := os.WriteFile(appFile, []byte("def main(config):\n    fail(\"reloaded\")\n"), 0644); err != nil {
+		t.Fatalf("Failed to update app file: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(appFile, later, later); err != nil {
+		t.Fatalf("Failed to touch app file: %v", err)
+	}
+	if _, err := render(nil); err == nil {
+		t.Error("Expected updated app to be reloaded and fail")
+	}
+
+	if err := processor.RefreshAppRegistry(); err != nil {
+		t.Fatalf("RefreshAppRegistry failed: %v", err)
+	}
+	if got := processor.workerPool.applets.Len(); got != 0 {
+		t.Errorf("Expected registry refresh to purge the cache, got %d cached applets", got)
+	}
+}
+
+func TestRenderAppConcurrent(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "sized-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+
+	// Each render fails unless it sees its own dimensions, so any shared state
+	// between concurrently running workers shows up as an error
+	appContent := `
+def main(config):
+    if config.width() != int(config.get("want_width")):
+        fail("got width %d, want %s" % (config.width(), config.get("want_width")))
+    return []
+`
+	if err := os.WriteFile(filepath.Join(appDir, "sized-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "sized-app", "sized-app.star")
+
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, RenderWorkers: 4, AppletCacheSize: 4, QueueFullPolicy: "block"}, zap.NewNop())
+	defer processor.Stop()
+
+	errs := make(chan error, 32)
+	for i := 0; i < cap(errs); i++ {
+		go func(width int) {
+			_, err := processor.RenderApp(context.Background(), &models.RenderRequest{
+				Type:   "render_request",
+				AppID:  "sized-app",
+				Device: models.Device{ID: "test-device", Width: width, Height: 32},
+				Params: map[string]interface{}{"want_width": fmt.Sprint(width)},
+			})
+			errs <- err
+		}(32 + i)
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("concurrent render failed: %v", err)
+		}
+	}
+}
+
+func TestRenderAppRenderCache(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, id := range []string{"cached-app", "uncached-app"} {
+		appDir := filepath.Join(tempDir, id)
+		if err := os.MkdirAll(appDir, 0755); err != nil {
+			t.Fatalf("Failed to create app directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(appDir, id+".star"), []byte("def main(config):\n    return []\n"), 0644); err != nil {
+			t.Fatalf("Failed to create app file: %v", err)
+		}
+		writeManifest(t, appDir, id, id+".star")
+	}
+
+	// Per-app TTL in the manifest; the server default leaves caching off
+	f, err := os.OpenFile(filepath.Join(tempDir, "cached-app", "manifest.yaml"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open manifest: %v", err)
+	}
+	f.WriteString("renderCacheTTL: 60\n")
+	f.Close()
+
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir}, zap.NewNop())
+	defer processor.Stop()
+
+	render := func(appID string) (*models.RenderResult, error) {
+		return processor.RenderApp(context.Background(), &models.RenderRequest{
+			Type:   "render_request",
+			AppID:  appID,
+			Device: models.Device{ID: "test-device", Width: 64, Height: 32},
+			Params: map[string]interface{}{"color": "red"},
+		})
+	}
+
+	for _, id := range []string{"cached-app", "uncached-app"} {
+		if result, err := render(id); err != nil || !result.Skipped {
+			t.Fatalf("%s: expected skipped render, got %+v, %v", id, result, err)
+		}
+		// Break the app; only a cached render can still succeed
+		if err := os.WriteFile(filepath.Join(tempDir, id, id+".star"), []byte("def main(config):\n    fail(\"rendered\")\n"), 0644); err != nil {
+			t.Fatalf("Failed to update app file: %v", err)
+		}
+	}
+
+	result, err := render("cached-app")
+	if err != nil {
+		t.Fatalf("Expected cached render, got error: %v", err)
+	}
+	if !result.Skipped || result.Error {
+		t.Errorf("Expected cached skipped result, got %+v", result)
+	}
+
+	if _, err := render("uncached-app"); err == nil {
+		t.Error("Expected uncached app to render again and fail")
+	}
+}
+
+func TestRenderAppStepLimit(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "loop-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+
+	appContent := `
+def main(config):
+    total = 0
+    for i in range(1000000000):
+        total += i
+    return []
+`
+	if err := os.WriteFile(filepath.Join(appDir, "loop-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "loop-app", "loop-app.star")
+
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, MaxExecutionSteps: 100000}, zap.NewNop())
+	defer processor.Stop()
+
+	start := time.Now()
+	result, err := processor.RenderApp(context.Background(), &models.RenderRequest{
+		Type:   "render_request",
+		AppID:  "loop-app",
+		Device: models.Device{ID: "test-device", Width: 64, Height: 32},
+		Params: map[string]interface{}{},
+	})
+	if err == nil {
+		t.Fatal("Expected runaway app to be aborted")
+	}
+	if !strings.Contains(err.Error(), "too many steps") {
+		t.Errorf("Expected step limit error, got: %v", err)
+	}
+	if !result.Error {
+		t.Error("Expected error flag on result")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Step limit took %v to abort the render", elapsed)
+	}
+}
+
+func TestRenderAppHeapGrowthLimit(t *testing.T) {
+	tempDir := t.TempDir()
+	appDir := filepath.Join(tempDir, "hungry-app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+
+	appContent := `
+def main(config):
+    data = []
+    for i in range(500):
+        data.append("x" * 1000000)
+    return []
+`
+	if err := os.WriteFile(filepath.Join(appDir, "hungry-app.star"), []byte(appContent), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, "hungry-app", "hungry-app.star")
+
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, MaxHeapGrowthMB: 32}, zap.NewNop())
+	defer processor.Stop()
+
+	result, err := processor.RenderApp(context.Background(), &models.RenderRequest{
+		Type:   "render_request",
+		AppID:  "hungry-app",
+		Device: models.Device{ID: "test-device", Width: 64, Height: 32},
+		Params: map[string]interface{}{},
+	})
+	if !errors.Is(err, ErrHeapGrowthExceeded) {
+		t.Fatalf("Expected heap growth error, got: %v", err)
+	}
+	if !result.Error {
+		t.Error("Expected error flag on result")
+	}
+}
+
 func writeManifest(t *testing.T, dir, id, fileName string) {
 	t.Helper()
 	manifest := fmt.Sprintf(`id: %s
@@ -0,0 +1,145 @@
+package pixlet
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ColorProfile describes how a panel displays color. Frames are passed through the
+// profile before encoding so devices don't have to correct colors in firmware.
+type ColorProfile struct {
+	BitDepth      int     `yaml:"bitDepth"`      // Bits per color channel, 1-8 (0 keeps full 8-bit color)
+	Gamma         float64 `yaml:"gamma"`         // Gamma exponent applied to each channel (0 or 1 is linear)
+	MaxBrightness float64 `yaml:"maxBrightness"` // Channel scale factor, 0-1 (0 keeps full brightness)
+
+	lut *[256]uint8 // Precomputed by LoadDeviceProfiles
+}
+
+// Validate checks that the profile values are within range
+func (c *ColorProfile) Validate() error {
+	if c.BitDepth < 0 || c.BitDepth > 8 {
+		return fmt.Errorf("bit depth must be 0 (unset) or 1-8, got %d", c.BitDepth)
+	}
+	if c.Gamma < 0 {
+		return fmt.Errorf("gamma must not be negative, got %g", c.Gamma)
+	}
+	if c.MaxBrightness < 0 || c.MaxBrightness > 1 {
+		return fmt.Errorf("max brightness must be between 0 and 1, got %g", c.MaxBrightness)
+	}
+	return nil
+}
+
+// buildLUT computes the per-channel mapping: gamma, then brightness, then
+// quantization to the panel's bit depth
+func (c *ColorProfile) buildLUT() *[256]uint8 {
+	gamma := c.Gamma
+	if gamma == 0 {
+		gamma = 1
+	}
+	brightness := c.MaxBrightness
+	if brightness == 0 {
+		brightness = 1
+	}
+	levels := 255.0
+	if c.BitDepth > 0 {
+		levels = float64(int(1)<<c.BitDepth - 1)
+	}
+
+	var lut [256]uint8
+	for i := range lut {
+		v := math.Pow(float64(i)/255, gamma) * brightness
+		v = math.Round(v*levels) / levels
+		lut[i] = uint8(math.Round(v * 255))
+	}
+	return &lut
+}
+
+// Apply returns a copy of img with the profile applied to its color channels
+func (c *ColorProfile) Apply(img image.Image) *image.RGBA {
+	lut := c.lut
+	if lut == nil {
+		lut = c.buildLUT()
+	}
+
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+	for i := 0; i < len(out.Pix); i += 4 {
+		out.Pix[i] = lut[out.Pix[i]]
+		out.Pix[i+1] = lut[out.Pix[i+1]]
+		out.Pix[i+2] = lut[out.Pix[i+2]]
+	}
+	return out
+}
+
+// filter wraps emit so every frame is passed through the profile first.
+// A nil profile leaves frames untouched.
+func (c *ColorProfile) filter(emit FrameFunc) FrameFunc {
+	if c == nil {
+		return emit
+	}
+	return func(frame Frame) error {
+		frame.Image = c.Apply(frame.Image)
+		return emit(frame)
+	}
+}
+
+// DeviceProfiles maps device IDs to named color profiles
+type DeviceProfiles struct {
+	Profiles map[string]*ColorProfile `yaml:"profiles"`
+	Devices  map[string]string        `yaml:"devices"` // Device ID -> profile name
+	Default  string                   `yaml:"default"` // Profile for devices not listed (optional)
+}
+
+// LoadDeviceProfiles reads and validates a device profiles YAML file
+func LoadDeviceProfiles(path string) (*DeviceProfiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device profiles file: %w", err)
+	}
+
+	var profiles DeviceProfiles
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse device profiles file: %w", err)
+	}
+
+	for name, profile := range profiles.Profiles {
+		if profile == nil {
+			return nil, fmt.Errorf("profile %q is empty", name)
+		}
+		if err := profile.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid profile %q: %w", name, err)
+		}
+		profile.lut = profile.buildLUT()
+	}
+	for deviceID, name := range profiles.Devices {
+		if _, ok := profiles.Profiles[name]; !ok {
+			return nil, fmt.Errorf("device %q references unknown profile %q", deviceID, name)
+		}
+	}
+	if profiles.Default != "" {
+		if _, ok := profiles.Profiles[profiles.Default]; !ok {
+			return nil, fmt.Errorf("default references unknown profile %q", profiles.Default)
+		}
+	}
+
+	return &profiles, nil
+}
+
+// Lookup returns the color profile for a device, or nil if none applies
+func (d *DeviceProfiles) Lookup(deviceID string) *ColorProfile {
+	if d == nil {
+		return nil
+	}
+	if name, ok := d.Devices[deviceID]; ok {
+		return d.Profiles[name]
+	}
+	if d.Default != "" {
+		return d.Profiles[d.Default]
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package pixlet
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestColorProfileApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile ColorProfile
+		in      uint8
+		want    uint8
+	}{
+		{"zero profile is identity", ColorProfile{}, 200, 200},
+		{"half brightness", ColorProfile{MaxBrightness: 0.5}, 255, 128},
+		{"gamma darkens midtones", ColorProfile{Gamma: 2}, 128, 64},
+		{"1-bit depth rounds down", ColorProfile{BitDepth: 1}, 100, 0},
+		{"1-bit depth rounds up", ColorProfile{BitDepth: 1}, 200, 255},
+		{"black stays black", ColorProfile{BitDepth: 5, Gamma: 2.2, MaxBrightness: 0.8}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+			img.Set(0, 0, color.RGBA{R: tt.in, G: tt.in, B: tt.in, A: 255})
+
+			got := tt.profile.Apply(img).RGBAAt(0, 0)
+			if got.R != tt.want || got.G != tt.want || got.B != tt.want {
+				t.Errorf("Apply(%d) = %+v, want channels %d", tt.in, got, tt.want)
+			}
+			if got.A != 255 {
+				t.Errorf("alpha = %d, want 255", got.A)
+			}
+			if img.RGBAAt(0, 0).R != tt.in {
+				t.Error("Apply modified the source image")
+			}
+		})
+	}
+}
+
+func TestColorProfileValidate(t *testing.T) {
+	tests := []struct {
+		profile ColorProfile
+		wantErr bool
+	}{
+		{ColorProfile{BitDepth: 5, Gamma: 2.2, MaxBrightness: 0.8}, false},
+		{ColorProfile{}, false},
+		{ColorProfile{BitDepth: 9}, true},
+		{ColorProfile{BitDepth: -1}, true},
+		{ColorProfile{Gamma: -1}, true},
+		{ColorProfile{MaxBrightness: 1.5}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.profile.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.profile, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadDeviceProfiles(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "profiles.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write profiles: %v", err)
+		}
+		return path
+	}
+
+	t.Run("looks up devices and falls back to default", func(t *testing.T) {
+		profiles, err := LoadDeviceProfiles(write(t, `
+profiles:
+  p3:
+    bitDepth: 5
+    gamma: 2.2
+  p5:
+    maxBrightness: 0.6
+devices:
+  device-a: p3
+default: p5
+`))
+		if err != nil {
+			t.Fatalf("LoadDeviceProfiles: %v", err)
+		}
+		if got := profiles.Lookup("device-a"); got == nil || got.BitDepth != 5 {
+			t.Errorf("Lookup(device-a) = %+v, want p3", got)
+		}
+		if got := profiles.Lookup("device-b"); got == nil || got.MaxBrightness != 0.6 {
+			t.Errorf("Lookup(device-b) = %+v, want default p5", got)
+		}
+	})
+
+	t.Run("no default leaves unknown devices unfiltered", func(t *testing.T) {
+		profiles, err := LoadDeviceProfiles(write(t, "profiles:\n  p3:\n    bitDepth: 5\n"))
+		if err != nil {
+			t.Fatalf("LoadDeviceProfiles: %v", err)
+		}
+		if got := profiles.Lookup("device-b"); got != nil {
+			t.Errorf("Lookup(device-b) = %+v, want nil", got)
+		}
+	})
+
+	t.Run("unknown profile reference is rejected", func(t *testing.T) {
+		if _, err := LoadDeviceProfiles(write(t, "profiles: {}\ndevices:\n  device-a: p3\n")); err == nil {
+			t.Error("expected error for unknown profile")
+		}
+	})
+
+	t.Run("invalid profile is rejected", func(t *testing.T) {
+		if _, err := LoadDeviceProfiles(write(t, "profiles:\n  p3:\n    bitDepth: 12\n")); err == nil {
+			t.Error("expected error for invalid bit depth")
+		}
+	})
+
+	t.Run("nil profiles lookup", func(t *testing.T) {
+		var profiles *DeviceProfiles
+		if got := profiles.Lookup("device-a"); got != nil {
+			t.Errorf("Lookup on nil = %+v, want nil", got)
+		}
+	})
+}
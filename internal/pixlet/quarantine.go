@@ -0,0 +1,160 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// QuarantineConfig moves apps that keep rendering slowly onto a small pool of
+// their own, so they can't hold up workers serving well-behaved apps
+type QuarantineConfig struct {
+	Workers   int           // Workers serving quarantined apps; 0 disables quarantine
+	Threshold time.Duration // Render time that counts as a strike (default: 10s); timeouts always do
+	Strikes   int           // Consecutive strikes that quarantine an app (default: 3)
+	Duration  time.Duration // How long an app stays quarantined (default: 10m)
+}
+
+// Default quarantine settings
+const (
+	defaultQuarantineThreshold = 10 * time.Second
+	defaultQuarantineStrikes   = 3
+	defaultQuarantineDuration  = 10 * time.Minute
+)
+
+// quarantine tracks slow renders per app and which apps are quarantined
+type quarantine struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	strikes   int
+	duration  time.Duration
+	apps      map[string]*quarantineState
+}
+
+type quarantineState struct {
+	strikes int       // consecutive slow renders
+	until   time.Time // quarantined until then; zero if not quarantined
+}
+
+// newQuarantine returns a tracker for cfg, or nil if quarantine is disabled
+func newQuarantine(cfg QuarantineConfig) *quarantine {
+	if cfg.Workers <= 0 {
+		return nil
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = defaultQuarantineThreshold
+	}
+	if cfg.Strikes <= 0 {
+		cfg.Strikes = defaultQuarantineStrikes
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = defaultQuarantineDuration
+	}
+	return &quarantine{
+		threshold: cfg.Threshold,
+		strikes:   cfg.Strikes,
+		duration:  cfg.Duration,
+		apps:      make(map[string]*quarantineState),
+	}
+}
+
+// contains reports whether appID is quarantined. Always false for a nil tracker.
+func (q *quarantine) contains(appID string) bool {
+	if q == nil {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	state, ok := q.apps[appID]
+	return ok && time.Now().Before(state.until)
+}
+
+// record counts a render of appID that took elapsed and ended with err, and
+// reports whether it put the app into quarantine
+func (q *quarantine) record(appID string, elapsed time.Duration, err error) bool {
+	if q == nil {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	slow := elapsed >= q.threshold || errors.Is(err, context.DeadlineExceeded)
+	state, ok := q.apps[appID]
+	if !slow {
+		// A good render resets the count, but doesn't end a quarantine early
+		if ok && !time.Now().Before(state.until) {
+			delete(q.apps, appID)
+		}
+		return false
+	}
+
+	if !ok {
+		state = &quarantineState{}
+		q.apps[appID] = state
+	}
+	if time.Now().Before(state.until) {
+		return false
+	}
+	state.strikes++
+	if state.strikes < q.strikes {
+		return false
+	}
+	state.strikes = 0
+	state.until = time.Now().Add(q.duration)
+	metricQuarantines.Inc()
+	return true
+}
+
+// list returns every quarantined app with the time it is released
+func (q *quarantine) list() map[string]time.Time {
+	apps := make(map[string]time.Time)
+	if q == nil {
+		return apps
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	for appID, state := range q.apps {
+		if now.Before(state.until) {
+			apps[appID] = state.until
+		}
+	}
+	return apps
+}
+
+// quarantineWorker serves jobs of quarantined apps until the pool stops
+func (wp *WorkerPool) quarantineWorker(id int) {
+	defer wp.wg.Done()
+
+	wp.logger.Debug("Quarantine worker started", zap.Int("worker_id", id))
+	for {
+		select {
+		case job := <-wp.quarantined:
+			wp.pickUp(job)
+			wp.processJob(id, job)
+		case <-wp.quit:
+			// Draining: finish what's queued, then exit
+			select {
+			case job := <-wp.quarantined:
+				wp.pickUp(job)
+				wp.processJob(id, job)
+			default:
+				return
+			}
+		case <-wp.ctx.Done():
+			return
+		}
+	}
+}
+
+// QuarantinedApps returns the apps currently routed to the quarantine pool, with
+// the time each is released
+func (p *Processor) QuarantinedApps() map[string]time.Time {
+	return p.workerPool.quarantine.list()
+}
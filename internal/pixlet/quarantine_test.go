@@ -0,0 +1,121 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func TestQuarantine(t *testing.T) {
+	if q := newQuarantine(QuarantineConfig{}); q != nil {
+		t.Fatal("Expected quarantine to be disabled without workers")
+	}
+
+	q := newQuarantine(QuarantineConfig{Workers: 1, Threshold: time.Second, Strikes: 3, Duration: time.Minute})
+
+	// Strikes must be consecutive
+	q.record("slow-app", 2*time.Second, nil)
+	q.record("slow-app", 2*time.Second, nil)
+	q.record("slow-app", 100*time.Millisecond, nil)
+	if q.record("slow-app", 2*time.Second, nil) || q.contains("slow-app") {
+		t.Fatal("Expected a fast render to reset the strike count")
+	}
+
+	q.record("slow-app", 2*time.Second, nil)
+	if !q.record("slow-app", 2*time.Second, nil) {
+		t.Fatal("Expected a third strike to quarantine the app")
+	}
+	if !q.contains("slow-app") {
+		t.Error("Expected slow-app to be quarantined")
+	}
+	if q.contains("fast-app") {
+		t.Error("Expected fast-app not to be quarantined")
+	}
+
+	// Fast renders while quarantined don't release the app early
+	q.record("slow-app", time.Millisecond, nil)
+	until, ok := q.list()["slow-app"]
+	if !ok || time.Until(until) <= 0 {
+		t.Errorf("Expected slow-app to stay quarantined, got %v", q.list())
+	}
+
+	var disabled *quarantine
+	if disabled.record("slow-app", time.Hour, nil) || disabled.contains("slow-app") || len(disabled.list()) != 0 {
+		t.Error("Expected a nil quarantine to do nothing")
+	}
+}
+
+func TestWorkerPoolQuarantine(t *testing.T) {
+	appsDir := t.TempDir()
+	writeTestApp(t, appsDir, "blank-app", "def main(config):\n    return []\n")
+	registry := models.NewAppRegistry()
+	if err := registry.LoadApps(appsDir); err != nil {
+		t.Fatalf("LoadApps: %v", err)
+	}
+
+	wp := NewWorkerPool(1, zap.NewNop(), registry, runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0, ScalingConfig{}, false,
+		QueueConfig{Quarantine: QuarantineConfig{Workers: 1, Threshold: time.Second, Strikes: 1}}, RenderLimits{})
+	wp.quarantine.record("blank-app", time.Minute, nil)
+
+	// Queue the job before starting the pool so we can see where it lands
+	errs := make(chan error, 1)
+	go func() {
+		_, err := wp.SubmitRoots(context.Background(), "blank-app", nil, models.Device{Width: 64, Height: 32}, RenderOptions{})
+		errs <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(wp.quarantined) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(wp.quarantined) != 1 || wp.queued() != 0 {
+		t.Fatalf("Expected the job on the quarantine queue, got %d quarantined and %d shared", len(wp.quarantined), wp.queued())
+	}
+
+	wp.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wp.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("quarantined job failed: %v", err)
+	}
+}
+
+func TestWorkerPoolQuarantinesTimeouts(t *testing.T) {
+	appsDir := t.TempDir()
+	writeTestApp(t, appsDir, "stalling-app", "def main(config):\n    for i in range(1000000000):\n        pass\n    return []\n")
+	registry := models.NewAppRegistry()
+	if err := registry.LoadApps(appsDir); err != nil {
+		t.Fatalf("LoadApps: %v", err)
+	}
+
+	// An adaptive timeout far below the slow threshold still counts as a strike
+	wp := NewWorkerPool(1, zap.NewNop(), registry, runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 4, ScalingConfig{}, false,
+		QueueConfig{Quarantine: QuarantineConfig{Workers: 1, Threshold: 10 * time.Second, Strikes: 1, Duration: time.Minute}},
+		RenderLimits{AdaptiveTimeout: true, MinTimeout: 100 * time.Millisecond})
+	wp.Start()
+	defer wp.Stop()
+	for i := 0; i < renderTimeMinSamples; i++ {
+		wp.renderTimes.record("stalling-app", time.Millisecond)
+	}
+
+	_, err := wp.SubmitRoots(context.Background(), "stalling-app", nil, models.Device{Width: 64, Height: 32}, RenderOptions{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the render to time out, got %v", err)
+	}
+	// The worker records the render just after handing back its result
+	deadline := time.Now().Add(2 * time.Second)
+	for !wp.quarantine.contains("stalling-app") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !wp.quarantine.contains("stalling-app") {
+		t.Error("Expected the timed out app to be quarantined")
+	}
+}
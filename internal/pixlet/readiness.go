@@ -0,0 +1,133 @@
+package pixlet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+)
+
+// readinessRedisTimeout bounds the Redis ping of a readiness check
+const readinessRedisTimeout = 2 * time.Second
+
+// ReadinessCheck is the outcome of one dependency check
+type ReadinessCheck struct {
+	Name  string            `json:"name"`
+	OK    bool              `json:"ok"`
+	Error string            `json:"error,omitempty"`
+	Redis *RedisDiagnostics `json:"redis,omitempty"` // On the redis check, once Redis answers
+}
+
+// RedisDiagnostics describes this instance's place in the render requests
+// stream, as Redis sees it, so a replica that is connected but not consuming
+// stands out. It is informational and never fails readiness.
+type RedisDiagnostics struct {
+	Stream         string `json:"stream"`
+	StreamExists   bool   `json:"stream_exists"`
+	Group          string `json:"group"`
+	GroupExists    bool   `json:"group_exists"`
+	Consumer       string `json:"consumer"`
+	ConsumerMember bool   `json:"consumer_member"` // The group has a consumer by this instance's name
+	Pending        int64  `json:"pending"`         // Entries delivered to this consumer but not yet acknowledged
+	// Seconds since this consumer last read from the stream successfully;
+	// omitted if it never has
+	LastReadSecondsAgo *float64 `json:"last_read_seconds_ago,omitempty"`
+}
+
+// Readiness checks whether the processor can serve renders: the apps path
+// loaded, the worker pool is accepting jobs and, when the processor uses
+// Redis, Redis answers. ready is false if any check failed.
+func (p *Processor) Readiness(ctx context.Context) (ready bool, checks []ReadinessCheck) {
+	ready = true
+	check := func(name string, err error) {
+		c := ReadinessCheck{Name: name, OK: err == nil}
+		if err != nil {
+			c.Error = err.Error()
+			ready = false
+		}
+		checks = append(checks, c)
+	}
+
+	if errp := p.appsErr.Load(); errp != nil {
+		check("apps", *errp)
+	} else {
+		check("apps", nil)
+	}
+
+	if p.workerPool == nil || p.workerPool.stopping() {
+		check("workers", ErrPoolStopped)
+	} else {
+		check("workers", nil)
+	}
+
+	if p.redisCache != nil {
+		ctx, cancel := context.WithTimeout(ctx, readinessRedisTimeout)
+		defer cancel()
+		if err := p.redisCache.Ping(ctx); err != nil {
+			check("redis", fmt.Errorf("redis unreachable: %w", err))
+		} else {
+			check("redis", nil)
+			checks[len(checks)-1].Redis = p.redisDiagnostics(ctx)
+		}
+	}
+
+	return ready, checks
+}
+
+// redisDiagnostics looks up the render requests stream, its consumer group and
+// this instance's consumer in it. Lookups that fail leave their fields unset.
+func (p *Processor) redisDiagnostics(ctx context.Context) *RedisDiagnostics {
+	client := p.redisCache.client
+	d := &RedisDiagnostics{Stream: renderRequestsStream(p.redisConfig)}
+	if p.redisConfig != nil {
+		d.Group = p.redisConfig.ConsumerGroup
+		d.Consumer = p.redisConfig.ConsumerName
+	}
+	if d.Consumer == "" {
+		d.Consumer = config.DefaultConsumerName()
+	}
+
+	if n, err := client.Exists(ctx, d.Stream).Result(); err != nil || n == 0 {
+		return d
+	}
+	d.StreamExists = true
+
+	groups, err := client.XInfoGroups(ctx, d.Stream).Result()
+	if err != nil {
+		return d
+	}
+	for _, g := range groups {
+		d.GroupExists = d.GroupExists || g.Name == d.Group
+	}
+	if !d.GroupExists {
+		return d
+	}
+
+	consumers, err := client.XInfoConsumers(ctx, d.Stream, d.Group).Result()
+	if err != nil {
+		return d
+	}
+	for _, c := range consumers {
+		if c.Name != d.Consumer {
+			continue
+		}
+		d.ConsumerMember = true
+		d.Pending = c.Pending
+		// Redis reports -1 for a consumer that never read successfully
+		if c.Inactive >= 0 {
+			seconds := c.Inactive.Seconds()
+			d.LastReadSecondsAgo = &seconds
+		}
+	}
+	return d
+}
+
+// setAppsErr records the outcome of the latest load of the apps path
+func (p *Processor) setAppsErr(err error) {
+	if err == nil {
+		p.appsErr.Store(nil)
+		return
+	}
+	p.appsErr.Store(&err)
+}
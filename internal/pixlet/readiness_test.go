@@ -0,0 +1,79 @@
+package pixlet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+)
+
+func readinessByName(checks []ReadinessCheck) map[string]ReadinessCheck {
+	byName := make(map[string]ReadinessCheck, len(checks))
+	for _, c := range checks {
+		byName[c.Name] = c
+	}
+	return byName
+}
+
+func TestReadiness(t *testing.T) {
+	processor := NewProcessor(&config.PixletConfig{AppsPath: t.TempDir()}, zap.NewNop())
+
+	ready, checks := processor.Readiness(context.Background())
+	if !ready {
+		t.Fatalf("Expected ready, got %+v", checks)
+	}
+	byName := readinessByName(checks)
+	if !byName["apps"].OK || !byName["workers"].OK {
+		t.Errorf("Expected apps and workers checks to pass, got %+v", checks)
+	}
+	if _, ok := byName["redis"]; ok {
+		t.Error("Redis should not be checked without Redis")
+	}
+
+	processor.Stop()
+	ready, checks = processor.Readiness(context.Background())
+	if ready || readinessByName(checks)["workers"].OK {
+		t.Errorf("Expected a stopped pool to fail readiness, got %+v", checks)
+	}
+}
+
+func TestReadiness_AppsPath(t *testing.T) {
+	appsPath := filepath.Join(t.TempDir(), "apps")
+	processor := NewProcessor(&config.PixletConfig{AppsPath: appsPath}, zap.NewNop())
+	defer processor.Stop()
+
+	ready, checks := processor.Readiness(context.Background())
+	if ready || readinessByName(checks)["apps"].Error == "" {
+		t.Fatalf("Expected a missing apps path to fail readiness, got %+v", checks)
+	}
+
+	// A refresh that loads the apps path clears the failure
+	if err := os.MkdirAll(appsPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.RefreshAppRegistry(); err != nil {
+		t.Fatalf("RefreshAppRegistry failed: %v", err)
+	}
+	if ready, checks := processor.Readiness(context.Background()); !ready {
+		t.Errorf("Expected ready after refresh, got %+v", checks)
+	}
+}
+
+func TestReadiness_Redis(t *testing.T) {
+	// Nothing listens on port 1, so the ping fails fast
+	processor := NewProcessorWithRedis(&config.PixletConfig{AppsPath: t.TempDir()},
+		&config.RedisConfig{Addr: "127.0.0.1:1"}, zap.NewNop())
+	defer processor.Stop()
+
+	ready, checks := processor.Readiness(context.Background())
+	redis, ok := readinessByName(checks)["redis"]
+	if ready || !ok || redis.OK {
+		t.Errorf("Expected an unreachable Redis to fail readiness, got %+v", checks)
+	}
+	if redis.Redis != nil {
+		t.Errorf("Expected no stream diagnostics without a connection, got %+v", redis.Redis)
+	}
+}
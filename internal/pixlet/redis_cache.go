@@ -3,6 +3,8 @@ package pixlet
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/koios/matrx-renderer/internal/config"
@@ -10,26 +12,73 @@ import (
 	"go.starlark.net/starlark"
 )
 
-// RedisCache implements the runtime.Cache interface using Redis
+// RedisCache implements the runtime.Cache interface using Redis. Keys carry
+// the app ID as a hash tag, so on Redis Cluster all of an app's keys share one
+// slot and FlushApp can find them on a single node.
 type RedisCache struct {
-	client *redis.Client
+	client   redis.UniversalClient
+	fillWait time.Duration // How long a miss waits for another render refilling the key; 0 doesn't wait
 }
 
-// NewRedisCache creates a new shared Redis cache instance
+// cacheFillPoll is how often a waiting miss checks whether the key was refilled
+const cacheFillPoll = 50 * time.Millisecond
+
+// NewRedisCache creates a new shared Redis cache instance. With a Sentinel
+// master name configured it follows the master through failovers; with cluster
+// addresses it routes keys across the cluster's shards.
 func NewRedisCache(cfg *config.RedisConfig) *RedisCache {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     cfg.Addr,
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
+	poolTimeout := time.Duration(cfg.PoolTimeoutMs) * time.Millisecond
+	dialTimeout := time.Duration(cfg.DialTimeoutMs) * time.Millisecond
+	readTimeout := time.Duration(cfg.ReadTimeoutMs) * time.Millisecond
+	writeTimeout := time.Duration(cfg.WriteTimeoutMs) * time.Millisecond
+
+	// Unset (zero) pool and timeout settings keep go-redis' defaults
+	var rdb redis.UniversalClient
+	switch {
+	case len(cfg.ClusterAddrs) > 0:
+		rdb = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.ClusterAddrs,
+			Password:     cfg.Password,
+			DialTimeout:  dialTimeout,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+			PoolSize:     cfg.PoolSize,
+			PoolTimeout:  poolTimeout,
+		})
+	case cfg.SentinelMasterName != "":
+		rdb = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.SentinelMasterName,
+			SentinelAddrs:    cfg.SentinelAddrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			DialTimeout:      dialTimeout,
+			ReadTimeout:      readTimeout,
+			WriteTimeout:     writeTimeout,
+			PoolSize:         cfg.PoolSize,
+			PoolTimeout:      poolTimeout,
+		})
+	default:
+		rdb = redis.NewClient(&redis.Options{
+			Addr:         cfg.Addr,
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			DialTimeout:  dialTimeout,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+			PoolSize:     cfg.PoolSize,
+			PoolTimeout:  poolTimeout,
+		})
+	}
 
 	return &RedisCache{
-		client: rdb,
+		client:   rdb,
+		fillWait: time.Duration(cfg.CacheFillWaitMs) * time.Millisecond,
 	}
 }
 
 // NewRedisCacheFromClient creates a new Redis cache instance from an existing client
-func NewRedisCacheFromClient(client *redis.Client) *RedisCache {
+func NewRedisCacheFromClient(client redis.UniversalClient) *RedisCache {
 	return &RedisCache{
 		client: client,
 	}
@@ -47,21 +96,14 @@ func (r *RedisCache) Ping(ctx context.Context) error {
 
 // Get retrieves a value from the Redis cache
 func (c *RedisCache) Get(thread *starlark.Thread, key string) ([]byte, bool, error) {
-	ctx := context.Background()
-	if thread != nil {
-		// Use thread context if available
-		if threadCtx := thread.Local("context"); threadCtx != nil {
-			if threadContext, ok := threadCtx.(context.Context); ok {
-				ctx = threadContext
-			}
-		}
-	}
+	ctx := threadContext(thread)
 
-	result, err := c.client.Get(ctx, key).Result()
+	cacheKey := appletCacheKey(key)
+	result, err := c.client.Get(ctx, cacheKey).Result()
 	if err != nil {
 		if err == redis.Nil {
 			// Key doesn't exist
-			return nil, false, nil
+			return c.awaitFill(ctx, cacheKey)
 		}
 		return nil, false, fmt.Errorf("failed to get key %s from Redis: %w", key, err)
 	}
@@ -69,24 +111,170 @@ func (c *RedisCache) Get(thread *starlark.Thread, key string) ([]byte, bool, err
 	return []byte(result), true, nil
 }
 
-// Set stores a value in the Redis cache with the specified TTL
-func (c *RedisCache) Set(thread *starlark.Thread, key string, value []byte, ttl int64) error {
-	ctx := context.Background()
-	if thread != nil {
-		// Use thread context if available
-		if threadCtx := thread.Local("context"); threadCtx != nil {
-			if threadContext, ok := threadCtx.(context.Context); ok {
-				ctx = threadContext
-			}
+// awaitFill handles a miss on cacheKey. The first render to miss takes a short
+// lock and gets the miss, so it fetches the value and sets it. Renders missing
+// while the lock is held wait up to fillWait for that value instead of all
+// fetching it from upstream at once, then get a miss and fetch it themselves.
+func (c *RedisCache) awaitFill(ctx context.Context, cacheKey string) ([]byte, bool, error) {
+	if c.fillWait <= 0 {
+		return nil, false, nil
+	}
+	lockKey := cacheFillLockKey(cacheKey)
+	locked, err := c.client.SetNX(ctx, lockKey, 1, c.fillWait).Result()
+	if err != nil || locked {
+		return nil, false, nil
+	}
+
+	timer := time.NewTimer(c.fillWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(cacheFillPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			return nil, false, nil
+		case <-ctx.Done():
+			return nil, false, nil
+		}
+		result, err := c.client.Get(ctx, cacheKey).Result()
+		if err == nil {
+			return []byte(result), true, nil
+		}
+		// A lock gone without a value means its holder is done without one
+		if err != redis.Nil || c.client.Exists(ctx, lockKey).Val() == 0 {
+			return nil, false, nil
 		}
 	}
+}
+
+// cacheFillLockKey returns the key locking the refill of cacheKey. It keeps
+// cacheKey's hash tag, so both live on the same cluster node.
+func cacheFillLockKey(cacheKey string) string {
+	return "matrx:fill:" + cacheKey
+}
+
+// Set stores a value in the Redis cache with the specified TTL
+func (c *RedisCache) Set(thread *starlark.Thread, key string, value []byte, ttl int64) error {
+	ctx := threadContext(thread)
 
 	expiration := time.Duration(ttl) * time.Second
 
-	err := c.client.Set(ctx, key, value, expiration).Err()
+	cacheKey := appletCacheKey(key)
+	err := c.client.Set(ctx, cacheKey, value, expiration).Err()
 	if err != nil {
 		return fmt.Errorf("failed to set key %s in Redis: %w", key, err)
 	}
+	if c.fillWait > 0 {
+		// Renders waiting on the refill see the value on their next poll anyway
+		c.client.Del(ctx, cacheFillLockKey(cacheKey))
+	}
 
 	return nil
 }
+
+// appletCacheKey hash-tags the app ID in a key from an applet's cache module,
+// which pixlet scopes as "pixlet:{app ID}:{key}"
+func appletCacheKey(key string) string {
+	rest, ok := strings.CutPrefix(key, "pixlet:")
+	if !ok {
+		return key
+	}
+	appID, appKey, ok := strings.Cut(rest, ":")
+	if !ok {
+		return key
+	}
+	return "pixlet:" + appKeyTag(appID) + ":" + appKey
+}
+
+// appKeyTag returns the hash tag grouping an app's keys into one cluster slot
+func appKeyTag(appID string) string {
+	return "{" + appID + "}"
+}
+
+// flushScanCount is the SCAN batch size when flushing an app's keys
+const flushScanCount = 500
+
+// FlushApp deletes an app's rendered output and applet cache entries, returning
+// how many keys were removed. On Redis Cluster the keys are scanned on the node
+// owning the app's slot, since SCAN only sees one node's keys.
+func (c *RedisCache) FlushApp(ctx context.Context, appID string) (int64, error) {
+	var scanner redis.Cmdable = c.client
+	if cluster, ok := c.client.(*redis.ClusterClient); ok {
+		node, err := cluster.MasterForKey(ctx, appKeyTag(appID))
+		if err != nil {
+			return 0, fmt.Errorf("failed to find the cluster node for app %s: %w", appID, err)
+		}
+		scanner = node
+	}
+
+	var deleted int64
+	tag := globEscape(appKeyTag(appID))
+	for _, pattern := range []string{"matrx:render:" + tag + ":*", "pixlet:" + tag + ":*"} {
+		var cursor uint64
+		for {
+			keys, next, err := scanner.Scan(ctx, cursor, pattern, flushScanCount).Result()
+			if err != nil {
+				return deleted, fmt.Errorf("failed to scan keys of app %s: %w", appID, err)
+			}
+			if len(keys) > 0 {
+				n, err := scanner.Del(ctx, keys...).Result()
+				if err != nil {
+					return deleted, fmt.Errorf("failed to delete keys of app %s: %w", appID, err)
+				}
+				deleted += n
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// CountAppKeys returns how many applet cache keys each app has, in a single
+// pass over the keyspace. On Redis Cluster every master is scanned.
+func (c *RedisCache) CountAppKeys(ctx context.Context) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	var mu sync.Mutex
+	count := func(ctx context.Context, node redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := node.Scan(ctx, cursor, "pixlet:{*}:*", flushScanCount).Result()
+			if err != nil {
+				return fmt.Errorf("failed to scan applet cache keys: %w", err)
+			}
+			mu.Lock()
+			for _, key := range keys {
+				if appID, _, ok := strings.Cut(strings.TrimPrefix(key, "pixlet:{"), "}:"); ok {
+					counts[appID]++
+				}
+			}
+			mu.Unlock()
+			if cursor = next; cursor == 0 {
+				return nil
+			}
+		}
+	}
+
+	if cluster, ok := c.client.(*redis.ClusterClient); ok {
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return count(ctx, node)
+		})
+		return counts, err
+	}
+	return counts, count(ctx, c.client)
+}
+
+// globEscape escapes SCAN MATCH metacharacters in s
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package pixlet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestAppletCacheKey(t *testing.T) {
+	tests := map[string]string{
+		"pixlet:weather:forecast":     "pixlet:{weather}:forecast",
+		"pixlet:weather:city:chicago": "pixlet:{weather}:city:chicago",
+		"pixlet:weather":              "pixlet:weather",
+		"other:key":                   "other:key",
+	}
+	for key, want := range tests {
+		if got := appletCacheKey(key); got != want {
+			t.Errorf("appletCacheKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestGlobEscape(t *testing.T) {
+	if got := globEscape("{a*b?[c]}"); got != `{a\*b\?\[c\]}` {
+		t.Errorf("globEscape = %q", got)
+	}
+}
+
+func TestNewRedisCache_Pool(t *testing.T) {
+	cache := NewRedisCache(&config.RedisConfig{Addr: "127.0.0.1:1", PoolSize: 64, ReadTimeoutMs: 500})
+	defer cache.Close()
+	opts := cache.client.(*redis.Client).Options()
+	if opts.PoolSize != 64 || opts.ReadTimeout != 500*time.Millisecond {
+		t.Errorf("Pool settings not applied: size %d, read timeout %v", opts.PoolSize, opts.ReadTimeout)
+	}
+	// Unset timeouts keep go-redis' defaults
+	if opts.DialTimeout != 5*time.Second {
+		t.Errorf("Expected the default dial timeout, got %v", opts.DialTimeout)
+	}
+}
+
+func TestCacheFillLockKey(t *testing.T) {
+	// The lock keeps the app's hash tag, landing on the value's cluster slot
+	if got := cacheFillLockKey("pixlet:{weather}:forecast"); got != "matrx:fill:pixlet:{weather}:forecast" {
+		t.Errorf("cacheFillLockKey = %q", got)
+	}
+}
+
+func TestRedisCache_MissWithoutRedis(t *testing.T) {
+	// With Redis unreachable a miss neither waits nor fails the render
+	cache := NewRedisCache(&config.RedisConfig{Addr: "127.0.0.1:1", CacheFillWaitMs: 5000})
+	defer cache.Close()
+	start := time.Now()
+	if _, ok, err := cache.awaitFill(context.Background(), "pixlet:{weather}:forecast"); ok || err != nil {
+		t.Errorf("Expected a plain miss, got %v, %v", ok, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected no wait without the lock, took %v", elapsed)
+	}
+}
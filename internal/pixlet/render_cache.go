@@ -0,0 +1,134 @@
+package pixlet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// renderCacheMaxEntries bounds the in-memory tier; Redis holds the overflow
+const renderCacheMaxEntries = 4096
+
+// renderCache stores encoded render output so identical requests within an app's
+// TTL skip Starlark entirely. Entries live in memory and, when configured, in Redis
+// so they are shared across replicas.
+type renderCache struct {
+	mu      sync.Mutex
+	entries map[string]renderCacheEntry
+	redis   *RedisCache // optional shared tier
+	lookups hitCounter
+}
+
+type renderCacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+func newRenderCache(redisCache *RedisCache) *renderCache {
+	return &renderCache{
+		entries: make(map[string]renderCacheEntry),
+		redis:   redisCache,
+	}
+}
+
+// Get returns cached output for key, checking memory before Redis. An empty,
+// non-nil slice is a cached render that displayed nothing.
+func (c *renderCache) Get(ctx context.Context, key string) (data []byte, ok bool) {
+	defer func() { c.lookups.record(ok) }()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		return entry.data, true
+	}
+
+	if c.redis == nil {
+		return nil, false
+	}
+	data, err := c.redis.client.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	if ttl, err := c.redis.client.PTTL(ctx, key).Result(); err == nil && ttl > 0 {
+		c.setLocal(key, data, ttl)
+	}
+	return data, true
+}
+
+// Set stores output for key in every tier for the given TTL
+func (c *renderCache) Set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	c.setLocal(key, data, ttl)
+	if c.redis == nil {
+		return nil
+	}
+	if err := c.redis.client.Set(ctx, key, data, ttl).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to store render in Redis: %w", err)
+	}
+	return nil
+}
+
+// forgetApp drops an app's output from the in-memory tier
+func (c *renderCache) forgetApp(appID string) {
+	prefix := renderCacheKeyPrefix(appID)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// setLocal stores an entry in memory, sweeping expired entries when full and
+// skipping the write if the cache is still full afterwards
+func (c *renderCache) setLocal(key string, data []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= renderCacheMaxEntries {
+		now := time.Now()
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= renderCacheMaxEntries {
+			return
+		}
+	}
+	c.entries[key] = renderCacheEntry{data: data, expires: time.Now().Add(ttl)}
+}
+
+// renderCacheKey identifies encoded output by app, size and format, plus a hash of
+// everything else that changes the bytes: config, render time, encoder settings,
+// the device's color profile and, with perDevice, the device itself
+func renderCacheKey(appID string, params map[string]interface{}, device models.Device, format string, opts RenderOptions, webp WebPOptions, profile *ColorProfile, perDevice bool) string {
+	job := &RenderJob{AppID: appID, Params: params, Device: device, Options: opts}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "job=%s\n", jobKey(job, perDevice))
+	fmt.Fprintf(h, "webp=%+v\n", webp)
+	if profile != nil {
+		fmt.Fprintf(h, "profile=%d/%g/%g\n", profile.BitDepth, profile.Gamma, profile.MaxBrightness)
+	}
+
+	return fmt.Sprintf("%s%dx%d:%s:%s", renderCacheKeyPrefix(appID), device.Width, device.Height, format, hex.EncodeToString(h.Sum(nil)))
+}
+
+// renderCacheKeyPrefix starts every render cache key of an app. The app ID is a
+// hash tag, see RedisCache.
+func renderCacheKeyPrefix(appID string) string {
+	return "matrx:render:" + appKeyTag(appID) + ":"
+}
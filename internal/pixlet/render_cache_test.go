@@ -0,0 +1,96 @@
+package pixlet
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+)
+
+func TestRenderCache(t *testing.T) {
+	ctx := context.Background()
+	cache := newRenderCache(nil)
+
+	if _, ok := cache.Get(ctx, "missing"); ok {
+		t.Error("expected miss for unknown key")
+	}
+
+	if err := cache.Set(ctx, "webp", []byte("data"), time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, ok := cache.Get(ctx, "webp"); !ok || string(got) != "data" {
+		t.Errorf("Get = %q, %v; want cached data", got, ok)
+	}
+
+	// Skipped renders are cached as empty output
+	cache.Set(ctx, "skipped", []byte{}, time.Minute)
+	if got, ok := cache.Get(ctx, "skipped"); !ok || len(got) != 0 {
+		t.Errorf("Get = %q, %v; want empty cached output", got, ok)
+	}
+
+	cache.Set(ctx, "expired", []byte("data"), -time.Second)
+	if _, ok := cache.Get(ctx, "expired"); ok {
+		t.Error("expected miss for expired entry")
+	}
+}
+
+func TestRenderCacheKey(t *testing.T) {
+	device := models.Device{ID: "device-a", Width: 64, Height: 32}
+	params := map[string]interface{}{"color": "red"}
+	webp := WebPOptions{Lossless: true, Quality: 75, Method: 4}
+	key := renderCacheKey("clock", params, device, "webp", RenderOptions{}, webp, nil, false)
+
+	if got := renderCacheKey("clock", params, models.Device{ID: "device-b", Width: 64, Height: 32}, "webp", RenderOptions{}, webp, nil, false); got != key {
+		t.Error("expected devices without a profile to share a key")
+	}
+
+	different := map[string]string{
+		"size":    renderCacheKey("clock", params, models.Device{ID: "device-a", Width: 128, Height: 64}, "webp", RenderOptions{}, webp, nil, false),
+		"format":  renderCacheKey("clock", params, device, "gif", RenderOptions{}, webp, nil, false),
+		"config":  renderCacheKey("clock", map[string]interface{}{"color": "blue"}, device, "webp", RenderOptions{}, webp, nil, false),
+		"webp":    renderCacheKey("clock", params, device, "webp", RenderOptions{}, WebPOptions{Quality: 50, Method: 4}, nil, false),
+		"profile": renderCacheKey("clock", params, device, "webp", RenderOptions{}, webp, &ColorProfile{BitDepth: 5}, false),
+		"time":    renderCacheKey("clock", params, device, "webp", RenderOptions{RenderTime: time.Unix(1700000000, 0)}, webp, nil, false),
+	}
+	for name, got := range different {
+		if got == key {
+			t.Errorf("%s: expected a different key", name)
+		}
+	}
+}
+
+func TestRenderCacheKey_PerDevice(t *testing.T) {
+	params := map[string]interface{}{"city": "chicago"}
+	webp := WebPOptions{Lossless: true, Quality: 75, Method: 4}
+	kitchen := models.Device{ID: "kitchen", Width: 64, Height: 32}
+	office := models.Device{ID: "office", Width: 64, Height: 32}
+
+	if renderCacheKey("weather", params, kitchen, "webp", RenderOptions{}, webp, nil, true) == renderCacheKey("weather", params, office, "webp", RenderOptions{}, webp, nil, true) {
+		t.Error("Expected each device's output to be cached apart when apps cache per device")
+	}
+}
+
+func TestRenderCache_ForgetApp(t *testing.T) {
+	ctx := context.Background()
+	cache := newRenderCache(nil)
+	device := models.Device{Width: 64, Height: 32}
+	clockKey := renderCacheKey("clock", nil, device, "webp", RenderOptions{}, WebPOptions{}, nil, false)
+	// An app whose ID extends another's keeps its entries
+	clocksKey := renderCacheKey("clock-2", nil, device, "webp", RenderOptions{}, WebPOptions{}, nil, false)
+	cache.Set(ctx, clockKey, []byte("clock"), time.Minute)
+	cache.Set(ctx, clocksKey, []byte("clock-2"), time.Minute)
+
+	if !strings.HasPrefix(clockKey, "matrx:render:{clock}:") {
+		t.Errorf("Expected the app ID as a hash tag, got %s", clockKey)
+	}
+
+	cache.forgetApp("clock")
+	if _, ok := cache.Get(ctx, clockKey); ok {
+		t.Error("Expected the app's output to be dropped")
+	}
+	if _, ok := cache.Get(ctx, clocksKey); !ok {
+		t.Error("Expected other apps' output to be kept")
+	}
+}
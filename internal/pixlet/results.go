@@ -0,0 +1,149 @@
+package pixlet
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+)
+
+// ResultFeed fans render results out to in-process subscribers by device ID, so
+// clients such as browser-based virtual devices can follow a device without Redis
+type ResultFeed struct {
+	mu   sync.Mutex
+	subs map[string]map[*ResultSubscription]struct{}
+}
+
+// ResultSubscription receives the results published for the devices it follows
+type ResultSubscription struct {
+	feed    *ResultFeed
+	results chan *models.RenderResult
+	devices map[string]struct{} // guarded by feed.mu
+	closed  bool                // guarded by feed.mu
+}
+
+// NewResultFeed creates an empty result feed
+func NewResultFeed() *ResultFeed {
+	return &ResultFeed{subs: make(map[string]map[*ResultSubscription]struct{})}
+}
+
+// Subscribe returns a subscription to the given devices that buffers up to
+// buffer results. A subscriber that falls behind loses its oldest results
+// rather than holding up the renderer.
+func (f *ResultFeed) Subscribe(deviceIDs []string, buffer int) *ResultSubscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	s := &ResultSubscription{
+		feed:    f,
+		results: make(chan *models.RenderResult, buffer),
+		devices: make(map[string]struct{}),
+	}
+	s.Add(deviceIDs...)
+	return s
+}
+
+// Publish delivers result to every subscriber of its device
+func (f *ResultFeed) Publish(result *models.RenderResult) {
+	if f == nil || result == nil || result.DeviceID == "" {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for s := range f.subs[result.DeviceID] {
+		for {
+			select {
+			case s.results <- result:
+			default:
+				// Full: drop the oldest result so the latest frame gets through
+				select {
+				case <-s.results:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+// Watched reports whether any subscriber follows deviceID, so callers can skip
+// building results nobody will receive
+func (f *ResultFeed) Watched(deviceID string) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs[deviceID]) > 0
+}
+
+// Results returns the channel results are delivered on. It is closed by Close.
+func (s *ResultSubscription) Results() <-chan *models.RenderResult {
+	return s.results
+}
+
+// Add follows more devices
+func (s *ResultSubscription) Add(deviceIDs ...string) {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for _, id := range deviceIDs {
+		if id == "" {
+			continue
+		}
+		s.devices[id] = struct{}{}
+		if s.feed.subs[id] == nil {
+			s.feed.subs[id] = make(map[*ResultSubscription]struct{})
+		}
+		s.feed.subs[id][s] = struct{}{}
+	}
+}
+
+// Remove stops following devices
+func (s *ResultSubscription) Remove(deviceIDs ...string) {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	for _, id := range deviceIDs {
+		s.unsubscribe(id)
+	}
+}
+
+// DeviceIDs returns the devices followed, sorted
+func (s *ResultSubscription) DeviceIDs() []string {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	ids := make([]string, 0, len(s.devices))
+	for id := range s.devices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Close stops all deliveries and closes the results channel
+func (s *ResultSubscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for id := range s.devices {
+		s.unsubscribe(id)
+	}
+	s.closed = true
+	close(s.results)
+}
+
+// unsubscribe removes s from deviceID's subscribers. Callers hold feed.mu.
+func (s *ResultSubscription) unsubscribe(deviceID string) {
+	delete(s.devices, deviceID)
+	subs := s.feed.subs[deviceID]
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(s.feed.subs, deviceID)
+	}
+}
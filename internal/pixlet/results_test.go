@@ -0,0 +1,85 @@
+package pixlet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+)
+
+func TestResultFeed_PublishesByDevice(t *testing.T) {
+	feed := NewResultFeed()
+	sub := feed.Subscribe([]string{"a", "b"}, 4)
+	defer sub.Close()
+
+	feed.Publish(&models.RenderResult{UUID: "1", DeviceID: "a"})
+	feed.Publish(&models.RenderResult{UUID: "2", DeviceID: "c"})
+	feed.Publish(&models.RenderResult{UUID: "3", DeviceID: "b"})
+
+	for _, want := range []string{"1", "3"} {
+		select {
+		case result := <-sub.Results():
+			if result.UUID != want {
+				t.Errorf("Expected result %s, got %s", want, result.UUID)
+			}
+		default:
+			t.Fatalf("Expected result %s to be delivered", want)
+		}
+	}
+	select {
+	case result := <-sub.Results():
+		t.Errorf("Expected no result for unfollowed devices, got %s", result.UUID)
+	default:
+	}
+}
+
+func TestResultFeed_DropsOldestWhenFull(t *testing.T) {
+	feed := NewResultFeed()
+	sub := feed.Subscribe([]string{"a"}, 2)
+	defer sub.Close()
+
+	for _, id := range []string{"1", "2", "3"} {
+		feed.Publish(&models.RenderResult{UUID: id, DeviceID: "a"})
+	}
+
+	var got []string
+	for len(sub.Results()) > 0 {
+		got = append(got, (<-sub.Results()).UUID)
+	}
+	if !reflect.DeepEqual(got, []string{"2", "3"}) {
+		t.Errorf("Expected the latest results, got %v", got)
+	}
+}
+
+func TestResultFeed_AddRemoveClose(t *testing.T) {
+	feed := NewResultFeed()
+	sub := feed.Subscribe([]string{"a"}, 4)
+
+	sub.Add("b", "")
+	sub.Remove("a")
+	if got := sub.DeviceIDs(); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("Expected [b], got %v", got)
+	}
+	if feed.Watched("a") || !feed.Watched("b") {
+		t.Error("Expected only b to be watched")
+	}
+
+	sub.Close()
+	sub.Close()
+	if feed.Watched("b") {
+		t.Error("Expected no devices watched after Close")
+	}
+	if _, ok := <-sub.Results(); ok {
+		t.Error("Expected the results channel to be closed")
+	}
+	// Publishing after Close must not panic on the closed channel
+	feed.Publish(&models.RenderResult{DeviceID: "b"})
+}
+
+func TestResultFeed_NilSafe(t *testing.T) {
+	var feed *ResultFeed
+	feed.Publish(&models.RenderResult{DeviceID: "a"})
+	if feed.Watched("a") {
+		t.Error("Expected a nil feed to watch nothing")
+	}
+}
@@ -0,0 +1,104 @@
+package pixlet
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Default schema pool settings
+const (
+	defaultSchemaWorkers = 2
+	defaultSchemaTimeout = 10 * time.Second
+)
+
+// schemaPool runs schema loads and schema handler calls on a small, fixed set of
+// workers kept apart from the render pool, so a burst of typeahead calls can
+// neither starve renders nor load applets without bound on HTTP goroutines.
+type schemaPool struct {
+	jobs     chan *schemaJob
+	timeout  time.Duration // covers both waiting for a worker and running
+	quit     chan struct{}
+	quitOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+type schemaJob struct {
+	ctx  context.Context
+	fn   func(context.Context) error
+	done chan error
+}
+
+// newSchemaPool starts a pool of workers that each run one call at a time
+func newSchemaPool(workers int, timeout time.Duration) *schemaPool {
+	if workers <= 0 {
+		workers = defaultSchemaWorkers
+	}
+	if timeout <= 0 {
+		timeout = defaultSchemaTimeout
+	}
+
+	sp := &schemaPool{
+		jobs:    make(chan *schemaJob),
+		timeout: timeout,
+		quit:    make(chan struct{}),
+	}
+	sp.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go sp.worker()
+	}
+	return sp
+}
+
+// Do runs fn on a pool worker and returns its error. It fails with the context's
+// error if no worker frees up, or fn doesn't finish, within the pool timeout.
+// A nil pool runs fn on the calling goroutine.
+func (sp *schemaPool) Do(ctx context.Context, fn func(context.Context) error) error {
+	if sp == nil {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sp.timeout)
+	defer cancel()
+
+	job := &schemaJob{ctx: ctx, fn: fn, done: make(chan error, 1)}
+	select {
+	case sp.jobs <- job:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-sp.quit:
+		return ErrPoolStopped
+	}
+
+	select {
+	case err := <-job.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (sp *schemaPool) worker() {
+	defer sp.wg.Done()
+	for {
+		select {
+		case job := <-sp.jobs:
+			if err := job.ctx.Err(); err != nil {
+				job.done <- err
+				continue
+			}
+			job.done <- job.fn(job.ctx)
+		case <-sp.quit:
+			return
+		}
+	}
+}
+
+// Stop rejects new calls and waits for running ones to finish
+func (sp *schemaPool) Stop() {
+	if sp == nil {
+		return
+	}
+	sp.quitOnce.Do(func() { close(sp.quit) })
+	sp.wg.Wait()
+}
@@ -0,0 +1,74 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchemaPoolBoundsConcurrency(t *testing.T) {
+	sp := newSchemaPool(2, time.Second)
+	defer sp.Stop()
+
+	var running, peak atomic.Int32
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			errs <- sp.Do(context.Background(), func(ctx context.Context) error {
+				n := running.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+				return nil
+			})
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Do: %v", err)
+		}
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", got)
+	}
+}
+
+func TestSchemaPoolTimeout(t *testing.T) {
+	sp := newSchemaPool(1, 20*time.Millisecond)
+	defer sp.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	go sp.Do(context.Background(), func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	// The only worker is busy, so this call times out waiting for it
+	time.Sleep(5 * time.Millisecond)
+	err := sp.Do(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do with a busy pool = %v, want deadline exceeded", err)
+	}
+}
+
+func TestSchemaPoolStop(t *testing.T) {
+	sp := newSchemaPool(1, time.Second)
+	sp.Stop()
+
+	if err := sp.Do(context.Background(), func(ctx context.Context) error { return nil }); err != ErrPoolStopped {
+		t.Errorf("Do after Stop = %v, want ErrPoolStopped", err)
+	}
+
+	var nilPool *schemaPool
+	if err := nilPool.Do(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("Do on nil pool = %v, want direct call", err)
+	}
+}
@@ -0,0 +1,87 @@
+package pixlet
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sharedRateLimitTimeout bounds one bucket update, so a slow Redis falls back to
+// local limiting instead of stalling the request
+const sharedRateLimitTimeout = 250 * time.Millisecond
+
+// tokenBucketScript updates a token bucket stored as a hash and returns how many
+// milliseconds the caller must wait. Time comes from the Redis server, so
+// replicas with skewed clocks agree. With ARGV[3] == "1" the caller reserves a
+// token, going into debt and waiting it out; otherwise it only takes a token
+// that is there.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local reserve = ARGV[3] == "1"
+local t = redis.call("TIME")
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(bucket[1]) or burst
+local last = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + (now - last) / 1000 * rate)
+
+local wait = 0
+if reserve then
+	tokens = tokens - 1
+	if tokens < 0 then
+		wait = math.ceil(-tokens / rate * 1000)
+	end
+elseif tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return wait
+`)
+
+// SharedRateLimiter keeps token buckets in Redis, so a rate limit holds across
+// every replica rather than per process. Buckets expire once they have refilled.
+type SharedRateLimiter struct {
+	client redis.UniversalClient
+}
+
+// newSharedRateLimiter returns a limiter over redisCache, or nil without Redis
+func newSharedRateLimiter(redisCache *RedisCache) *SharedRateLimiter {
+	if redisCache == nil {
+		return nil
+	}
+	return &SharedRateLimiter{client: redisCache.client}
+}
+
+// Take spends one of key's tokens. It returns zero if the caller may go ahead,
+// or how long until a token is available, in which case nothing is spent.
+func (l *SharedRateLimiter) Take(ctx context.Context, key string, rate, burst float64) (time.Duration, error) {
+	return l.run(ctx, key, rate, burst, false)
+}
+
+// Reserve takes one of key's tokens, going into debt if none is left, and
+// returns how long the caller must wait before using it
+func (l *SharedRateLimiter) Reserve(ctx context.Context, key string, rate, burst float64) (time.Duration, error) {
+	return l.run(ctx, key, rate, burst, true)
+}
+
+func (l *SharedRateLimiter) run(ctx context.Context, key string, rate, burst float64, reserve bool) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, sharedRateLimitTimeout)
+	defer cancel()
+
+	flag := "0"
+	if reserve {
+		flag = "1"
+	}
+	ms, err := tokenBucketScript.Run(ctx, l.client, []string{"matrx:ratelimit:" + key}, rate, burst, flag).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
@@ -0,0 +1,175 @@
+package pixlet
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point-in-time summary of the processor, served on /stats for
+// dashboards that can't scrape Prometheus. Counters cover this process since it
+// started.
+type Stats struct {
+	UptimeSeconds float64             `json:"uptime_seconds"`
+	Apps          int                 `json:"apps"`
+	Renders       AppStats            `json:"renders"`
+	RendersByApp  map[string]AppStats `json:"renders_by_app"`
+	Pool          PoolStats           `json:"pool"`
+	Cache         CacheStatsSet       `json:"cache"`
+	Stream        *StreamStats        `json:"stream,omitempty"` // Only with Redis configured
+}
+
+// StreamStats describes the Redis stream render requests are queued on
+type StreamStats struct {
+	Key    string             `json:"key"`
+	Length int64              `json:"length"`
+	Groups []StreamGroupStats `json:"groups"`
+}
+
+// StreamGroupStats describes how far a consumer group is behind on the stream
+type StreamGroupStats struct {
+	Name                 string  `json:"name"`
+	Consumers            int64   `json:"consumers"`
+	Pending              int64   `json:"pending"`                // Delivered but not yet acknowledged
+	Lag                  int64   `json:"lag"`                    // Not yet delivered; -1 when Redis can't tell
+	OldestPendingSeconds float64 `json:"oldest_pending_seconds"` // Since the oldest pending entry was last delivered
+}
+
+// AppStats counts render jobs processed by workers
+type AppStats struct {
+	Total  uint64 `json:"total"`
+	Errors uint64 `json:"errors"`
+}
+
+// PoolStats describes the render worker pool right now
+type PoolStats struct {
+	Workers           int     `json:"workers"`
+	BusyWorkers       int     `json:"busy_workers"`
+	Utilization       float64 `json:"utilization"` // busy / live workers
+	QueuedInteractive int     `json:"queued_interactive"`
+	QueuedBackground  int     `json:"queued_background"`
+	QueuedQuarantine  int     `json:"queued_quarantine"` // jobs of quarantined apps
+}
+
+// CacheStats counts lookups in one cache
+type CacheStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// CacheStatsSet groups the processor's caches
+type CacheStatsSet struct {
+	Render CacheStats `json:"render"` // encoded output
+	Applet CacheStats `json:"applet"` // loaded apps
+	HTTP   CacheStats `json:"http"`   // app HTTP responses
+}
+
+// hitCounter counts cache lookups
+type hitCounter struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func (c *hitCounter) record(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+func (c *hitCounter) stats() CacheStats {
+	stats := CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// appCounters counts processed jobs per app
+type appCounters struct {
+	mu   sync.Mutex
+	apps map[string]*AppStats
+}
+
+func newAppCounters() *appCounters {
+	return &appCounters{apps: make(map[string]*AppStats)}
+}
+
+func (c *appCounters) record(appID string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats, ok := c.apps[appID]
+	if !ok {
+		stats = &AppStats{}
+		c.apps[appID] = stats
+	}
+	stats.Total++
+	if err != nil {
+		stats.Errors++
+	}
+}
+
+// snapshot returns per-app counts and their sum
+func (c *appCounters) snapshot() (map[string]AppStats, AppStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	byApp := make(map[string]AppStats, len(c.apps))
+	var total AppStats
+	for appID, stats := range c.apps {
+		byApp[appID] = *stats
+		total.Total += stats.Total
+		total.Errors += stats.Errors
+	}
+	return byApp, total
+}
+
+// poolStats reports the pool's current size, load and queue depth
+func (wp *WorkerPool) poolStats() PoolStats {
+	interactive, background := wp.queuedByPriority()
+	stats := PoolStats{
+		Workers:           wp.Workers(),
+		BusyWorkers:       int(wp.busy.Load()),
+		QueuedInteractive: interactive,
+		QueuedBackground:  background,
+		QueuedQuarantine:  len(wp.quarantined),
+	}
+	if stats.Workers > 0 {
+		stats.Utilization = float64(stats.BusyWorkers) / float64(stats.Workers)
+	}
+	return stats
+}
+
+// statsRedisTimeout bounds the Redis lookups of a stats request
+const statsRedisTimeout = time.Second
+
+// Stats returns a summary of the processor's apps, renders, caches, worker pool
+// and, with Redis configured, the render requests stream
+func (p *Processor) Stats(ctx context.Context) Stats {
+	byApp, total := p.workerPool.renders.snapshot()
+
+	stats := Stats{
+		UptimeSeconds: time.Since(p.started).Seconds(),
+		Apps:          len(p.appRegistry.GetAllApps()),
+		Renders:       total,
+		RendersByApp:  byApp,
+		Pool:          p.workerPool.poolStats(),
+		Cache: CacheStatsSet{
+			Render: p.renderCache.lookups.stats(),
+			Applet: p.workerPool.applets.lookups.stats(),
+		},
+	}
+	if p.httpCache != nil {
+		stats.Cache.HTTP = p.httpCache.lookups.stats()
+	}
+	if p.redisCache != nil {
+		ctx, cancel := context.WithTimeout(ctx, statsRedisTimeout)
+		defer cancel()
+		stats.Stream = p.streamStats(ctx)
+	}
+	return stats
+}
@@ -0,0 +1,71 @@
+package pixlet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestProcessorStats(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestApp(t, tempDir, "empty-app", "def main(config):\n    return []\n")
+	writeTestApp(t, tempDir, "failing-app", "def main(config):\n    fail(\"boom\")\n")
+
+	processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, AppletCacheSize: 4, RenderWorkers: 2}, zap.NewNop())
+	defer processor.Stop()
+
+	render := func(appID string) error {
+		_, err := processor.RenderApp(context.Background(), &models.RenderRequest{
+			Type:   "render_request",
+			AppID:  appID,
+			Device: models.Device{ID: "test-device", Width: 64, Height: 32},
+			Params: map[string]interface{}{},
+		})
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := render("empty-app"); err != nil {
+			t.Fatalf("RenderApp failed: %v", err)
+		}
+	}
+	if err := render("failing-app"); err == nil {
+		t.Fatal("Expected failing-app to fail")
+	}
+
+	stats := processor.Stats(context.Background())
+	if stats.Apps != 2 {
+		t.Errorf("Expected 2 apps, got %d", stats.Apps)
+	}
+	if stats.Renders != (AppStats{Total: 3, Errors: 1}) {
+		t.Errorf("Expected 3 renders with 1 error, got %+v", stats.Renders)
+	}
+	if got := stats.RendersByApp["empty-app"]; got != (AppStats{Total: 2}) {
+		t.Errorf("Expected 2 successful empty-app renders, got %+v", got)
+	}
+	if got := stats.RendersByApp["failing-app"]; got != (AppStats{Total: 1, Errors: 1}) {
+		t.Errorf("Expected 1 failed failing-app render, got %+v", got)
+	}
+	if stats.Cache.Applet.Hits != 1 || stats.Cache.Applet.Misses != 2 {
+		t.Errorf("Expected 1 applet cache hit and 2 misses, got %+v", stats.Cache.Applet)
+	}
+	if stats.Pool.Workers != 2 || stats.Pool.BusyWorkers != 0 {
+		t.Errorf("Expected 2 idle workers, got %+v", stats.Pool)
+	}
+}
+
+func TestHitCounter(t *testing.T) {
+	var c hitCounter
+	if got := c.stats(); got.HitRatio != 0 {
+		t.Errorf("Expected a zero ratio before any lookup, got %v", got.HitRatio)
+	}
+	c.record(true)
+	c.record(true)
+	c.record(true)
+	c.record(false)
+	if got := c.stats(); got != (CacheStats{Hits: 3, Misses: 1, HitRatio: 0.75}) {
+		t.Errorf("Unexpected stats: %+v", got)
+	}
+}
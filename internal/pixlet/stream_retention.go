@@ -0,0 +1,121 @@
+package pixlet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+)
+
+// renderRequestsStream returns the Redis stream render requests are queued on
+func renderRequestsStream(cfg *config.RedisConfig) string {
+	if cfg == nil || cfg.StreamKey == "" {
+		return config.DefaultStreamKey
+	}
+	return cfg.StreamKey
+}
+
+// streamTrimTimeout bounds one round of trimming
+const streamTrimTimeout = 10 * time.Second
+
+// streamRetention trims the render requests stream, which producers append to
+// without bound, by length and by entry age. Trimming is approximate (XTRIM ~),
+// so Redis only drops whole macro nodes and it stays cheap.
+type streamRetention struct {
+	redis    *RedisCache
+	key      string
+	maxLen   int64
+	maxAge   time.Duration
+	interval time.Duration
+	logger   *zap.Logger
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// newStreamRetention returns a trimmer for cfg, or nil if Redis or both limits
+// are unset
+func newStreamRetention(redisCache *RedisCache, cfg *config.RedisConfig, logger *zap.Logger) *streamRetention {
+	if redisCache == nil || cfg == nil || (cfg.StreamMaxLen <= 0 && cfg.StreamMaxAge <= 0) {
+		return nil
+	}
+	interval := time.Duration(cfg.StreamTrimInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &streamRetention{
+		redis:    redisCache,
+		key:      renderRequestsStream(cfg),
+		maxLen:   int64(cfg.StreamMaxLen),
+		maxAge:   time.Duration(cfg.StreamMaxAge) * time.Second,
+		interval: interval,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start trims the stream now and then every interval until Stop
+func (s *streamRetention) Start() {
+	if s == nil {
+		return
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.trim()
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends trimming and waits for a round in progress. It is safe to call more
+// than once, and on a nil trimmer.
+func (s *streamRetention) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+func (s *streamRetention) trim() {
+	ctx, cancel := context.WithTimeout(context.Background(), streamTrimTimeout)
+	defer cancel()
+
+	var trimmed int64
+	if s.maxLen > 0 {
+		n, err := s.redis.client.XTrimMaxLenApprox(ctx, s.key, s.maxLen, 0).Result()
+		if err != nil {
+			s.logger.Warn("Failed to trim render requests stream by length", zap.Error(err))
+		}
+		trimmed += n
+	}
+	if s.maxAge > 0 {
+		n, err := s.redis.client.XTrimMinIDApprox(ctx, s.key, streamMinID(time.Now(), s.maxAge), 0).Result()
+		if err != nil {
+			s.logger.Warn("Failed to trim render requests stream by age", zap.Error(err))
+		}
+		trimmed += n
+	}
+	if trimmed > 0 {
+		s.logger.Debug("Trimmed render requests stream", zap.Int64("entries", trimmed))
+	}
+}
+
+// streamMinID returns the oldest stream entry ID kept when entries expire after
+// maxAge. Auto-generated IDs start with their creation time in milliseconds.
+func streamMinID(now time.Time, maxAge time.Duration) string {
+	return fmt.Sprintf("%d-0", now.Add(-maxAge).UnixMilli())
+}
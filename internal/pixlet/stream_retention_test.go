@@ -0,0 +1,55 @@
+package pixlet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestStreamMinID(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	if got := streamMinID(now, time.Hour); got != "1699996400000-0" {
+		t.Errorf("streamMinID = %q, want 1699996400000-0", got)
+	}
+}
+
+func TestNewStreamRetention(t *testing.T) {
+	redisCache := NewRedisCache(&config.RedisConfig{Addr: "127.0.0.1:1"})
+	defer redisCache.Close()
+
+	if newStreamRetention(nil, &config.RedisConfig{StreamMaxLen: 10}, zap.NewNop()) != nil {
+		t.Error("Expected no trimming without Redis")
+	}
+	if newStreamRetention(redisCache, &config.RedisConfig{}, zap.NewNop()) != nil {
+		t.Error("Expected no trimming without limits")
+	}
+
+	s := newStreamRetention(redisCache, &config.RedisConfig{StreamMaxLen: 10, StreamMaxAge: 60}, zap.NewNop())
+	if s == nil || s.interval != time.Minute || s.maxAge != time.Minute || s.key != config.DefaultStreamKey {
+		t.Fatalf("Unexpected trimmer %+v", s)
+	}
+	if s := newStreamRetention(redisCache, &config.RedisConfig{StreamKey: "staging:render_requests", StreamMaxLen: 10}, zap.NewNop()); s.key != "staging:render_requests" {
+		t.Errorf("Expected the configured stream key, got %s", s.key)
+	}
+
+	// Trimming an unreachable Redis logs and carries on; Stop is idempotent
+	s.Start()
+	s.Stop()
+	s.Stop()
+
+	var nilTrimmer *streamRetention
+	nilTrimmer.Start()
+	nilTrimmer.Stop()
+}
+
+func TestStats_StreamWithoutRedis(t *testing.T) {
+	processor := NewProcessor(&config.PixletConfig{AppsPath: t.TempDir()}, zap.NewNop())
+	defer processor.Stop()
+
+	if stats := processor.Stats(context.Background()); stats.Stream != nil {
+		t.Errorf("Expected no stream stats without Redis, got %+v", stats.Stream)
+	}
+}
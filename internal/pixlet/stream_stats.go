@@ -0,0 +1,201 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// streamStats reports the render requests stream's length and how far each
+// consumer group is behind, or nil without Redis or when Redis doesn't answer
+func (p *Processor) streamStats(ctx context.Context) *StreamStats {
+	if p.redisCache == nil {
+		return nil
+	}
+	key := renderRequestsStream(p.redisConfig)
+	client := p.redisCache.client
+
+	length, err := client.XLen(ctx, key).Result()
+	if err != nil {
+		return nil
+	}
+	stats := &StreamStats{Key: key, Length: length, Groups: []StreamGroupStats{}}
+
+	// Fails on a stream that was never created, which has no groups either
+	groups, err := client.XInfoGroups(ctx, key).Result()
+	if err != nil {
+		return stats
+	}
+	for _, group := range groups {
+		g := StreamGroupStats{
+			Name:      group.Name,
+			Consumers: group.Consumers,
+			Pending:   group.Pending,
+			Lag:       group.Lag,
+		}
+		if group.Pending > 0 {
+			oldest, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+				Stream: key,
+				Group:  group.Name,
+				Start:  "-",
+				End:    "+",
+				Count:  1,
+			}).Result()
+			if err == nil && len(oldest) > 0 {
+				g.OldestPendingSeconds = oldest[0].Idle.Seconds()
+			}
+		}
+		stats.Groups = append(stats.Groups, g)
+	}
+	return stats
+}
+
+// ErrNoRedis is returned by operations that need Redis when none is configured
+var ErrNoRedis = errors.New("redis is not configured")
+
+// QueueStats describes the render requests stream in more detail than /stats,
+// down to what each consumer holds, for diagnosing a backlog
+type QueueStats struct {
+	Stream    *StreamStats          `json:"stream"`
+	Consumers []StreamConsumerStats `json:"consumers"`
+}
+
+// StreamConsumerStats describes one consumer of a group on the stream
+type StreamConsumerStats struct {
+	Group           string  `json:"group"`
+	Name            string  `json:"name"`
+	Pending         int64   `json:"pending"`          // Delivered to this consumer but not yet acknowledged
+	IdleSeconds     float64 `json:"idle_seconds"`     // Since the consumer last read or claimed an entry
+	InactiveSeconds float64 `json:"inactive_seconds"` // Since its last successful read; -1 if it never read
+}
+
+// QueueStats reports the render requests stream with each group's consumers. It
+// returns ErrNoRedis without Redis, and Redis' error when it doesn't answer.
+func (p *Processor) QueueStats(ctx context.Context) (*QueueStats, error) {
+	if p.redisCache == nil {
+		return nil, ErrNoRedis
+	}
+	ctx, cancel := context.WithTimeout(ctx, statsRedisTimeout)
+	defer cancel()
+
+	key := renderRequestsStream(p.redisConfig)
+	client := p.redisCache.client
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+	stream := p.streamStats(ctx)
+	if stream == nil {
+		return nil, errors.New("failed to read render requests stream")
+	}
+
+	stats := &QueueStats{Stream: stream, Consumers: []StreamConsumerStats{}}
+	for _, group := range stream.Groups {
+		consumers, err := client.XInfoConsumers(ctx, key, group.Name).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range consumers {
+			inactive := c.Inactive.Seconds()
+			if c.Inactive < 0 {
+				inactive = -1
+			}
+			stats.Consumers = append(stats.Consumers, StreamConsumerStats{
+				Group:           group.Name,
+				Name:            c.Name,
+				Pending:         c.Pending,
+				IdleSeconds:     c.Idle.Seconds(),
+				InactiveSeconds: inactive,
+			})
+		}
+	}
+	return stats, nil
+}
+
+// streamMetrics samples streamStats into the matrx_render_stream_* gauges, since
+// Redis state can't be counted in process like the rest of /metrics
+type streamMetrics struct {
+	processor *Processor
+	interval  time.Duration
+	logger    *zap.Logger
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+}
+
+// newStreamMetrics returns a sampler for p, or nil without Redis or with a
+// non-positive interval
+func newStreamMetrics(p *Processor, interval time.Duration) *streamMetrics {
+	if p.redisCache == nil || interval <= 0 {
+		return nil
+	}
+	return &streamMetrics{
+		processor: p,
+		interval:  interval,
+		logger:    p.logger,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start samples now and then every interval until Stop
+func (m *streamMetrics) Start() {
+	if m == nil {
+		return
+	}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			m.sample()
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends sampling and waits for a sample in progress. It is safe to call more
+// than once, and on a nil sampler.
+func (m *streamMetrics) Stop() {
+	if m == nil {
+		return
+	}
+	m.stopOnce.Do(func() {
+		close(m.stop)
+		<-m.done
+	})
+}
+
+func (m *streamMetrics) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), statsRedisTimeout)
+	defer cancel()
+
+	stats := m.processor.streamStats(ctx)
+	if stats == nil {
+		m.logger.Debug("Failed to sample render requests stream")
+		return
+	}
+	setStreamMetrics(stats)
+}
+
+// setStreamMetrics replaces the stream gauges with stats, dropping groups that
+// no longer exist
+func setStreamMetrics(stats *StreamStats) {
+	metricStreamLength.Set(float64(stats.Length))
+	for _, vec := range []interface{ Reset() }{metricStreamLag, metricStreamPending, metricStreamOldestPending, metricStreamConsumers} {
+		vec.Reset()
+	}
+	for _, g := range stats.Groups {
+		metricStreamLag.WithLabelValues(g.Name).Set(float64(g.Lag))
+		metricStreamPending.WithLabelValues(g.Name).Set(float64(g.Pending))
+		metricStreamOldestPending.WithLabelValues(g.Name).Set(g.OldestPendingSeconds)
+		metricStreamConsumers.WithLabelValues(g.Name).Set(float64(g.Consumers))
+	}
+}
@@ -0,0 +1,70 @@
+package pixlet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestSetStreamMetrics(t *testing.T) {
+	setStreamMetrics(&StreamStats{Length: 12, Groups: []StreamGroupStats{
+		{Name: "renderers", Consumers: 3, Pending: 2, Lag: 5, OldestPendingSeconds: 42},
+		{Name: "archivers", Consumers: 1, Lag: -1},
+	}})
+	if got := testutil.ToFloat64(metricStreamLength); got != 12 {
+		t.Errorf("stream length = %v, want 12", got)
+	}
+	if got := testutil.ToFloat64(metricStreamLag.WithLabelValues("renderers")); got != 5 {
+		t.Errorf("lag = %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(metricStreamOldestPending.WithLabelValues("renderers")); got != 42 {
+		t.Errorf("oldest pending = %v, want 42", got)
+	}
+
+	// A deleted group stops being reported
+	setStreamMetrics(&StreamStats{Length: 12, Groups: []StreamGroupStats{{Name: "renderers", Consumers: 3}}})
+	if n := testutil.CollectAndCount(metricStreamConsumers); n != 1 {
+		t.Errorf("Expected 1 group, got %d", n)
+	}
+}
+
+func TestNewStreamMetrics(t *testing.T) {
+	processor := NewProcessor(&config.PixletConfig{AppsPath: t.TempDir()}, zap.NewNop())
+	defer processor.Stop()
+	if newStreamMetrics(processor, time.Second) != nil {
+		t.Error("Expected no sampling without Redis")
+	}
+
+	redisProcessor := NewProcessorWithRedis(&config.PixletConfig{AppsPath: t.TempDir()}, &config.RedisConfig{Addr: "127.0.0.1:1"}, zap.NewNop())
+	defer redisProcessor.Close()
+	defer redisProcessor.Stop()
+	if newStreamMetrics(redisProcessor, 0) != nil {
+		t.Error("Expected no sampling with a zero interval")
+	}
+
+	// Sampling an unreachable Redis leaves the gauges alone
+	m := newStreamMetrics(redisProcessor, time.Hour)
+	m.Start()
+	m.Stop()
+	m.Stop()
+}
+
+func TestQueueStats_Unavailable(t *testing.T) {
+	processor := NewProcessor(&config.PixletConfig{AppsPath: t.TempDir()}, zap.NewNop())
+	defer processor.Stop()
+	if _, err := processor.QueueStats(context.Background()); !errors.Is(err, ErrNoRedis) {
+		t.Errorf("Expected ErrNoRedis, got %v", err)
+	}
+
+	redisProcessor := NewProcessorWithRedis(&config.PixletConfig{AppsPath: t.TempDir()}, &config.RedisConfig{Addr: "127.0.0.1:1"}, zap.NewNop())
+	defer redisProcessor.Close()
+	defer redisProcessor.Stop()
+	if _, err := redisProcessor.QueueStats(context.Background()); err == nil || errors.Is(err, ErrNoRedis) {
+		t.Errorf("Expected a Redis error, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package pixlet
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Adaptive timeout tuning
+const (
+	renderTimeWindow      = 100             // recent renders kept per app
+	renderTimeMinSamples  = 20              // renders needed before an app's timeout adapts
+	adaptiveTimeoutFactor = 4               // timeout as a multiple of the app's p95
+	defaultMinTimeout     = 2 * time.Second // floor when RenderLimits.MinTimeout is unset
+)
+
+// renderTimes tracks recent render durations per app and derives a timeout for
+// each from its p95, so a normally fast app that stalls is killed long before the
+// global timeout while slow apps keep the time they usually need
+type renderTimes struct {
+	mu   sync.Mutex
+	min  time.Duration
+	max  time.Duration
+	apps map[string]*renderWindow
+}
+
+// renderWindow is a ring of an app's most recent render durations, with the
+// timeout derived from them
+type renderWindow struct {
+	samples []time.Duration
+	next    int
+	timeout time.Duration // 0 until enough samples are in
+}
+
+func newRenderTimes(min, max time.Duration) *renderTimes {
+	if min <= 0 {
+		min = defaultMinTimeout
+	}
+	if min > max {
+		min = max
+	}
+	return &renderTimes{min: min, max: max, apps: make(map[string]*renderWindow)}
+}
+
+// timeout returns how long a render of appID may run: the global maximum until
+// the app has enough history, then its p95 times adaptiveTimeoutFactor, between
+// the floor and the maximum. A nil tracker always returns the maximum.
+func (r *renderTimes) timeout(appID string, max time.Duration) time.Duration {
+	if r == nil {
+		return max
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w, ok := r.apps[appID]; ok && w.timeout > 0 {
+		return w.timeout
+	}
+	return r.max
+}
+
+// record adds a render duration for appID. Renders that hit their timeout are
+// recorded at the timeout, so an app that has genuinely slowed down raises its
+// own limit instead of timing out forever.
+func (r *renderTimes) record(appID string, d time.Duration) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.apps[appID]
+	if !ok {
+		w = &renderWindow{samples: make([]time.Duration, 0, renderTimeWindow)}
+		r.apps[appID] = w
+	}
+	if len(w.samples) < renderTimeWindow {
+		w.samples = append(w.samples, d)
+	} else {
+		w.samples[w.next] = d
+		w.next = (w.next + 1) % renderTimeWindow
+	}
+	if len(w.samples) < renderTimeMinSamples {
+		return
+	}
+
+	w.timeout = percentile(w.samples, 0.95) * adaptiveTimeoutFactor
+	if w.timeout < r.min {
+		w.timeout = r.min
+	}
+	if w.timeout > r.max {
+		w.timeout = r.max
+	}
+}
+
+// forget drops every app's history, used when apps are reloaded
+func (r *renderTimes) forget() {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apps = make(map[string]*renderWindow)
+}
+
+// percentile returns the p-th percentile (0-1) of samples by nearest rank
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,103 @@
+package pixlet
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func TestRenderTimes(t *testing.T) {
+	r := newRenderTimes(time.Second, 30*time.Second)
+
+	for i := 0; i < renderTimeMinSamples-1; i++ {
+		r.record("fast-app", 100*time.Millisecond)
+	}
+	if got := r.timeout("fast-app", 30*time.Second); got != 30*time.Second {
+		t.Errorf("Expected the global timeout before enough samples, got %s", got)
+	}
+
+	r.record("fast-app", 100*time.Millisecond)
+	if got := r.timeout("fast-app", 30*time.Second); got != time.Second {
+		t.Errorf("Expected the floor for a fast app, got %s", got)
+	}
+
+	for i := 0; i < renderTimeMinSamples; i++ {
+		r.record("medium-app", 2*time.Second)
+	}
+	if got := r.timeout("medium-app", 30*time.Second); got != 8*time.Second {
+		t.Errorf("Expected 4x p95 for a medium app, got %s", got)
+	}
+
+	for i := 0; i < renderTimeMinSamples; i++ {
+		r.record("slow-app", 20*time.Second)
+	}
+	if got := r.timeout("slow-app", 30*time.Second); got != 30*time.Second {
+		t.Errorf("Expected the global timeout to cap a slow app, got %s", got)
+	}
+
+	// Only the most recent window counts
+	for i := 0; i < renderTimeWindow; i++ {
+		r.record("medium-app", 100*time.Millisecond)
+	}
+	if got := r.timeout("medium-app", 30*time.Second); got != time.Second {
+		t.Errorf("Expected old samples to age out, got %s", got)
+	}
+
+	r.forget()
+	if got := r.timeout("fast-app", 30*time.Second); got != 30*time.Second {
+		t.Errorf("Expected forget to reset history, got %s", got)
+	}
+
+	var disabled *renderTimes
+	disabled.record("fast-app", time.Millisecond)
+	if got := disabled.timeout("fast-app", 30*time.Second); got != 30*time.Second {
+		t.Errorf("Expected a nil tracker to use the global timeout, got %s", got)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	samples := make([]time.Duration, 100)
+	for i := range samples {
+		samples[i] = time.Duration(100-i) * time.Millisecond
+	}
+	if got := percentile(samples, 0.95); got != 95*time.Millisecond {
+		t.Errorf("p95 = %s, want 95ms", got)
+	}
+	if got := percentile(nil, 0.95); got != 0 {
+		t.Errorf("p95 of no samples = %s, want 0", got)
+	}
+}
+
+func TestWorkerPoolAdaptiveTimeout(t *testing.T) {
+	appsDir := t.TempDir()
+	writeTestApp(t, appsDir, "stalling-app", "def main(config):\n    for i in range(1000000000):\n        pass\n    return []\n")
+	registry := models.NewAppRegistry()
+	if err := registry.LoadApps(appsDir); err != nil {
+		t.Fatalf("LoadApps: %v", err)
+	}
+
+	wp := NewWorkerPool(1, zap.NewNop(), registry, runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 4,
+		ScalingConfig{}, false, QueueConfig{}, RenderLimits{AdaptiveTimeout: true, MinTimeout: 100 * time.Millisecond})
+	wp.Start()
+	defer wp.Stop()
+
+	// The app has always been fast until now
+	for i := 0; i < renderTimeMinSamples; i++ {
+		wp.renderTimes.record("stalling-app", time.Millisecond)
+	}
+
+	start := time.Now()
+	_, err := wp.SubmitRoots(context.Background(), "stalling-app", nil, models.Device{Width: 64, Height: 32}, RenderOptions{})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Fatalf("Expected an adaptive timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the render to stop near its adaptive timeout, took %s", elapsed)
+	}
+}
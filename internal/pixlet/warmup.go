@@ -0,0 +1,97 @@
+package pixlet
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Warm-up modes for PIXLET_WARMUP
+const (
+	WarmupOff    = "off"    // No warm-up
+	WarmupLoad   = "load"   // Load every app into the applet cache
+	WarmupRender = "render" // Load and dry-render every app with its default config
+)
+
+// warmupDeviceID identifies warm-up renders in logs and debug overlays
+const warmupDeviceID = "warmup"
+
+// Warmup pre-loads every registered app, and in render mode dry-renders it at the
+// default size with no config, so the first real request per app doesn't pay
+// parse and HTTP cache cold-start costs. Failures are logged and don't stop the
+// remaining apps. It returns early if ctx is cancelled.
+func (p *Processor) Warmup(ctx context.Context) {
+	mode := strings.ToLower(strings.TrimSpace(p.config.Warmup))
+	if mode == "" || mode == WarmupOff {
+		return
+	}
+	if mode != WarmupLoad && mode != WarmupRender {
+		p.logger.Warn("Unknown warm-up mode, skipping warm-up", zap.String("mode", p.config.Warmup))
+		return
+	}
+
+	apps := p.appRegistry.GetAppsList()
+	start := time.Now()
+	p.logger.Info("Warming up apps", zap.String("mode", mode), zap.Int("app_count", len(apps)))
+
+	// Warm up as many apps at once as there are base workers, so render mode
+	// neither overflows the queue nor starves jobs that arrive meanwhile
+	concurrency := p.config.RenderWorkers
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+	for _, app := range apps {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			p.logger.Warn("Warm-up interrupted", zap.Error(ctx.Err()))
+			return
+		}
+
+		wg.Add(1)
+		go func(appID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := p.warmupApp(ctx, appID, mode); err != nil {
+				p.logger.Warn("Failed to warm up app", zap.String("app_id", appID), zap.Error(err))
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}(app.ID)
+	}
+	wg.Wait()
+
+	p.logger.Info("Warm-up complete",
+		zap.Int("app_count", len(apps)),
+		zap.Int("failed", failed),
+		zap.Duration("duration", time.Since(start)))
+}
+
+// warmupApp loads one app and, in render mode, renders it at the default size
+func (p *Processor) warmupApp(ctx context.Context, appID, mode string) error {
+	if mode == WarmupLoad {
+		return p.workerPool.Preload(appID)
+	}
+
+	_, err := p.RenderApp(WithPriority(ctx, PriorityBackground), &models.RenderRequest{
+		Type:   "render_request",
+		AppID:  appID,
+		Device: models.Device{ID: warmupDeviceID},
+		Params: map[string]interface{}{},
+	})
+	return err
+}
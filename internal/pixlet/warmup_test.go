@@ -0,0 +1,54 @@
+package pixlet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"go.uber.org/zap"
+)
+
+func writeTestApp(t *testing.T, appsDir, id, source string) {
+	t.Helper()
+	appDir := filepath.Join(appsDir, id)
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, id+".star"), []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to create app file: %v", err)
+	}
+	writeManifest(t, appDir, id, id+".star")
+}
+
+func TestWarmup(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestApp(t, tempDir, "good-app", "def main(config):\n    return []\n")
+	writeTestApp(t, tempDir, "other-app", "def main(config):\n    return []\n")
+	writeTestApp(t, tempDir, "broken-app", "def main(config)\n")
+
+	for _, mode := range []string{WarmupLoad, WarmupRender} {
+		t.Run(mode, func(t *testing.T) {
+			processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, AppletCacheSize: 8, RenderWorkers: 2, Warmup: mode}, zap.NewNop())
+			defer processor.Stop()
+
+			processor.Warmup(context.Background())
+
+			if got := processor.workerPool.applets.Len(); got != 2 {
+				t.Errorf("applet cache holds %d apps after warm-up, want 2", got)
+			}
+		})
+	}
+
+	t.Run("off", func(t *testing.T) {
+		processor := NewProcessor(&config.PixletConfig{AppsPath: tempDir, AppletCacheSize: 8, RenderWorkers: 2}, zap.NewNop())
+		defer processor.Stop()
+
+		processor.Warmup(context.Background())
+
+		if got := processor.workerPool.applets.Len(); got != 0 {
+			t.Errorf("applet cache holds %d apps without warm-up, want 0", got)
+		}
+	})
+}
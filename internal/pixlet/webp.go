@@ -0,0 +1,192 @@
+package pixlet
+
+/*
+#cgo LDFLAGS: -lwebp -lwebpmux
+
+#include <stdlib.h>
+#include <webp/encode.h>
+#include <webp/mux.h>
+
+static WebPPicture *new_picture(int width, int height) {
+	WebPPicture *pic = calloc(1, sizeof(WebPPicture));
+	if (pic == NULL) {
+		return NULL;
+	}
+	if (!WebPPictureInit(pic)) {
+		free(pic);
+		return NULL;
+	}
+	pic->use_argb = 1;
+	pic->width = width;
+	pic->height = height;
+	return pic;
+}
+
+static void free_picture(WebPPicture *pic) {
+	WebPPictureFree(pic);
+	free(pic);
+}
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"time"
+	"unsafe"
+
+	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/render"
+)
+
+// Default WebP settings, matching libwebp's animation encoder defaults
+const (
+	defaultWebPQuality = 75
+	defaultWebPMethod  = 4
+)
+
+// WebPOptions controls how rendered frames are compressed
+type WebPOptions struct {
+	Lossless bool
+	Quality  int // 0-100; for lossless output this trades encode time for size
+	Method   int // 0 (fastest) to 6 (slowest, smallest)
+}
+
+// Validate checks that the options are within libwebp's accepted ranges
+func (o WebPOptions) Validate() error {
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("webp quality must be between 0 and 100, got %d", o.Quality)
+	}
+	if o.Method < 0 || o.Method > 6 {
+		return fmt.Errorf("webp method must be between 0 and 6, got %d", o.Method)
+	}
+	return nil
+}
+
+// encodeWebP paints roots and encodes them as an animated WebP using the given options,
+// passing each frame through profile (if any) first. Frame timing, the maximum
+// animation duration and the frame limit follow paintFrames.
+func encodeWebP(ctx context.Context, roots []render.Root, opts WebPOptions, maxFrames int, profile *ColorProfile) ([]byte, error) {
+	var enc *webpEncoder
+	defer func() {
+		if enc != nil {
+			enc.Close()
+		}
+	}()
+
+	_, err := paintFrames(ctx, roots, maxFrames, profile.filter(func(frame Frame) error {
+		if enc == nil {
+			bounds := frame.Image.Bounds()
+			var err error
+			enc, err = newWebPEncoder(bounds.Dx(), bounds.Dy(), opts)
+			if err != nil {
+				return err
+			}
+		}
+		return enc.AddFrame(frame.Image, frame.Delay)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	if enc == nil {
+		return []byte{}, nil
+	}
+	return enc.Assemble()
+}
+
+// webpEncoder wraps libwebp's animation encoder with an explicit per-frame config
+type webpEncoder struct {
+	c         *C.WebPAnimEncoder
+	config    C.WebPConfig
+	width     int
+	height    int
+	timestamp time.Duration
+}
+
+// newWebPEncoder creates an animation encoder for frames of the given size
+func newWebPEncoder(width, height int, opts WebPOptions) (*webpEncoder, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	enc := &webpEncoder{width: width, height: height}
+
+	if C.WebPConfigPreset(&enc.config, C.WEBP_PRESET_DEFAULT, C.float(opts.Quality)) == 0 {
+		return nil, errors.New("failed to initialize webp config")
+	}
+	if opts.Lossless {
+		enc.config.lossless = 1
+	}
+	enc.config.method = C.int(opts.Method)
+	if C.WebPValidateConfig(&enc.config) == 0 {
+		return nil, errors.New("invalid webp config")
+	}
+
+	var animOpts C.WebPAnimEncoderOptions
+	if C.WebPAnimEncoderOptionsInit(&animOpts) == 0 {
+		return nil, errors.New("failed to initialize animation encoder options")
+	}
+	animOpts.kmin = C.int(encode.WebPKMin)
+	animOpts.kmax = C.int(encode.WebPKMax)
+
+	enc.c = C.WebPAnimEncoderNew(C.int(width), C.int(height), &animOpts)
+	if enc.c == nil {
+		return nil, errors.New("failed to initialize animation encoder")
+	}
+
+	return enc, nil
+}
+
+// AddFrame appends a frame that is displayed for the given delay
+func (e *webpEncoder) AddFrame(img image.Image, delay time.Duration) error {
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+
+	pic := C.new_picture(C.int(e.width), C.int(e.height))
+	if pic == nil {
+		return errors.New("failed to allocate webp picture")
+	}
+	defer C.free_picture(pic)
+
+	if C.WebPPictureImportRGBA(pic, (*C.uint8_t)(unsafe.Pointer(&rgba.Pix[0])), C.int(rgba.Stride)) == 0 {
+		return errors.New("failed to import frame into webp picture")
+	}
+
+	timestamp := C.int(e.timestamp / time.Millisecond)
+	e.timestamp += delay
+
+	if C.WebPAnimEncoderAdd(e.c, pic, timestamp, &e.config) == 0 {
+		return fmt.Errorf("adding frame: %s", C.GoString(C.WebPAnimEncoderGetError(e.c)))
+	}
+	return nil
+}
+
+// Assemble finalizes the animation and returns the encoded WebP bytes
+func (e *webpEncoder) Assemble() ([]byte, error) {
+	// A final nil frame marks the end timestamp of the last frame
+	if C.WebPAnimEncoderAdd(e.c, nil, C.int(e.timestamp/time.Millisecond), nil) == 0 {
+		return nil, fmt.Errorf("finishing animation: %s", C.GoString(C.WebPAnimEncoderGetError(e.c)))
+	}
+
+	var data C.WebPData
+	C.WebPDataInit(&data)
+	defer C.WebPDataClear(&data)
+
+	if C.WebPAnimEncoderAssemble(e.c, &data) == 0 {
+		return nil, fmt.Errorf("assembling animation: %s", C.GoString(C.WebPAnimEncoderGetError(e.c)))
+	}
+
+	return C.GoBytes(unsafe.Pointer(data.bytes), C.int(data.size)), nil
+}
+
+// Close releases the underlying libwebp encoder
+func (e *webpEncoder) Close() {
+	C.WebPAnimEncoderDelete(e.c)
+}
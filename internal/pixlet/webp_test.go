@@ -0,0 +1,77 @@
+package pixlet
+
+import (
+	"testing"
+
+	"github.com/koios/matrx-renderer/internal/config"
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+)
+
+func TestWebPOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts    WebPOptions
+		wantErr bool
+	}{
+		{WebPOptions{Lossless: true, Quality: 75, Method: 4}, false},
+		{WebPOptions{Quality: 0, Method: 0}, false},
+		{WebPOptions{Quality: 100, Method: 6}, false},
+		{WebPOptions{Quality: 101, Method: 4}, true},
+		{WebPOptions{Quality: -1, Method: 4}, true},
+		{WebPOptions{Quality: 75, Method: 7}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWebPOptionsFromConfig(t *testing.T) {
+	t.Run("zero config uses lossless defaults", func(t *testing.T) {
+		got := webpOptionsFromConfig(&config.PixletConfig{}, zap.NewNop())
+		want := WebPOptions{Lossless: true, Quality: defaultWebPQuality, Method: defaultWebPMethod}
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("lossy settings are applied", func(t *testing.T) {
+		got := webpOptionsFromConfig(&config.PixletConfig{WebPLossy: true, WebPQuality: 40, WebPMethod: 6}, zap.NewNop())
+		want := WebPOptions{Lossless: false, Quality: 40, Method: 6}
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("out of range falls back to defaults", func(t *testing.T) {
+		got := webpOptionsFromConfig(&config.PixletConfig{WebPQuality: 500, WebPMethod: 9}, zap.NewNop())
+		if got.Quality != defaultWebPQuality || got.Method != defaultWebPMethod {
+			t.Errorf("got %+v, want default quality and method", got)
+		}
+	})
+}
+
+func TestProcessorWebPOverrides(t *testing.T) {
+	p := &Processor{webp: WebPOptions{Lossless: true, Quality: 75, Method: 4}}
+
+	got, err := p.webpOptions(nil)
+	if err != nil || got != p.webp {
+		t.Errorf("webpOptions(nil) = %+v, %v; want defaults", got, err)
+	}
+
+	lossless := false
+	quality := 30
+	got, err = p.webpOptions(&models.EncodingOptions{Lossless: &lossless, Quality: &quality})
+	if err != nil {
+		t.Fatalf("webpOptions failed: %v", err)
+	}
+	if got.Lossless || got.Quality != 30 || got.Method != 4 {
+		t.Errorf("got %+v, want lossy quality 30 method 4", got)
+	}
+
+	method := 12
+	if _, err := p.webpOptions(&models.EncodingOptions{Method: &method}); err == nil {
+		t.Error("Expected error for out-of-range method")
+	}
+}
@@ -2,39 +2,183 @@ package pixlet
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/koios/matrx-renderer/pkg/models"
 	"go.uber.org/zap"
 
 	"tidbyt.dev/pixlet/encode"
+	"tidbyt.dev/pixlet/render"
 	"tidbyt.dev/pixlet/runtime"
 	"tidbyt.dev/pixlet/tools"
+
+	starlibtime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+)
+
+// RenderOptions carries per-request overrides applied while rendering
+type RenderOptions struct {
+	// RenderTime pins the Starlark clock (time.now()) to a fixed instant when non-zero
+	RenderTime time.Time
+	// Encoding overrides the configured WebP encoder settings when set
+	Encoding *models.EncodingOptions
+	// DebugOverlay stamps app ID, device ID, render time and worker onto every frame
+	DebugOverlay bool
+}
+
+// Priority orders render jobs: interactive jobs are always picked up before background ones
+type Priority int
+
+const (
+	// PriorityInteractive is for user-facing renders such as HTTP previews (the default)
+	PriorityInteractive Priority = iota
+	// PriorityBackground is for queue-driven refreshes that nobody is waiting on
+	PriorityBackground
 )
 
+// String returns the priority name used in logs
+func (p Priority) String() string {
+	if p == PriorityBackground {
+		return "background"
+	}
+	return "interactive"
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose render jobs are queued at the given priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// priorityFromContext returns the priority set by WithPriority, defaulting to interactive
+func priorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityInteractive
+}
+
+type jobIDKey struct{}
+
+// WithJobID returns a context whose render jobs carry the given ID in worker logs
+// and results, so they can be correlated with the request that caused them
+func WithJobID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, jobIDKey{}, id)
+}
+
+// JobIDFromContext returns the ID set by WithJobID, or "" if there is none
+func JobIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(jobIDKey{}).(string)
+	return id
+}
+
+// NewJobID returns a random job ID for requests that don't bring their own
+func NewJobID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
 // RenderJob represents a render request to be processed by a worker
 type RenderJob struct {
-	AppID  string
-	Params map[string]interface{}
-	Device models.Device
-	Result chan *RenderResult
+	ID       string // correlates worker logs with the originating request
+	AppID    string
+	Params   map[string]interface{}
+	Device   models.Device
+	Sizes    []models.Size // when set, the applet is loaded once and run at each size
+	Options  RenderOptions
+	Priority Priority
+	Enqueued time.Time // when the job entered the queue, used to measure wait time
+	Result   chan *RenderResult
 }
 
 // RenderResult contains the result of a render job
 type RenderResult struct {
+	JobID   string // ID of the job that rendered this; differs from the caller's when deduplicated
 	Screens *encode.Screens
+	Roots   []render.Root   // Raw render roots, used for per-frame painting
+	Batch   []*RenderResult // Per-size results for batch jobs, in the order of RenderJob.Sizes
 	Error   error
 }
 
+// JobResult is the outcome of one job submitted through SubmitAll
+type JobResult struct {
+	Index  int           // Position of the job in the submitted slice
+	Result *RenderResult // nil if the job could not be submitted
+	Error  error         // Submit or render error, nil on success
+}
+
+// ScalingConfig lets the pool add workers beyond its base count under load
+type ScalingConfig struct {
+	MaxWorkers  int           // Upper bound on workers; at or below the base count disables scaling
+	ScaleUpWait time.Duration // Queue wait that triggers an extra worker (default: 250ms)
+	IdleTimeout time.Duration // How long an extra worker may sit idle before exiting (default: 30s)
+}
+
+// QueueConfig bounds the job queues and decides what happens when they fill up
+type QueueConfig struct {
+	Size           int  // Jobs buffered per priority (default: 2x max workers)
+	RejectWhenFull bool // Fail with ErrQueueFull instead of waiting for room
+	Affinity       bool // Route each app's jobs to the same base worker, spilling to the shared queue when it is busy
+	Quarantine     QuarantineConfig
+}
+
+// RenderLimits bounds the resources a single render may use
+type RenderLimits struct {
+	MaxExecutionSteps uint64        // Starlark steps per thread before the interpreter aborts; 0 is unlimited
+	MaxHeapGrowth     uint64        // Process heap growth in bytes during a render before it is aborted; 0 is unlimited
+	AdaptiveTimeout   bool          // Derive each app's timeout from its recent render times, up to the pool timeout
+	MinTimeout        time.Duration // Floor for adaptive timeouts (default: 2s)
+}
+
+// ErrQueueFull is returned when a job is rejected because its queue is full
+var ErrQueueFull = errors.New("render queue is full")
+
+// ErrPoolStopped is returned for jobs submitted after the pool began shutting down,
+// and for queued jobs it could not finish before its drain deadline
+var ErrPoolStopped = errors.New("worker pool is shutting down")
+
+// errRenderTimeout marks a render the pool's timeout stopped. Such errors also
+// wrap context.DeadlineExceeded.
+var errRenderTimeout = errors.New("timed out")
+
+// Default scaling thresholds
+const (
+	defaultScaleUpWait = 250 * time.Millisecond
+	defaultIdleTimeout = 30 * time.Second
+)
+
 // WorkerPool manages a pool of render workers for concurrent processing
 type WorkerPool struct {
-	workers     int
-	jobQueue    chan *RenderJob
+	workers     int // base worker count, always kept running
+	scaling     ScalingConfig
+	queue       QueueConfig
+	limits      RenderLimits
+	mu          sync.Mutex
+	running     int             // live workers, between workers and scaling.MaxWorkers
+	nextID      int             // ID for the next worker started
+	freeIDs     []int           // IDs of retired workers, reused to keep metric labels bounded
+	interactive chan *RenderJob // user-facing jobs, drained before background
+	background  chan *RenderJob // queue-driven refreshes
+	affinity    []affinityQueue // per base worker, filled by app; nil when affinity is disabled
+	quarantined chan *RenderJob // jobs of quarantined apps; nil when quarantine is disabled
+	quarantine  *quarantine     // slow render tracking; nil when quarantine is disabled
+	quit        chan struct{}   // closed when the pool stops accepting jobs
+	quitOnce    sync.Once
+	submitMu    sync.RWMutex // held shared while pushing, so a drain can wait out racing submits
 	wg          sync.WaitGroup
 	ctx         context.Context
 	cancel      context.CancelFunc
@@ -43,10 +187,18 @@ type WorkerPool struct {
 	cache       runtime.Cache
 	redisCache  *RedisCache
 	secretKey   runtime.SecretDecryptionKey
-	timeout     int // timeout in seconds
+	timeout     int          // timeout in seconds
+	instance    string       // hostname, used to identify this replica in debug overlays
+	applets     *appletCache // loaded applets, shared by all workers
+	flights     *jobFlights  // identical jobs in flight; nil when deduplication is disabled
+	perDevice   bool         // apps cache per device, so identical jobs for different devices differ
+	renders     *appCounters // processed jobs per app, for /stats
+	renderTimes *renderTimes // recent render durations per app; nil unless timeouts are adaptive
+	busy        atomic.Int32 // workers processing a job
 }
 
-// NewWorkerPool creates a new worker pool with the specified number of workers
+// NewWorkerPool creates a new worker pool with the specified base number of workers,
+// growing up to scaling.MaxWorkers when jobs back up
 func NewWorkerPool(
 	workers int,
 	logger *zap.Logger,
@@ -55,16 +207,43 @@ func NewWorkerPool(
 	redisCache *RedisCache,
 	secretKey runtime.SecretDecryptionKey,
 	timeout int,
+	appletCacheSize int,
+	scaling ScalingConfig,
+	dedupe bool,
+	queue QueueConfig,
+	limits RenderLimits,
 ) *WorkerPool {
 	if workers <= 0 {
 		workers = 4 // default to 4 workers
 	}
+	if scaling.MaxWorkers < workers {
+		scaling.MaxWorkers = workers
+	}
+	if scaling.ScaleUpWait <= 0 {
+		scaling.ScaleUpWait = defaultScaleUpWait
+	}
+	if scaling.IdleTimeout <= 0 {
+		scaling.IdleTimeout = defaultIdleTimeout
+	}
+	if queue.Size <= 0 {
+		queue.Size = scaling.MaxWorkers * 2 // buffer for 2x max workers
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+
 	pool := &WorkerPool{
 		workers:     workers,
-		jobQueue:    make(chan *RenderJob, workers*2), // buffer for 2x workers
+		scaling:     scaling,
+		interactive: make(chan *RenderJob, queue.Size),
+		background:  make(chan *RenderJob, queue.Size),
+		quit:        make(chan struct{}),
+		queue:       queue,
+		limits:      limits,
 		ctx:         ctx,
 		cancel:      cancel,
 		logger:      logger,
@@ -73,6 +252,22 @@ func NewWorkerPool(
 		redisCache:  redisCache,
 		secretKey:   secretKey,
 		timeout:     timeout,
+		instance:    instance,
+		applets:     newAppletCache(appletCacheSize),
+		renders:     newAppCounters(),
+	}
+	if dedupe {
+		pool.flights = newJobFlights()
+	}
+	if queue.Affinity {
+		pool.affinity = newAffinityQueues(workers)
+	}
+	if q := newQuarantine(queue.Quarantine); q != nil {
+		pool.quarantine = q
+		pool.quarantined = make(chan *RenderJob, queue.Size)
+	}
+	if limits.AdaptiveTimeout {
+		pool.renderTimes = newRenderTimes(limits.MinTimeout, secondsToDuration(timeout))
 	}
 
 	return pool
@@ -82,57 +277,367 @@ func NewWorkerPool(
 func (wp *WorkerPool) Start() {
 	wp.logger.Info("Starting render worker pool",
 		zap.Int("workers", wp.workers),
-		zap.Int("queue_size", cap(wp.jobQueue)))
+		zap.Int("max_workers", wp.scaling.MaxWorkers),
+		zap.Int("queue_size", cap(wp.interactive)+cap(wp.background)))
 
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
 	for i := 0; i < wp.workers; i++ {
-		wp.wg.Add(1)
-		go wp.worker(i)
+		wp.startWorker()
+	}
+	if wp.quarantine != nil {
+		for i := 0; i < wp.queue.Quarantine.Workers; i++ {
+			wp.wg.Add(1)
+			go wp.quarantineWorker(wp.nextID)
+			wp.nextID++
+		}
 	}
 }
 
-// Stop gracefully shuts down the worker pool
+// startWorker launches one worker goroutine. Callers must hold wp.mu.
+func (wp *WorkerPool) startWorker() {
+	id := wp.nextID
+	if n := len(wp.freeIDs); n > 0 {
+		id = wp.freeIDs[n-1]
+		wp.freeIDs = wp.freeIDs[:n-1]
+	} else {
+		wp.nextID++
+	}
+
+	wp.wg.Add(1)
+	wp.running++
+	metricWorkers.Inc()
+	go wp.worker(id)
+}
+
+// scaleUp adds a worker if the pool is below its maximum
+func (wp *WorkerPool) scaleUp(reason string) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	// Only grow a running pool; below the base count it hasn't started
+	if wp.running < wp.workers || wp.running >= wp.scaling.MaxWorkers || wp.stopping() {
+		return
+	}
+	wp.startWorker()
+	wp.logger.Info("Scaled up render workers",
+		zap.String("reason", reason),
+		zap.Int("workers", wp.running))
+}
+
+// retire lets an idle worker exit if the pool is above its base count
+func (wp *WorkerPool) retire(workerID int) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if wp.running <= wp.workers || wp.ownQueue(workerID) != nil {
+		return false
+	}
+	wp.running--
+	wp.freeIDs = append(wp.freeIDs, workerID)
+	metricWorkers.Dec()
+	wp.logger.Info("Scaled down render workers",
+		zap.Int("worker_id", workerID),
+		zap.Int("workers", wp.running))
+	return true
+}
+
+// Workers returns the number of live workers
+func (wp *WorkerPool) Workers() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.running
+}
+
+// queued returns the number of jobs waiting for a worker
+func (wp *WorkerPool) queued() int {
+	interactive, background := wp.queuedByPriority()
+	return interactive + background
+}
+
+// queuedByPriority returns the number of jobs of each priority waiting for a worker
+func (wp *WorkerPool) queuedByPriority() (interactive, background int) {
+	interactive, background = len(wp.interactive), len(wp.background)
+	for i := range wp.affinity {
+		interactive += len(wp.affinity[i].interactive)
+		background += len(wp.affinity[i].background)
+	}
+	return interactive, background
+}
+
+// stopping reports whether the pool has stopped accepting jobs
+func (wp *WorkerPool) stopping() bool {
+	select {
+	case <-wp.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop shuts down the worker pool immediately, cancelling in-flight renders and
+// failing queued jobs with ErrPoolStopped
 func (wp *WorkerPool) Stop() {
-	wp.logger.Info("Stopping render worker pool")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	wp.Drain(ctx)
+}
+
+// Drain shuts down the worker pool gracefully. New jobs are rejected with
+// ErrPoolStopped while workers finish the jobs already accepted, both in flight and
+// queued. If ctx ends first, in-flight renders are cancelled, the remaining queued
+// jobs fail with ErrPoolStopped, and ctx's error is returned.
+func (wp *WorkerPool) Drain(ctx context.Context) error {
+	first := false
+	wp.mu.Lock()
+	wp.quitOnce.Do(func() {
+		close(wp.quit)
+		first = true
+	})
+	wp.mu.Unlock()
+	if !first {
+		return nil
+	}
+
+	wp.logger.Info("Draining render worker pool", zap.Int("queued", wp.queued()))
+
+	// Wait out submits that raced with quit, so no job lands after the final sweep
+	wp.submitMu.Lock()
+	wp.submitMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		wp.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		wp.logger.Warn("Drain deadline reached, cancelling in-flight renders")
+		wp.cancel()
+		<-done
+	}
 	wp.cancel()
-	close(wp.jobQueue)
-	wp.wg.Wait()
-	wp.logger.Info("Render worker pool stopped")
+	failed := wp.failQueued()
+
+	wp.mu.Lock()
+	metricWorkers.Sub(float64(wp.running))
+	wp.running = 0
+	wp.mu.Unlock()
+	wp.logger.Info("Render worker pool stopped", zap.Int("failed_jobs", failed))
+	return err
 }
 
-// UpdateAppRegistry updates the app registry used by workers
+// failQueued empties every queue, failing each job with ErrPoolStopped, and
+// returns how many jobs it failed. Workers must have exited.
+func (wp *WorkerPool) failQueued() int {
+	failed := 0
+	queues := []chan *RenderJob{wp.interactive, wp.background}
+	if wp.quarantined != nil {
+		queues = append(queues, wp.quarantined)
+	}
+	for i := range wp.affinity {
+		queues = append(queues, wp.affinity[i].interactive, wp.affinity[i].background)
+	}
+	for _, queue := range queues {
+	sweep:
+		for {
+			select {
+			case job := <-queue:
+				metricJobsQueued.WithLabelValues(job.Priority.String()).Dec()
+				job.Result <- &RenderResult{JobID: job.ID, Error: ErrPoolStopped}
+				failed++
+			default:
+				break sweep
+			}
+		}
+	}
+	return failed
+}
+
+// UpdateAppRegistry updates the app registry used by workers and drops cached applets
 func (wp *WorkerPool) UpdateAppRegistry(registry *models.AppRegistry) {
 	wp.appRegistry = registry
+	wp.applets.Purge()
+	wp.renderTimes.forget()
 	wp.logger.Info("Worker pool app registry updated")
 }
 
+// Preload loads an app into the applet cache without rendering it
+func (wp *WorkerPool) Preload(appID string) error {
+	_, err := wp.loadApplet(appID, RenderOptions{})
+	return err
+}
+
 // Submit submits a render job to the pool and returns the result channel
-func (wp *WorkerPool) Submit(ctx context.Context, appID string, params map[string]interface{}, device models.Device) (*encode.Screens, error) {
+func (wp *WorkerPool) Submit(ctx context.Context, appID string, params map[string]interface{}, device models.Device, opts RenderOptions) (*encode.Screens, error) {
+	result, err := wp.submit(ctx, appID, params, device, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.Screens, result.Error
+}
+
+// SubmitRoots submits a render job to the pool and returns the raw render roots
+func (wp *WorkerPool) SubmitRoots(ctx context.Context, appID string, params map[string]interface{}, device models.Device, opts RenderOptions) ([]render.Root, error) {
+	result, err := wp.submit(ctx, appID, params, device, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.Roots, result.Error
+}
+
+// SubmitBatch submits a job that renders the same app and config at several sizes,
+// reusing a single loaded applet. Results are returned in the order of sizes.
+func (wp *WorkerPool) SubmitBatch(ctx context.Context, appID string, params map[string]interface{}, sizes []models.Size, opts RenderOptions) ([]*RenderResult, error) {
+	result, err := wp.dedupe(ctx, &RenderJob{
+		AppID:   appID,
+		Params:  params,
+		Sizes:   sizes,
+		Options: opts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Batch, result.Error
+}
+
+// SubmitAll submits several jobs at once and delivers each outcome on the returned
+// channel as it completes, which is closed once every job has reported. Only
+// AppID, Params, Device, Sizes and Options are read from each job. Submission is
+// paced to the pool's worker count, so large batches don't overflow the queue,
+// and jobs not yet submitted when ctx ends report its error.
+func (wp *WorkerPool) SubmitAll(ctx context.Context, jobs []RenderJob) <-chan JobResult {
+	// Buffered for every job, so callers that stop reading early don't leak workers
+	results := make(chan JobResult, len(jobs))
+
+	go func() {
+		defer close(results)
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, wp.scaling.MaxWorkers)
+		for i, job := range jobs {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if err := ctx.Err(); err != nil {
+				for ; i < len(jobs); i++ {
+					results <- JobResult{Index: i, Error: err}
+				}
+				wg.Wait()
+				return
+			}
+
+			wg.Add(1)
+			go func(i int, job *RenderJob) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				result, err := wp.dedupe(ctx, job)
+				if err == nil {
+					err = result.Error
+				}
+				results <- JobResult{Index: i, Result: result, Error: err}
+			}(i, &RenderJob{
+				AppID:   job.AppID,
+				Params:  job.Params,
+				Device:  job.Device,
+				Sizes:   job.Sizes,
+				Options: job.Options,
+			})
+		}
+		wg.Wait()
+	}()
+
+	return results
+}
+
+// submit enqueues a render job and waits for the worker's result
+func (wp *WorkerPool) submit(ctx context.Context, appID string, params map[string]interface{}, device models.Device, opts RenderOptions) (*RenderResult, error) {
+	return wp.dedupe(ctx, &RenderJob{
+		AppID:   appID,
+		Params:  params,
+		Device:  device,
+		Options: opts,
+	})
+}
+
+// enqueue hands a job to the workers at the context's priority and waits for its result
+func (wp *WorkerPool) enqueue(ctx context.Context, job *RenderJob) (*RenderResult, error) {
 	resultChan := make(chan *RenderResult, 1)
+	job.Result = resultChan
+	job.Priority = priorityFromContext(ctx)
+	if job.ID == "" {
+		job.ID = JobIDFromContext(ctx)
+	}
+	if job.ID == "" {
+		job.ID = NewJobID()
+	}
+	job.Enqueued = time.Now()
 
-	job := &RenderJob{
-		AppID:  appID,
-		Params: params,
-		Device: device,
-		Result: resultChan,
+	queue := wp.interactive
+	if job.Priority == PriorityBackground {
+		queue = wp.background
+	}
+	if wp.quarantine.contains(job.AppID) {
+		queue = wp.quarantined
 	}
 
-	select {
-	case wp.jobQueue <- job:
-		// Job submitted
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-wp.ctx.Done():
-		return nil, fmt.Errorf("worker pool is shutting down")
+	if err := wp.push(ctx, queue, job); err != nil {
+		return nil, err
+	}
+
+	// Job submitted; add a worker if the backlog outnumbers them
+	metricJobsQueued.WithLabelValues(job.Priority.String()).Inc()
+	if wp.queued() > wp.Workers() {
+		wp.scaleUp("queue_depth")
 	}
 
 	// Wait for result
 	select {
 	case result := <-resultChan:
-		return result.Screens, result.Error
+		return result, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	case <-wp.ctx.Done():
-		return nil, fmt.Errorf("worker pool is shutting down")
+		return nil, ErrPoolStopped
+	}
+}
+
+// push adds a job to a queue, rejecting it immediately when the queue is full and the
+// pool is configured to, or otherwise waiting for room
+func (wp *WorkerPool) push(ctx context.Context, queue chan *RenderJob, job *RenderJob) error {
+	wp.submitMu.RLock()
+	defer wp.submitMu.RUnlock()
+
+	if wp.stopping() {
+		return ErrPoolStopped
+	}
+	if queue != wp.quarantined && wp.pushAffinity(job) {
+		return nil
+	}
+
+	if wp.queue.RejectWhenFull {
+		select {
+		case queue <- job:
+			return nil
+		default:
+			metricJobsRejected.WithLabelValues(job.Priority.String()).Inc()
+			return ErrQueueFull
+		}
+	}
+
+	select {
+	case queue <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wp.quit:
+		return ErrPoolStopped
 	}
 }
 
@@ -142,18 +647,89 @@ func (wp *WorkerPool) worker(id int) {
 
 	wp.logger.Debug("Render worker started", zap.Int("worker_id", id))
 
+	scalable := wp.scaling.MaxWorkers > wp.workers
+	own := wp.ownQueue(id)
 	for {
-		select {
-		case job, ok := <-wp.jobQueue:
-			if !ok {
-				wp.logger.Debug("Render worker stopping (queue closed)", zap.Int("worker_id", id))
+		var idle <-chan time.Time
+		var timer *time.Timer
+		if scalable {
+			timer = time.NewTimer(wp.scaling.IdleTimeout)
+			idle = timer.C
+		}
+
+		job, ok := wp.nextJob(idle, own)
+		if timer != nil {
+			timer.Stop()
+		}
+		if !ok {
+			wp.logger.Debug("Render worker stopping", zap.Int("worker_id", id))
+			return
+		}
+		if job == nil {
+			if wp.retire(id) {
 				return
 			}
-			wp.processJob(id, job)
-		case <-wp.ctx.Done():
-			wp.logger.Debug("Render worker stopping (context cancelled)", zap.Int("worker_id", id))
-			return
+			continue
 		}
+
+		wait := wp.pickUp(job)
+		if scalable && wait >= wp.scaling.ScaleUpWait {
+			wp.scaleUp("wait_time")
+		}
+		wp.processJob(id, job)
+	}
+}
+
+// pickUp accounts for a job leaving its queue and returns how long it waited
+func (wp *WorkerPool) pickUp(job *RenderJob) time.Duration {
+	wait := time.Since(job.Enqueued)
+	metricJobsQueued.WithLabelValues(job.Priority.String()).Dec()
+	metricQueueWait.WithLabelValues(job.Priority.String()).Observe(wait.Seconds())
+	return wait
+}
+
+// nextJob waits for the next job, from the worker's own affinity queue or the shared
+// ones, preferring interactive jobs over background ones. It returns a nil job if
+// idle fires first, and false once the pool is stopping and, unless it was
+// cancelled, has no jobs left.
+func (wp *WorkerPool) nextJob(idle <-chan time.Time, own *affinityQueue) (*RenderJob, bool) {
+	// Nil channels never receive, so workers without an affinity queue skip them
+	var ownInteractive, ownBackground chan *RenderJob
+	if own != nil {
+		ownInteractive, ownBackground = own.interactive, own.background
+	}
+
+	select {
+	case job := <-ownInteractive:
+		return job, true
+	case job := <-wp.interactive:
+		return job, true
+	default:
+	}
+
+	select {
+	case job := <-ownInteractive:
+		return job, true
+	case job := <-wp.interactive:
+		return job, true
+	case job := <-ownBackground:
+		return job, true
+	case job := <-wp.background:
+		return job, true
+	case <-idle:
+		return nil, true
+	case <-wp.quit:
+		// Draining: finish what's queued, then exit
+		for _, queue := range []chan *RenderJob{ownInteractive, wp.interactive, ownBackground, wp.background} {
+			select {
+			case job := <-queue:
+				return job, true
+			default:
+			}
+		}
+		return nil, false
+	case <-wp.ctx.Done():
+		return nil, false
 	}
 }
 
@@ -161,43 +737,134 @@ func (wp *WorkerPool) worker(id int) {
 func (wp *WorkerPool) processJob(workerID int, job *RenderJob) {
 	wp.logger.Debug("Worker processing job",
 		zap.Int("worker_id", workerID),
-		zap.String("app_id", job.AppID))
+		zap.String("job_id", job.ID),
+		zap.String("app_id", job.AppID),
+		zap.Stringer("priority", job.Priority))
+
+	metricJobsInFlight.Inc()
+	wp.busy.Add(1)
+	start := time.Now()
+
+	var result *RenderResult
+	var err error
+	if len(job.Sizes) > 0 {
+		result = wp.renderBatch(workerID, job)
+		err = result.Error
+	} else {
+		var roots []render.Root
+		roots, err = wp.renderRoots(job.AppID, job.Params, job.Device, job.Options)
+		if err == nil && job.Options.DebugOverlay {
+			roots, err = wp.debugOverlay(workerID, job, "", roots)
+		}
+		result = newRenderResult(roots, err)
+	}
+	result.JobID = job.ID
+	job.Result <- result
+	close(job.Result)
 
-	screens, err := wp.renderScreens(job.AppID, job.Params, job.Device)
+	took := time.Since(start)
+	elapsed := took.Seconds()
+	metricJobsInFlight.Dec()
+	wp.busy.Add(-1)
+	wp.renders.record(job.AppID, err)
+	metricWorkerBusy.WithLabelValues(strconv.Itoa(workerID)).Add(elapsed)
 
-	job.Result <- &RenderResult{
-		Screens: screens,
-		Error:   err,
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	metricProcessDuration.WithLabelValues(outcome).Observe(elapsed)
+
+	// Judge batch jobs by their time per size
+	if n := len(job.Sizes); n > 1 {
+		took /= time.Duration(n)
+	}
+	if wp.quarantine.record(job.AppID, took, err) {
+		wp.logger.Warn("Quarantined slow app",
+			zap.String("app_id", job.AppID),
+			zap.Duration("last_render", took),
+			zap.Duration("duration", wp.quarantine.duration))
 	}
-	close(job.Result)
 
 	if err != nil {
 		wp.logger.Debug("Worker completed job with error",
 			zap.Int("worker_id", workerID),
+			zap.String("job_id", job.ID),
 			zap.String("app_id", job.AppID),
 			zap.Error(err))
 	} else {
 		wp.logger.Debug("Worker completed job successfully",
 			zap.Int("worker_id", workerID),
+			zap.String("job_id", job.ID),
 			zap.String("app_id", job.AppID))
 	}
 }
 
-// renderScreens performs the actual rendering (called by workers)
-func (wp *WorkerPool) renderScreens(appID string, params map[string]interface{}, device models.Device) (*encode.Screens, error) {
-	if strings.Contains(appID, "..") || strings.Contains(appID, "/") {
-		return nil, fmt.Errorf("invalid app ID: %s", appID)
+// newRenderResult wraps rendered roots (or the render error) in a RenderResult
+func newRenderResult(roots []render.Root, err error) *RenderResult {
+	result := &RenderResult{Error: err}
+	if err == nil {
+		result.Roots = roots
+		result.Screens = encode.ScreensFromRoots(roots)
 	}
+	return result
+}
 
-	var requestCache runtime.Cache
-	if wp.redisCache != nil {
-		requestCache = wp.redisCache
-	} else {
-		requestCache = wp.cache
+// debugOverlay stamps the job's debug overlay, identifying this instance and worker
+func (wp *WorkerPool) debugOverlay(workerID int, job *RenderJob, size string, roots []render.Root) ([]render.Root, error) {
+	return withDebugOverlay(roots, wp.overlayInfo(workerID, job, size))
+}
+
+// overlayInfo describes a job's render for its debug overlay; size is set for
+// each size of a batch
+func (wp *WorkerPool) overlayInfo(workerID int, job *RenderJob, size string) overlayInfo {
+	renderTime := job.Options.RenderTime
+	if renderTime.IsZero() {
+		renderTime = time.Now()
+	}
+	return overlayInfo{
+		AppID:    job.AppID,
+		DeviceID: job.Device.ID,
+		Size:     size,
+		Worker:   fmt.Sprintf("%s#%d", wp.instance, workerID),
+		Time:     renderTime,
+	}
+}
+
+// renderBatch loads the applet once and runs it at every size in the job
+func (wp *WorkerPool) renderBatch(workerID int, job *RenderJob) *RenderResult {
+	applet, err := wp.loadApplet(job.AppID, job.Options)
+	if err != nil {
+		return &RenderResult{Error: err}
 	}
 
-	runtime.InitHTTP(requestCache)
-	runtime.InitCache(requestCache)
+	batch := make([]*RenderResult, len(job.Sizes))
+	for i, size := range job.Sizes {
+		roots, err := wp.runApplet(applet, job.Device.ID, job.Params, size.Width, size.Height)
+		if err == nil && job.Options.DebugOverlay {
+			roots, err = wp.debugOverlay(workerID, job, size.String(), roots)
+		}
+		batch[i] = newRenderResult(roots, err)
+	}
+	return &RenderResult{Batch: batch}
+}
+
+// renderRoots performs the actual rendering (called by workers)
+func (wp *WorkerPool) renderRoots(appID string, params map[string]interface{}, device models.Device, renderOpts RenderOptions) ([]render.Root, error) {
+	applet, err := wp.loadApplet(appID, renderOpts)
+	if err != nil {
+		return nil, err
+	}
+	return wp.runApplet(applet, device.ID, params, device.Width, device.Height)
+}
+
+// loadApplet resolves an app from the registry and loads it with the pool's runtime options.
+// Applets are served from the cache unless the source changed since they were loaded.
+// Renders with a pinned clock bypass the cache, since the clock is fixed at load time.
+func (wp *WorkerPool) loadApplet(appID string, renderOpts RenderOptions) (*runtime.Applet, error) {
+	if strings.Contains(appID, "..") || strings.Contains(appID, "/") {
+		return nil, fmt.Errorf("invalid app ID: %s", appID)
+	}
 
 	app, exists := wp.appRegistry.GetApp(appID)
 	if !exists {
@@ -221,9 +888,24 @@ func (wp *WorkerPool) renderScreens(appID string, params map[string]interface{},
 		appFS = tools.NewSingleFileFS(appPath)
 	}
 
+	cacheable := renderOpts.RenderTime.IsZero()
+	if cacheable {
+		if applet, ok := wp.applets.Get(appID, info.ModTime()); ok {
+			return applet, nil
+		}
+	}
+
 	opts := []runtime.AppletOption{
 		runtime.WithPrintDisabled(),
-		runtime.WithSecretDecryptionKey(&wp.secretKey),
+	}
+	if wp.secretKey.EncryptedKeysetJSON != nil {
+		opts = append(opts, runtime.WithSecretDecryptionKey(&wp.secretKey))
+	}
+	if wp.limits.MaxExecutionSteps > 0 {
+		opts = append(opts, withStepLimit(wp.limits.MaxExecutionSteps))
+	}
+	if !renderOpts.RenderTime.IsZero() {
+		opts = append(opts, withFixedClock(renderOpts.RenderTime))
 	}
 
 	applet, err := runtime.NewAppletFromFS(appID, appFS, opts...)
@@ -231,23 +913,28 @@ func (wp *WorkerPool) renderScreens(appID string, params map[string]interface{},
 		return nil, fmt.Errorf("failed to load applet: %w", err)
 	}
 
-	config := make(map[string]string)
-	for key, value := range params {
-		switch v := value.(type) {
-		case string:
-			config[key] = v
-		case nil:
-			config[key] = ""
-		default:
-			config[key] = fmt.Sprintf("%v", v)
-		}
+	// Cached applets run concurrently on several workers, so module globals must
+	// not be mutated after load
+	for _, globals := range applet.Globals {
+		globals.Freeze()
+	}
+
+	if cacheable {
+		wp.applets.Put(appID, info.ModTime(), applet)
 	}
 
-	width := device.Width
+	return applet, nil
+}
+
+// runApplet executes a loaded applet for a device with the given config at the
+// given dimensions. The device scopes the applet's cache keys when they are
+// scoped per device; it may be empty.
+func (wp *WorkerPool) runApplet(applet *runtime.Applet, deviceID string, params map[string]interface{}, width, height int) ([]render.Root, error) {
+	config := appletConfig(params)
+
 	if width <= 0 {
 		width = 64
 	}
-	height := device.Height
 	if height <= 0 {
 		height = 32
 	}
@@ -255,17 +942,77 @@ func (wp *WorkerPool) renderScreens(appID string, params map[string]interface{},
 	config["display_width"] = fmt.Sprintf("%d", width)
 	config["display_height"] = fmt.Sprintf("%d", height)
 
-	ctx, cancel := context.WithTimeout(wp.ctx, secondsToDuration(wp.timeout))
+	maxTimeout := secondsToDuration(wp.timeout)
+	timeout := wp.renderTimes.timeout(applet.ID, maxTimeout)
+
+	budgetCtx, cancelBudget := context.WithCancelCause(withCacheDevice(wp.ctx, deviceID))
+	defer cancelBudget(nil)
+	ctx, cancel := context.WithTimeout(budgetCtx, timeout)
 	defer cancel()
 
+	if wp.limits.MaxHeapGrowth > 0 {
+		stop := watchHeapGrowth(ctx, cancelBudget, wp.limits.MaxHeapGrowth)
+		defer stop()
+	}
+
 	// Use RunWithConfigAndDimensions to embed dimensions in roots for thread-safe rendering
+	start := time.Now()
 	roots, err := applet.RunWithConfigAndDimensions(ctx, config, width, height)
+	if errors.Is(context.Cause(budgetCtx), ErrHeapGrowthExceeded) {
+		return nil, fmt.Errorf("error running applet: %w", ErrHeapGrowthExceeded)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		wp.renderTimes.record(applet.ID, timeout)
+		if timeout < maxTimeout {
+			metricAdaptiveTimeouts.Inc()
+		}
+		// pixlet cancels the thread with its own error, so wrap the deadline for
+		// callers telling timeouts apart, such as the quarantine
+		return nil, fmt.Errorf("error running applet: %w after %s: %w (%v)", errRenderTimeout, timeout, context.DeadlineExceeded, err)
+	}
+	if err == nil {
+		wp.renderTimes.record(applet.ID, time.Since(start))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error running applet: %w", err)
 	}
 
-	screens := encode.ScreensFromRoots(roots)
-	return screens, nil
+	return roots, nil
+}
+
+// appletConfig converts request params to the string config passed to an applet
+func appletConfig(params map[string]interface{}) map[string]string {
+	config := make(map[string]string, len(params))
+	for key, value := range params {
+		switch v := value.(type) {
+		case string:
+			config[key] = v
+		case nil:
+			config[key] = ""
+		default:
+			config[key] = fmt.Sprintf("%v", v)
+		}
+	}
+	return config
+}
+
+// withStepLimit aborts any Starlark thread of the applet, including the one that
+// loads it, after the given number of execution steps
+func withStepLimit(steps uint64) runtime.AppletOption {
+	return runtime.WithThreadInitializer(func(thread *starlark.Thread) *starlark.Thread {
+		thread.SetMaxExecutionSteps(steps)
+		return thread
+	})
+}
+
+// withFixedClock pins time.now() in the applet's Starlark threads to the given instant
+func withFixedClock(at time.Time) runtime.AppletOption {
+	return runtime.WithThreadInitializer(func(thread *starlark.Thread) *starlark.Thread {
+		starlibtime.SetNow(thread, func() (time.Time, error) {
+			return at, nil
+		})
+		return thread
+	})
 }
 
 func secondsToDuration(seconds int) time.Duration {
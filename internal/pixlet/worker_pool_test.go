@@ -0,0 +1,282 @@
+package pixlet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/koios/matrx-renderer/pkg/models"
+	"go.uber.org/zap"
+
+	"tidbyt.dev/pixlet/runtime"
+)
+
+func TestPriorityFromContext(t *testing.T) {
+	if got := priorityFromContext(context.Background()); got != PriorityInteractive {
+		t.Errorf("default priority = %v, want interactive", got)
+	}
+	ctx := WithPriority(context.Background(), PriorityBackground)
+	if got := priorityFromContext(ctx); got != PriorityBackground {
+		t.Errorf("priority = %v, want background", got)
+	}
+}
+
+func TestWorkerPoolNextJobPrefersInteractive(t *testing.T) {
+	wp := NewWorkerPool(1, zap.NewNop(), models.NewAppRegistry(), runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0, ScalingConfig{}, false, QueueConfig{}, RenderLimits{})
+	defer wp.cancel()
+
+	wp.background <- &RenderJob{AppID: "background-1"}
+	wp.background <- &RenderJob{AppID: "background-2"}
+	wp.interactive <- &RenderJob{AppID: "interactive"}
+
+	var order []string
+	for i := 0; i < 3; i++ {
+		job, ok := wp.nextJob(nil, nil)
+		if !ok {
+			t.Fatal("nextJob returned false with jobs queued")
+		}
+		order = append(order, job.AppID)
+	}
+
+	want := []string{"interactive", "background-1", "background-2"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("job order = %v, want %v", order, want)
+		}
+	}
+
+	wp.cancel()
+	if _, ok := wp.nextJob(nil, nil); ok {
+		t.Error("nextJob returned a job after the pool was cancelled")
+	}
+}
+
+func TestWorkerPoolScaling(t *testing.T) {
+	wp := NewWorkerPool(1, zap.NewNop(), models.NewAppRegistry(), runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0,
+		ScalingConfig{MaxWorkers: 3, IdleTimeout: 20 * time.Millisecond}, false, QueueConfig{}, RenderLimits{})
+	wp.Start()
+	defer wp.Stop()
+
+	for i := 0; i < 5; i++ {
+		wp.scaleUp("test")
+	}
+	if got := wp.Workers(); got > 3 {
+		t.Fatalf("Workers() = %d, want at most max of 3", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for wp.Workers() > 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := wp.Workers(); got != 1 {
+		t.Errorf("Workers() = %d after idle timeout, want base count of 1", got)
+	}
+}
+
+func TestWorkerPoolScalingDisabled(t *testing.T) {
+	wp := NewWorkerPool(2, zap.NewNop(), models.NewAppRegistry(), runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0, ScalingConfig{}, false, QueueConfig{}, RenderLimits{})
+	wp.Start()
+	defer wp.Stop()
+
+	wp.scaleUp("test")
+	if got := wp.Workers(); got != 2 {
+		t.Errorf("Workers() = %d, want fixed count of 2", got)
+	}
+}
+
+func TestWorkerPoolNoScaleUpBeforeStart(t *testing.T) {
+	wp := NewWorkerPool(2, zap.NewNop(), models.NewAppRegistry(), runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0, ScalingConfig{}, false, QueueConfig{}, RenderLimits{})
+	defer wp.Stop()
+
+	// Jobs queued before Start must wait for it rather than start workers
+	wp.scaleUp("test")
+	if got := wp.Workers(); got != 0 {
+		t.Fatalf("Workers() = %d before Start, want 0", got)
+	}
+
+	wp.Start()
+	if got := wp.Workers(); got != 2 {
+		t.Errorf("Workers() = %d after Start, want base count of 2", got)
+	}
+}
+
+func TestWorkerPoolRejectsWhenFull(t *testing.T) {
+	wp := NewWorkerPool(1, zap.NewNop(), models.NewAppRegistry(), runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0,
+		ScalingConfig{}, false, QueueConfig{Size: 1, RejectWhenFull: true}, RenderLimits{})
+	defer wp.cancel()
+
+	// Not started, so the first job sits in the queue
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go wp.SubmitRoots(ctx, "first", nil, models.Device{}, RenderOptions{})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(wp.interactive) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := wp.SubmitRoots(context.Background(), "second", nil, models.Device{}, RenderOptions{}); err != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	// Background jobs have their own queue
+	bgCtx, bgCancel := context.WithTimeout(WithPriority(context.Background(), PriorityBackground), 10*time.Millisecond)
+	defer bgCancel()
+	if _, err := wp.SubmitRoots(bgCtx, "third", nil, models.Device{}, RenderOptions{}); err == ErrQueueFull {
+		t.Error("expected background job to be queued while the interactive queue is full")
+	}
+}
+
+func TestWorkerPoolDrain(t *testing.T) {
+	appsDir := t.TempDir()
+	writeTestApp(t, appsDir, "blank-app", "def main(config):\n    return []\n")
+	registry := models.NewAppRegistry()
+	if err := registry.LoadApps(appsDir); err != nil {
+		t.Fatalf("LoadApps: %v", err)
+	}
+
+	// queueJobs submits n jobs to a pool that hasn't started, so they wait in the queue
+	queueJobs := func(wp *WorkerPool, n int) <-chan error {
+		errs := make(chan error, n)
+		for i := 0; i < n; i++ {
+			go func() {
+				_, err := wp.SubmitRoots(context.Background(), "blank-app", nil, models.Device{Width: 64, Height: 32}, RenderOptions{})
+				errs <- err
+			}()
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for wp.queued() < n && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		return errs
+	}
+
+	t.Run("finishes queued jobs", func(t *testing.T) {
+		wp := NewWorkerPool(1, zap.NewNop(), registry, runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0, ScalingConfig{}, false, QueueConfig{}, RenderLimits{})
+		errs := queueJobs(wp, 2)
+
+		wp.Start()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := wp.Drain(ctx); err != nil {
+			t.Fatalf("Drain: %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err != nil {
+				t.Errorf("queued job failed during drain: %v", err)
+			}
+		}
+		if _, err := wp.SubmitRoots(context.Background(), "blank-app", nil, models.Device{}, RenderOptions{}); err != ErrPoolStopped {
+			t.Errorf("submit after drain = %v, want ErrPoolStopped", err)
+		}
+		if err := wp.Drain(ctx); err != nil {
+			t.Errorf("second Drain: %v", err)
+		}
+	})
+
+	t.Run("fails queued jobs at the deadline", func(t *testing.T) {
+		wp := NewWorkerPool(1, zap.NewNop(), registry, runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0, ScalingConfig{}, false, QueueConfig{}, RenderLimits{})
+		errs := queueJobs(wp, 2)
+
+		// Never started, so nothing can finish before the deadline
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := wp.Drain(ctx); err != context.Canceled {
+			t.Errorf("Drain = %v, want context.Canceled", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err != ErrPoolStopped {
+				t.Errorf("queued job error = %v, want ErrPoolStopped", err)
+			}
+		}
+	})
+}
+
+func TestWorkerPoolSubmitAll(t *testing.T) {
+	appsDir := t.TempDir()
+	writeTestApp(t, appsDir, "blank-app", "def main(config):\n    return []\n")
+	registry := models.NewAppRegistry()
+	if err := registry.LoadApps(appsDir); err != nil {
+		t.Fatalf("LoadApps: %v", err)
+	}
+
+	wp := NewWorkerPool(2, zap.NewNop(), registry, runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 4,
+		ScalingConfig{}, true, QueueConfig{Size: 1, RejectWhenFull: true}, RenderLimits{})
+	wp.Start()
+	defer wp.Stop()
+
+	// More jobs than the queue holds; pacing keeps them from being rejected
+	var jobs []RenderJob
+	for i := 0; i < 10; i++ {
+		jobs = append(jobs, RenderJob{AppID: "blank-app", Device: models.Device{Width: 32 + i, Height: 32}})
+	}
+	jobs = append(jobs, RenderJob{AppID: "missing-app"})
+
+	seen := make(map[int]bool)
+	for result := range wp.SubmitAll(context.Background(), jobs) {
+		if seen[result.Index] {
+			t.Errorf("job %d reported twice", result.Index)
+		}
+		seen[result.Index] = true
+
+		if jobs[result.Index].AppID == "missing-app" {
+			if result.Error == nil {
+				t.Error("expected an error for a missing app")
+			}
+			continue
+		}
+		if result.Error != nil {
+			t.Errorf("job %d failed: %v", result.Index, result.Error)
+		}
+	}
+	if len(seen) != len(jobs) {
+		t.Errorf("got %d results, want %d", len(seen), len(jobs))
+	}
+
+	t.Run("cancelled context fails unsubmitted jobs", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		count := 0
+		for result := range wp.SubmitAll(ctx, jobs) {
+			count++
+			if result.Error == nil {
+				t.Errorf("job %d succeeded after cancellation", result.Index)
+			}
+		}
+		if count != len(jobs) {
+			t.Errorf("got %d results, want %d", count, len(jobs))
+		}
+	})
+}
+
+func TestWorkerPoolJobID(t *testing.T) {
+	appsDir := t.TempDir()
+	writeTestApp(t, appsDir, "blank-app", "def main(config):\n    return []\n")
+	registry := models.NewAppRegistry()
+	if err := registry.LoadApps(appsDir); err != nil {
+		t.Fatalf("LoadApps: %v", err)
+	}
+
+	wp := NewWorkerPool(1, zap.NewNop(), registry, runtime.NewInMemoryCache(), nil, runtime.SecretDecryptionKey{}, 30, 0, ScalingConfig{}, false, QueueConfig{}, RenderLimits{})
+	wp.Start()
+	defer wp.Stop()
+
+	result, err := wp.submit(WithJobID(context.Background(), "trace-123"), "blank-app", nil, models.Device{Width: 64, Height: 32}, RenderOptions{})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if result.JobID != "trace-123" {
+		t.Errorf("JobID = %q, want trace-123", result.JobID)
+	}
+
+	result, err = wp.submit(context.Background(), "blank-app", nil, models.Device{Width: 64, Height: 32}, RenderOptions{})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if result.JobID == "" {
+		t.Error("expected a generated JobID")
+	}
+}
@@ -15,7 +15,7 @@ import (
 
 // Client wraps the Redis client for pub/sub operations
 type Client struct {
-	client *redis.Client
+	client redis.UniversalClient
 	config config.RedisConfig
 	logger *zap.Logger
 	ctx    context.Context
@@ -31,17 +31,56 @@ func NewClient(cfg config.RedisConfig, logger *zap.Logger) (*Client, error) {
 		}
 		cfg.ConsumerName = fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
 	}
+	if cfg.StreamKey == "" {
+		cfg.StreamKey = config.DefaultStreamKey
+	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:         cfg.Addr,
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		PoolSize:     10,
-		PoolTimeout:  30 * time.Second,
-	})
+	poolTimeout := time.Duration(cfg.PoolTimeoutMs) * time.Millisecond
+	dialTimeout := time.Duration(cfg.DialTimeoutMs) * time.Millisecond
+	readTimeout := time.Duration(cfg.ReadTimeoutMs) * time.Millisecond
+	writeTimeout := time.Duration(cfg.WriteTimeoutMs) * time.Millisecond
+
+	var rdb redis.UniversalClient
+	switch {
+	case len(cfg.ClusterAddrs) > 0:
+		// The stream is a single key, so it lives on one shard and consumer
+		// group commands are routed there
+		rdb = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.ClusterAddrs,
+			Password:     cfg.Password,
+			DialTimeout:  dialTimeout,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+			PoolSize:     cfg.PoolSize,
+			PoolTimeout:  poolTimeout,
+		})
+	case cfg.SentinelMasterName != "":
+		// Sentinel reports the current master, and the client reconnects to
+		// the new one after a failover
+		rdb = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.SentinelMasterName,
+			SentinelAddrs:    cfg.SentinelAddrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			DialTimeout:      dialTimeout,
+			ReadTimeout:      readTimeout,
+			WriteTimeout:     writeTimeout,
+			PoolSize:         cfg.PoolSize,
+			PoolTimeout:      poolTimeout,
+		})
+	default:
+		rdb = redis.NewClient(&redis.Options{
+			Addr:         cfg.Addr,
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			DialTimeout:  dialTimeout,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+			PoolSize:     cfg.PoolSize,
+			PoolTimeout:  poolTimeout,
+		})
+	}
 
 	ctx := context.Background()
 
@@ -59,6 +98,9 @@ func NewClient(cfg config.RedisConfig, logger *zap.Logger) (*Client, error) {
 
 	logger.Info("Connected to Redis",
 		zap.String("addr", cfg.Addr),
+		zap.String("sentinel_master", cfg.SentinelMasterName),
+		zap.Strings("cluster_addrs", cfg.ClusterAddrs),
+		zap.String("stream", cfg.StreamKey),
 		zap.String("consumer_group", cfg.ConsumerGroup),
 		zap.String("consumer_name", cfg.ConsumerName))
 
@@ -99,18 +141,16 @@ func (c *Client) PublishRenderResult(result *models.RenderResult) error {
 
 // initializeConsumerGroup creates the consumer group for the render requests stream
 func (c *Client) initializeConsumerGroup() error {
-	const streamKey = "matrx:render_requests"
-
 	// Create consumer group if it doesn't exist
 	// Using "0" as the ID means start from the beginning
 	// Using "$" would mean start from new messages only
-	err := c.client.XGroupCreateMkStream(c.ctx, streamKey, c.config.ConsumerGroup, "0").Err()
+	err := c.client.XGroupCreateMkStream(c.ctx, c.config.StreamKey, c.config.ConsumerGroup, "0").Err()
 	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
 		return fmt.Errorf("failed to create consumer group: %w", err)
 	}
 
 	c.logger.Info("Consumer group initialized",
-		zap.String("stream", streamKey),
+		zap.String("stream", c.config.StreamKey),
 		zap.String("group", c.config.ConsumerGroup))
 
 	return nil
@@ -118,14 +158,12 @@ func (c *Client) initializeConsumerGroup() error {
 
 // ReadFromStream reads messages from the render requests stream using consumer group
 func (c *Client) ReadFromStream(ctx context.Context, count int64, block time.Duration) ([]redis.XStream, error) {
-	const streamKey = "matrx:render_requests"
-
 	// Read from stream using consumer group
 	// ">" means only new messages not yet delivered to other consumers
 	streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
 		Group:    c.config.ConsumerGroup,
 		Consumer: c.config.ConsumerName,
-		Streams:  []string{streamKey, ">"},
+		Streams:  []string{c.config.StreamKey, ">"},
 		Count:    count,
 		Block:    block,
 		NoAck:    false, // We want to explicitly acknowledge messages
@@ -140,9 +178,7 @@ func (c *Client) ReadFromStream(ctx context.Context, count int64, block time.Dur
 
 // AcknowledgeMessage acknowledges a message from the stream
 func (c *Client) AcknowledgeMessage(ctx context.Context, messageID string) error {
-	const streamKey = "matrx:render_requests"
-
-	err := c.client.XAck(ctx, streamKey, c.config.ConsumerGroup, messageID).Err()
+	err := c.client.XAck(ctx, c.config.StreamKey, c.config.ConsumerGroup, messageID).Err()
 	if err != nil {
 		return fmt.Errorf("failed to acknowledge message %s: %w", messageID, err)
 	}
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Device represents the target device configuration
 type Device struct {
@@ -9,13 +14,52 @@ type Device struct {
 	Height int    `json:"height"`
 }
 
+// Size is a display resolution used for multi-resolution renders
+type Size struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// String formats the size as "WIDTHxHEIGHT", the key used for batch render results
+func (s Size) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// ParseSize parses a "WIDTHxHEIGHT" string such as "64x32"
+func ParseSize(raw string) (Size, error) {
+	dims := strings.SplitN(strings.ToLower(strings.TrimSpace(raw)), "x", 2)
+	if len(dims) != 2 {
+		return Size{}, fmt.Errorf("invalid size %q: expected WIDTHxHEIGHT", raw)
+	}
+	width, err := strconv.Atoi(strings.TrimSpace(dims[0]))
+	if err != nil || width <= 0 {
+		return Size{}, fmt.Errorf("invalid size %q: width must be a positive integer", raw)
+	}
+	height, err := strconv.Atoi(strings.TrimSpace(dims[1]))
+	if err != nil || height <= 0 {
+		return Size{}, fmt.Errorf("invalid size %q: height must be a positive integer", raw)
+	}
+	return Size{Width: width, Height: height}, nil
+}
+
 // RenderRequest represents a request to render a Pixlet app
 type RenderRequest struct {
-	Type   string                 `json:"type"`
-	UUID   string                 `json:"uuid"` // Unique identifier for the request
-	AppID  string                 `json:"app_id"`
-	Device Device                 `json:"device"`
-	Params map[string]interface{} `json:"params"`
+	Type           string                 `json:"type"`
+	UUID           string                 `json:"uuid"`                      // Unique identifier for the request
+	IdempotencyKey string                 `json:"idempotency_key,omitempty"` // Retries with the same key, app and device get the first result
+	AppID          string                 `json:"app_id"`
+	Device         Device                 `json:"device"`
+	Params         map[string]interface{} `json:"params"`
+	RenderTime     *time.Time             `json:"render_time,omitempty"`   // Optional fixed clock for the Starlark time module
+	Encoding       *EncodingOptions       `json:"encoding,omitempty"`      // Optional WebP encoder overrides
+	DebugOverlay   bool                   `json:"debug_overlay,omitempty"` // Stamp app/device/time/worker onto the output
+}
+
+// EncodingOptions overrides the server's WebP encoder settings for a single request
+type EncodingOptions struct {
+	Lossless *bool `json:"lossless,omitempty"`
+	Quality  *int  `json:"quality,omitempty"` // 0-100
+	Method   *int  `json:"method,omitempty"`  // 0 (fastest) to 6 (smallest)
 }
 
 // RenderResult represents the result of a render operation
@@ -26,6 +70,7 @@ type RenderResult struct {
 	AppID        string    `json:"app_id"`
 	RenderOutput string    `json:"render_output"` // base64 encoded WebP (empty string if nothing to display)
 	Error        bool      `json:"error"`         // true if rendering failed with an error
+	Skipped      bool      `json:"skipped"`       // true if the app chose not to display anything (returned [])
 	ProcessedAt  time.Time `json:"processed_at"`
 }
 
@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Size
+		wantErr bool
+	}{
+		{"64x32", Size{Width: 64, Height: 32}, false},
+		{" 128X64 ", Size{Width: 128, Height: 64}, false},
+		{"64", Size{}, true},
+		{"0x32", Size{}, true},
+		{"64x-1", Size{}, true},
+		{"axb", Size{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestSizeString(t *testing.T) {
+	if got := (Size{Width: 192, Height: 64}).String(); got != "192x64" {
+		t.Errorf("String() = %q, want 192x64", got)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -18,11 +20,34 @@ type AppManifest struct {
 	FileName    string `yaml:"fileName" json:"fileName"`
 	PackageName string `yaml:"packageName" json:"packageName"`
 
+	// Tags are free-form labels for browsing and filtering, e.g. "sports"
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+
+	// Icon and Screenshots are image files in the app directory, served from
+	// /apps/{id}/icon and /apps/{id}/assets/{name}
+	Icon        string   `yaml:"icon,omitempty" json:"icon,omitempty"`
+	Screenshots []string `yaml:"screenshots,omitempty" json:"screenshots,omitempty"`
+
+	// MaxFrameCount caps the frames painted for this app (0 uses the server default)
+	MaxFrameCount int `yaml:"maxFrameCount,omitempty" json:"maxFrameCount,omitempty"`
+
+	// RenderCacheTTL is how long rendered output is cached, in seconds
+	// (0 uses the server default, negative disables caching for this app)
+	RenderCacheTTL int `yaml:"renderCacheTTL,omitempty" json:"renderCacheTTL,omitempty"`
+
 	// Runtime fields (not in manifest)
 	DirectoryPath string `yaml:"-" json:"directoryPath"`
 	StarFilePath  string `yaml:"-" json:"starFilePath"`
+	HasSchema     bool   `yaml:"-" json:"hasSchema"` // source defines get_schema
 }
 
+// appIDPattern limits app IDs to names that are safe as directory names and URL
+// path segments
+var appIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// schemaFuncPattern matches a top-level get_schema definition in Starlark source
+var schemaFuncPattern = regexp.MustCompile(`(?m)^def\s+get_schema\s*\(`)
+
 // LoadManifest loads a manifest.yaml file from the given directory
 func LoadManifest(appDir string) (*AppManifest, error) {
 	manifestPath := filepath.Join(appDir, "manifest.yaml")
@@ -45,10 +70,55 @@ func LoadManifest(appDir string) (*AppManifest, error) {
 	if _, err := os.Stat(manifest.StarFilePath); err != nil {
 		return nil, fmt.Errorf("star file not found: %s", manifest.StarFilePath)
 	}
+	manifest.HasSchema = definesSchema(manifest.StarFilePath)
 
 	return &manifest, nil
 }
 
+// Validate checks the manifest fields an uploaded app must get right. Apps on
+// disk are loaded without it, so existing installs keep working.
+func (m *AppManifest) Validate() error {
+	if !appIDPattern.MatchString(m.ID) {
+		return fmt.Errorf("id must be 1-64 letters, digits, '-' or '_', starting with a letter or digit")
+	}
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if m.FileName == "" || !filepath.IsLocal(m.FileName) {
+		return fmt.Errorf("fileName must name a file inside the app directory")
+	}
+	if m.MaxFrameCount < 0 {
+		return fmt.Errorf("maxFrameCount must not be negative")
+	}
+	if m.Icon != "" && !filepath.IsLocal(m.Icon) {
+		return fmt.Errorf("icon must name a file inside the app directory")
+	}
+	for _, screenshot := range m.Screenshots {
+		if !filepath.IsLocal(screenshot) {
+			return fmt.Errorf("screenshots must name files inside the app directory")
+		}
+	}
+	return nil
+}
+
+// definesSchema reports whether the app's source defines get_schema. This scans
+// the source rather than loading the app, so listing thousands of apps stays
+// cheap; a directory app is checked across its top-level .star files.
+func definesSchema(starPath string) bool {
+	files := []string{starPath}
+	if info, err := os.Stat(starPath); err == nil && info.IsDir() {
+		files, _ = filepath.Glob(filepath.Join(starPath, "*.star"))
+	}
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err == nil && schemaFuncPattern.Match(source) {
+			return true
+		}
+	}
+	return false
+}
+
 // AppRegistry manages the collection of available apps
 type AppRegistry struct {
 	apps map[string]*AppManifest
@@ -35,6 +35,68 @@ func TestLoadManifest_Valid(t *testing.T) {
 	}
 }
 
+func TestLoadManifest_MaxFrameCount(t *testing.T) {
+	dir := t.TempDir()
+	content := "id: anim\nname: anim\nfileName: anim.star\npackageName: apps.anim\nmaxFrameCount: 120\n"
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "anim.star"), []byte("# app"), 0644)
+
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.MaxFrameCount != 120 {
+		t.Errorf("MaxFrameCount = %d, want 120", m.MaxFrameCount)
+	}
+}
+
+func TestLoadManifest_RenderCacheTTL(t *testing.T) {
+	dir := t.TempDir()
+	content := "id: clock\nname: clock\nfileName: clock.star\npackageName: apps.clock\nrenderCacheTTL: 300\n"
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "clock.star"), []byte("# app"), 0644)
+
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.RenderCacheTTL != 300 {
+		t.Errorf("RenderCacheTTL = %d, want 300", m.RenderCacheTTL)
+	}
+}
+
+func TestLoadManifest_TagsAndSchema(t *testing.T) {
+	dir := t.TempDir()
+	content := "id: weather\nname: Weather\nfileName: weather.star\npackageName: apps.weather\ntags: [weather, outdoors]\n"
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	source := "def main(config):\n    return []\n\ndef get_schema():\n    return None\n"
+	os.WriteFile(filepath.Join(dir, "weather.star"), []byte(source), 0644)
+
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Tags) != 2 || m.Tags[0] != "weather" {
+		t.Errorf("Tags = %v, want [weather outdoors]", m.Tags)
+	}
+	if !m.HasSchema {
+		t.Error("HasSchema = false, want true")
+	}
+
+	// A mention in a comment or a nested def doesn't count
+	os.WriteFile(filepath.Join(dir, "weather.star"), []byte("# def get_schema():\ndef main(config):\n    def get_schema():\n        pass\n"), 0644)
+	m, _ = LoadManifest(dir)
+	if m.HasSchema {
+		t.Error("HasSchema = true, want false")
+	}
+}
+
 func TestLoadManifest_MissingManifest(t *testing.T) {
 	dir := t.TempDir()
 	_, err := LoadManifest(dir)
@@ -204,3 +266,32 @@ func TestAppRegistry_LoadApps_ClearsOnReload(t *testing.T) {
 		t.Error("expected 0 apps after removing and reloading")
 	}
 }
+
+func TestAppManifest_Validate(t *testing.T) {
+	valid := AppManifest{ID: "my-app", Name: "My App", FileName: "my_app.star"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]func(m *AppManifest){
+		"empty id":            func(m *AppManifest) { m.ID = "" },
+		"id with slash":       func(m *AppManifest) { m.ID = "../etc" },
+		"id starting with -":  func(m *AppManifest) { m.ID = "-app" },
+		"blank name":          func(m *AppManifest) { m.Name = "  " },
+		"missing fileName":    func(m *AppManifest) { m.FileName = "" },
+		"escaping fileName":   func(m *AppManifest) { m.FileName = "../app.star" },
+		"absolute fileName":   func(m *AppManifest) { m.FileName = "/tmp/app.star" },
+		"negative frames":     func(m *AppManifest) { m.MaxFrameCount = -1 },
+		"escaping icon":       func(m *AppManifest) { m.Icon = "../icon.png" },
+		"absolute screenshot": func(m *AppManifest) { m.Screenshots = []string{"/etc/shot.png"} },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := valid
+			mutate(&m)
+			if err := m.Validate(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
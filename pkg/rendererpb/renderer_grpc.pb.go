@@ -0,0 +1,294 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: renderer/v1/renderer.proto
+
+package rendererpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Renderer_Render_FullMethodName         = "/matrx.renderer.v1.Renderer/Render"
+	Renderer_RenderStream_FullMethodName   = "/matrx.renderer.v1.Renderer/RenderStream"
+	Renderer_GetSchema_FullMethodName      = "/matrx.renderer.v1.Renderer/GetSchema"
+	Renderer_ValidateConfig_FullMethodName = "/matrx.renderer.v1.Renderer/ValidateConfig"
+	Renderer_ListApps_FullMethodName       = "/matrx.renderer.v1.Renderer/ListApps"
+)
+
+// RendererClient is the client API for Renderer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Renderer renders Pixlet apps and serves their schemas. It mirrors the HTTP API,
+// with renders returned as raw WebP bytes instead of base64 JSON.
+type RendererClient interface {
+	// Render validates a config against the app's schema and renders it once.
+	// Invalid configs fail with INVALID_ARGUMENT and a BadRequest detail listing
+	// each field violation.
+	Render(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (*RenderResult, error)
+	// RenderStream renders each request sent on the stream and sends its result
+	// back as soon as it is ready, so results may arrive out of order; match them
+	// by uuid. A failed render is reported in that result's error and doesn't end
+	// the stream.
+	RenderStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RenderRequest, RenderResult], error)
+	// GetSchema returns an app's config schema, as served by GET /apps/{id}/schema.
+	GetSchema(ctx context.Context, in *GetSchemaRequest, opts ...grpc.CallOption) (*GetSchemaResponse, error)
+	// ValidateConfig checks a config against an app's schema without rendering.
+	ValidateConfig(ctx context.Context, in *ValidateConfigRequest, opts ...grpc.CallOption) (*ValidateConfigResponse, error)
+	// ListApps lists loaded apps with the filters, sorting and paging of GET /apps.
+	ListApps(ctx context.Context, in *ListAppsRequest, opts ...grpc.CallOption) (*ListAppsResponse, error)
+}
+
+type rendererClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRendererClient(cc grpc.ClientConnInterface) RendererClient {
+	return &rendererClient{cc}
+}
+
+func (c *rendererClient) Render(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (*RenderResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenderResult)
+	err := c.cc.Invoke(ctx, Renderer_Render_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rendererClient) RenderStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RenderRequest, RenderResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Renderer_ServiceDesc.Streams[0], Renderer_RenderStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RenderRequest, RenderResult]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Renderer_RenderStreamClient = grpc.BidiStreamingClient[RenderRequest, RenderResult]
+
+func (c *rendererClient) GetSchema(ctx context.Context, in *GetSchemaRequest, opts ...grpc.CallOption) (*GetSchemaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSchemaResponse)
+	err := c.cc.Invoke(ctx, Renderer_GetSchema_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rendererClient) ValidateConfig(ctx context.Context, in *ValidateConfigRequest, opts ...grpc.CallOption) (*ValidateConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateConfigResponse)
+	err := c.cc.Invoke(ctx, Renderer_ValidateConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rendererClient) ListApps(ctx context.Context, in *ListAppsRequest, opts ...grpc.CallOption) (*ListAppsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAppsResponse)
+	err := c.cc.Invoke(ctx, Renderer_ListApps_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RendererServer is the server API for Renderer service.
+// All implementations must embed UnimplementedRendererServer
+// for forward compatibility.
+//
+// Renderer renders Pixlet apps and serves their schemas. It mirrors the HTTP API,
+// with renders returned as raw WebP bytes instead of base64 JSON.
+type RendererServer interface {
+	// Render validates a config against the app's schema and renders it once.
+	// Invalid configs fail with INVALID_ARGUMENT and a BadRequest detail listing
+	// each field violation.
+	Render(context.Context, *RenderRequest) (*RenderResult, error)
+	// RenderStream renders each request sent on the stream and sends its result
+	// back as soon as it is ready, so results may arrive out of order; match them
+	// by uuid. A failed render is reported in that result's error and doesn't end
+	// the stream.
+	RenderStream(grpc.BidiStreamingServer[RenderRequest, RenderResult]) error
+	// GetSchema returns an app's config schema, as served by GET /apps/{id}/schema.
+	GetSchema(context.Context, *GetSchemaRequest) (*GetSchemaResponse, error)
+	// ValidateConfig checks a config against an app's schema without rendering.
+	ValidateConfig(context.Context, *ValidateConfigRequest) (*ValidateConfigResponse, error)
+	// ListApps lists loaded apps with the filters, sorting and paging of GET /apps.
+	ListApps(context.Context, *ListAppsRequest) (*ListAppsResponse, error)
+	mustEmbedUnimplementedRendererServer()
+}
+
+// UnimplementedRendererServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRendererServer struct{}
+
+func (UnimplementedRendererServer) Render(context.Context, *RenderRequest) (*RenderResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Render not implemented")
+}
+func (UnimplementedRendererServer) RenderStream(grpc.BidiStreamingServer[RenderRequest, RenderResult]) error {
+	return status.Errorf(codes.Unimplemented, "method RenderStream not implemented")
+}
+func (UnimplementedRendererServer) GetSchema(context.Context, *GetSchemaRequest) (*GetSchemaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSchema not implemented")
+}
+func (UnimplementedRendererServer) ValidateConfig(context.Context, *ValidateConfigRequest) (*ValidateConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateConfig not implemented")
+}
+func (UnimplementedRendererServer) ListApps(context.Context, *ListAppsRequest) (*ListAppsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListApps not implemented")
+}
+func (UnimplementedRendererServer) mustEmbedUnimplementedRendererServer() {}
+func (UnimplementedRendererServer) testEmbeddedByValue()                  {}
+
+// UnsafeRendererServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RendererServer will
+// result in compilation errors.
+type UnsafeRendererServer interface {
+	mustEmbedUnimplementedRendererServer()
+}
+
+func RegisterRendererServer(s grpc.ServiceRegistrar, srv RendererServer) {
+	// If the following call pancis, it indicates UnimplementedRendererServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Renderer_ServiceDesc, srv)
+}
+
+func _Renderer_Render_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RendererServer).Render(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Renderer_Render_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RendererServer).Render(ctx, req.(*RenderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Renderer_RenderStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RendererServer).RenderStream(&grpc.GenericServerStream[RenderRequest, RenderResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Renderer_RenderStreamServer = grpc.BidiStreamingServer[RenderRequest, RenderResult]
+
+func _Renderer_GetSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSchemaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RendererServer).GetSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Renderer_GetSchema_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RendererServer).GetSchema(ctx, req.(*GetSchemaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Renderer_ValidateConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RendererServer).ValidateConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Renderer_ValidateConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RendererServer).ValidateConfig(ctx, req.(*ValidateConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Renderer_ListApps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAppsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RendererServer).ListApps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Renderer_ListApps_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RendererServer).ListApps(ctx, req.(*ListAppsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Renderer_ServiceDesc is the grpc.ServiceDesc for Renderer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Renderer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "matrx.renderer.v1.Renderer",
+	HandlerType: (*RendererServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Render",
+			Handler:    _Renderer_Render_Handler,
+		},
+		{
+			MethodName: "GetSchema",
+			Handler:    _Renderer_GetSchema_Handler,
+		},
+		{
+			MethodName: "ValidateConfig",
+			Handler:    _Renderer_ValidateConfig_Handler,
+		},
+		{
+			MethodName: "ListApps",
+			Handler:    _Renderer_ListApps_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RenderStream",
+			Handler:       _Renderer_RenderStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "renderer/v1/renderer.proto",
+}